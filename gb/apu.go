@@ -1,6 +1,13 @@
 package gb
 
 // The APU, or audio processing unit, is used to generate sound in the Gameboy.
+// It doesn't generate sound yet, so it has no per-model differences (see model.go).
 
 type APU struct {
 }
+
+// the APU's registers are saved with the memory map, it has no internal state yet
+
+func (apu *APU) saveState(s *stateWriter) {}
+
+func (apu *APU) loadState(s *stateReader) {}
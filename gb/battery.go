@@ -0,0 +1,153 @@
+package gb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+/*
+
+Battery saves
+
+Cartridges with a battery keep their external RAM between sessions. It's stored in <rom>.sav next to the ROM,
+as a raw dump of the RAM (sized by the header's RAM size code, or the mapper's built-in RAM),
+followed by the mapper's other battery backed state: the 48-byte RTC trailer for cartridges with
+an MBC3 timer, the HuC3 and TAMA5 clocks and their memory, or the MBC6 flash.
+Saves of the RAM alone load too, leaving the rest as is.
+
+The save is loaded by NewConsole, flushed every few seconds of emulated time while the RAM is being written,
+and flushed by Close. Flushes write a temporary file and rename it over the save, so a crash mid-write
+leaves the previous save intact. A failed flush is retried at the next interval, the latest failure is
+reported by FlushError and by Close if the final save fails too.
+
+*/
+
+// emulated seconds between flushes of the battery save
+const SAVE_FLUSH_SECONDS = 5
+
+// SaveSizeError is returned when a battery save doesn't match the cartridge's RAM size
+type SaveSizeError struct {
+	Path     string
+	Size     int // bytes in the save
+	Expected int // bytes of RAM, plus the mapper's battery state if any
+}
+
+func (e *SaveSizeError) Error() string {
+	return fmt.Sprintf("battery save %s is %d bytes, expected %d", e.Path, e.Size, e.Expected)
+}
+
+// battery returns the mapper if it has state to save alongside the RAM, or nil
+func (c *Console) battery() batteryMapper {
+	if m, ok := c.cart.mapper.(batteryMapper); ok && m.batterySize() > 0 {
+		return m
+	}
+	return nil
+}
+
+// clocked reports whether the battery save includes a clock, which changes without RAM writes
+func (c *Console) clocked() bool {
+	_, ok := c.cart.mapper.(wallClockMapper)
+	return ok && c.battery() != nil
+}
+
+// Save writes the battery save, it does nothing for cartridges without a battery
+func (c *Console) Save() error {
+	if c.savePath == "" {
+		return nil
+	}
+
+	data := append([]uint8{}, c.cart.ram...)
+	if m := c.battery(); m != nil {
+		data = append(data, m.saveBattery()...)
+	}
+
+	if err := writeFileAtomic(c.savePath, data); err != nil {
+		return err
+	}
+
+	c.cart.dirty = false
+	c.flushErr = nil
+	return nil
+}
+
+// Load reads the battery save, a missing save leaves the RAM as it is
+func (c *Console) Load() error {
+	if c.savePath == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(c.savePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	size := len(c.cart.ram)
+	m := c.battery()
+
+	switch {
+	case len(data) == size:
+		// saves made without the mapper's state, it keeps its current value
+	case m != nil && len(data) == size+m.batterySize():
+		if err := m.loadBattery(data[size:]); err != nil {
+			return err
+		}
+	default:
+		expected := size
+		if m != nil {
+			expected += m.batterySize()
+		}
+		return &SaveSizeError{Path: c.savePath, Size: len(data), Expected: expected}
+	}
+
+	copy(c.cart.ram, data)
+	c.cart.dirty = false
+	return nil
+}
+
+// Close flushes the battery save, call it when done with the console
+func (c *Console) Close() error {
+	return c.Save()
+}
+
+// FlushError returns the error of the latest periodic battery save flush, or nil if it succeeded.
+// A successful Save or Close clears it too
+func (c *Console) FlushError() error {
+	return c.flushErr
+}
+
+// flush saves if the RAM changed since the last save, or the cartridge has a clock that keeps moving
+func (c *Console) flush() error {
+	if !c.cart.dirty && !c.clocked() {
+		return nil
+	}
+	return c.Save()
+}
+
+// writeFileAtomic writes to a temporary file in the same directory and renames it over path
+func writeFileAtomic(path string, data []uint8) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	// clean up if anything fails before the rename
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
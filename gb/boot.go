@@ -0,0 +1,212 @@
+package gb
+
+import (
+	"fmt"
+	"os"
+)
+
+/*
+
+Boot ROM
+
+At power on the boot ROM is mapped over the cartridge, it scrolls the logo, checks the header
+and sets up the registers, then unmaps itself by writing to 0xFF50 and falls through to 0x0100.
+
+	DMG, MGB, SGB  256 bytes, mapped at 0000 - 00FF
+	CGB            2304 bytes, mapped at 0000 - 00FF and 0200 - 08FF, the cartridge header
+	               at 0100 - 01FF stays visible
+
+	FF50 - BANK  Writing a value with bit 0 set unmaps the boot ROM until the next power on
+
+Without a boot ROM the console starts at 0x0100 with the registers and I/O the boot ROM leaves behind.
+
+*/
+
+const BOOT_ADDRESS = 0xFF50
+
+const DMG_BOOT_ROM_SIZE = 0x100
+const CGB_BOOT_ROM_SIZE = 0x900
+
+// BootROMSizeError is returned when a boot ROM isn't the size of any known boot ROM, or not the size of
+// the selected model's boot ROM
+type BootROMSizeError struct {
+	Size  int
+	Model Model // MODEL_AUTO when the size matches no boot ROM at all
+}
+
+func (e *BootROMSizeError) Error() string {
+	if e.Model != MODEL_AUTO {
+		return fmt.Sprintf("boot rom is %d bytes, the %v boot rom is %d", e.Size, e.Model, e.Model.bootROMSize())
+	}
+	return fmt.Sprintf("boot rom is %d bytes, expected %d (DMG, MGB, SGB) or %d (CGB)", e.Size, DMG_BOOT_ROM_SIZE, CGB_BOOT_ROM_SIZE)
+}
+
+// loadBootROM reads a boot ROM file and checks its size
+func loadBootROM(path string) ([]uint8, error) {
+	boot, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(boot) != DMG_BOOT_ROM_SIZE && len(boot) != CGB_BOOT_ROM_SIZE {
+		return nil, &BootROMSizeError{Size: len(boot)}
+	}
+
+	return boot, nil
+}
+
+// readBoot returns the boot ROM byte mapped at the address, if any
+func (mem *MemoryMap) readBoot(address uint16) (uint8, bool) {
+	if !mem.booting {
+		return 0, false
+	}
+
+	if address < DMG_BOOT_ROM_SIZE || (len(mem.boot) == CGB_BOOT_ROM_SIZE && address >= 0x200 && address < CGB_BOOT_ROM_SIZE) {
+		return mem.boot[address], true
+	}
+
+	return 0, false
+}
+
+// <----------------------------- POST BOOT STATE -----------------------------> //
+
+// I/O registers as the boot ROM leaves them, registers not listed are 0x00
+var dmgPostBootIO = map[uint16]uint8{
+	0xFF02: 0x7E, // SC
+	0xFF10: 0x80, // NR10
+	0xFF11: 0xBF, // NR11
+	0xFF12: 0xF3, // NR12
+	0xFF13: 0xFF, // NR13
+	0xFF14: 0xBF, // NR14
+	0xFF16: 0x3F, // NR21
+	0xFF18: 0xFF, // NR23
+	0xFF19: 0xBF, // NR24
+	0xFF1A: 0x7F, // NR30
+	0xFF1B: 0xFF, // NR31
+	0xFF1C: 0x9F, // NR32
+	0xFF1D: 0xFF, // NR33
+	0xFF1E: 0xBF, // NR34
+	0xFF20: 0xFF, // NR41
+	0xFF23: 0xBF, // NR44
+	0xFF24: 0x77, // NR50
+	0xFF25: 0xF3, // NR51
+	0xFF26: 0xF1, // NR52
+	0xFF40: 0x91, // LCDC
+	0xFF41: 0x85, // STAT
+	0xFF46: 0xFF, // DMA
+	0xFF47: 0xFC, // BGP
+	0xFF48: 0xFF, // OBP0
+	0xFF49: 0xFF, // OBP1
+}
+
+// CGB differences from the DMG
+var cgbPostBootIO = map[uint16]uint8{
+	0xFF02: 0x7F, // SC
+	0xFF46: 0x00, // DMA
+	0xFF4F: 0xFE, // VBK
+	0xFF51: 0xFF, // HDMA1
+	0xFF52: 0xFF, // HDMA2
+	0xFF53: 0xFF, // HDMA3
+	0xFF54: 0xFF, // HDMA4
+	0xFF55: 0xFF, // HDMA5
+	0xFF56: 0x3E, // RP
+	0xFF70: 0xF8, // SVBK
+}
+
+// reset powers the console on, running the boot ROM if there is one
+func (c *Console) reset() {
+	c.cpu.Reset()
+	c.timer.Reset()
+	c.joypad.Reset()
+	c.ppu.Reset()
+	c.mem.io = [0x80]uint8{}
+	c.mem.ie = 0
+	c.mem.booting = c.mem.boot != nil
+
+	if !c.mem.booting {
+		c.skipBoot()
+	}
+}
+
+// CPU registers as the boot ROM leaves them
+type postBootRegisters struct {
+	af, bc, de, hl uint16
+	div            uint16 // internal divider, depends on how long the boot ROM ran
+}
+
+var postBootRegs = map[Model]postBootRegisters{
+	MODEL_DMG0: {0x0100, 0xFF13, 0x00C1, 0x8403, 0x1830},
+	MODEL_DMG:  {0x01B0, 0x0013, 0x00D8, 0x014D, 0xABCC},
+	MODEL_MGB:  {0xFFB0, 0x0013, 0x00D8, 0x014D, 0xABCC},
+	MODEL_SGB:  {0x0100, 0x0014, 0x0000, 0xC060, 0xD85C},
+	MODEL_SGB2: {0xFF00, 0x0014, 0x0000, 0xC060, 0xD85C},
+	MODEL_CGB:  {0x1180, 0x0000, 0xFF56, 0x000D, 0x1EA0},
+	MODEL_AGB:  {0x1100, 0x0100, 0xFF56, 0x000D, 0x1EA0},
+}
+
+// skipBoot sets the registers and I/O the model's boot ROM would have left
+func (c *Console) skipBoot() {
+	regs := postBootRegs[c.model]
+	r := &c.cpu.regs
+
+	r.SetAF(regs.af)
+	r.SetBC(regs.bc)
+	r.SetDE(regs.de)
+	r.SetHL(regs.hl)
+	r.sp = 0xFFFE
+	r.pc = 0x0100
+
+	switch {
+	case c.model == MODEL_DMG || c.model == MODEL_MGB:
+		// the header check leaves H and C set unless the header checksum is 0
+		if c.cart.Header.HeaderChecksum == 0 {
+			r.SetHalfCarry(false)
+			r.SetCarry(false)
+		}
+	case c.model.IsCGB() && !c.cgbMode():
+		// DMG compatibility mode, B holds the title checksum used to pick the colorization palette
+		r.SetDE(0x0008)
+		r.SetHL(0x007C)
+		r.b = 0
+		if c.nintendoLicensed() {
+			r.b = c.titleChecksum()
+		}
+		// the AGB boot ROM ends with an extra INC B
+		if c.model == MODEL_AGB {
+			r.b++
+			r.SetZero(r.b == 0)
+			r.SetHalfCarry(r.b&0x0F == 0)
+		}
+	}
+
+	for address, value := range dmgPostBootIO {
+		c.mem.io[address-UNUSED_END] = value
+	}
+	if c.model.IsSGB() {
+		c.mem.io[0xFF26-UNUSED_END] = 0xF0 // NR52
+	}
+	if c.model.IsCGB() {
+		for address, value := range cgbPostBootIO {
+			c.mem.io[address-UNUSED_END] = value
+		}
+		c.ppu.bootPalettes()
+	}
+
+	// registers owned by other parts of the console
+	c.mem.io[IF_ADDRESS-UNUSED_END] = 0x01
+	c.joypad.selected = 0x00
+	c.timer.div = regs.div
+
+	// the boot ROM hands over near the end of VBlank, where LY already reads 0
+	c.ppu.line = LINES_PER_FRAME - 1
+	c.ppu.ly = 0
+	c.ppu.compare = 0
+	c.ppu.dots = 400
+	c.ppu.mode = MODE_VBLANK
+}
+
+// nintendoLicensed reports whether the old (or new) licensee code is Nintendo's
+func (c *Console) nintendoLicensed() bool {
+	rom := c.cart.rom
+	return rom[0x14B] == 0x01 || (rom[0x14B] == 0x33 && rom[0x144] == '0' && rom[0x145] == '1')
+}
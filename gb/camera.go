@@ -0,0 +1,298 @@
+package gb
+
+import (
+	"image"
+	"image/color"
+)
+
+/*
+
+Pocket Camera (Game Boy Camera), Mitsubishi M64282FP image sensor
+
+	0000 - 1FFF RAM write enable, 0x0A enables writes, RAM is always readable
+	2000 - 3FFF ROM bank number, 6 bits
+	4000 - 5FFF RAM bank number (0x00 - 0x0F), 0x10 maps the sensor registers at A000 - A07F instead
+
+Sensor registers (mirrored every 0x80 bytes, only A000 is readable, the rest read 0x00)
+
+	A000        Bit 0 - Start capture, reads 1 while capturing
+	            Bits 1-2 - Edge mode, 0 = none, 1 = horizontal, 2 = vertical, 3 = both
+	A001        Bit 7 - N, skips the 512 cycle exposure offset
+	            Bits 0-4 - Gain (not emulated)
+	A002 - A003 Exposure time, big endian, in steps of 16 cycles
+	A004        Bits 4-6 - Edge enhancement ratio (50%, 75%, 100%, 125%, 200%, 300%, 400%, 500%)
+	            Bit 3 - Invert
+	            Bits 0-2 - Output reference voltage (not emulated)
+	A005        Zero point and voltage offset (not emulated)
+	A006 - A035 Dithering/contrast matrix, 3 thresholds for each pixel of a 4x4 tile
+
+A capture takes 32446 + 512 (unless N) + 16 * exposure cycles (1MHz), RAM reads return 0x00 until it ends.
+The 128x112 picture is then written to RAM bank 0 at A100 - AEFF as 2bpp tiles, 16 tiles per row.
+
+Each pixel is scaled by the exposure, edge enhanced, optionally inverted, then compared against the thresholds
+at its position in the matrix: at least the third threshold is white, the second light gray, the first dark gray,
+below that black.
+
+*/
+
+const CAMERA_WIDTH = 128
+const CAMERA_HEIGHT = 112
+
+const CAMERA_REGISTERS = 0x36
+const CAMERA_MATRIX = 0x06
+const CAMERA_IMAGE = 0x0100
+
+// CameraSource provides the picture the sensor sees, images of any size are scaled to 128x112 and converted to grayscale
+type CameraSource interface {
+	Image() image.Image
+}
+
+// CameraSourceFunc adapts a function to a CameraSource, e.g. a generated test pattern
+type CameraSourceFunc func() image.Image
+
+func (f CameraSourceFunc) Image() image.Image {
+	return f()
+}
+
+// StaticCameraSource always shows the same picture, e.g. a decoded PNG
+type StaticCameraSource struct {
+	Picture image.Image
+}
+
+func (s StaticCameraSource) Image() image.Image {
+	return s.Picture
+}
+
+// CameraTestPattern is a source of vertical bars fading from black to white, with a white border
+var CameraTestPattern = CameraSourceFunc(func() image.Image {
+	img := image.NewGray(image.Rect(0, 0, CAMERA_WIDTH, CAMERA_HEIGHT))
+	for y := 0; y < CAMERA_HEIGHT; y++ {
+		for x := 0; x < CAMERA_WIDTH; x++ {
+			value := uint8(x / 16 * 255 / 7)
+			if x < 4 || y < 4 || x >= CAMERA_WIDTH-4 || y >= CAMERA_HEIGHT-4 {
+				value = 0xFF
+			}
+			img.SetGray(x, y, color.Gray{value})
+		}
+	}
+	return img
+})
+
+type PocketCamera struct {
+	cart      *Cartridge
+	ramEnable bool
+	romBank   uint8
+	ramBank   uint8 // 0x10 selects the sensor registers
+	registers [CAMERA_REGISTERS]uint8
+	capturing int // cycles left in the current capture, 0 when idle
+	source    CameraSource
+}
+
+func NewPocketCamera(cart *Cartridge) *PocketCamera {
+	return &PocketCamera{
+		cart:    cart,
+		romBank: 1,
+	}
+}
+
+// SetCameraSource sets where the sensor gets its picture from, nil shows black
+func (m *PocketCamera) SetCameraSource(source CameraSource) {
+	m.source = source
+}
+
+// Step runs the capture, the picture is taken when it finishes
+func (m *PocketCamera) Step(ticks int) {
+	if m.capturing == 0 {
+		return
+	}
+
+	m.capturing -= ticks
+	if m.capturing <= 0 {
+		m.capturing = 0
+		m.registers[0] &^= 0x01
+		m.capture()
+	}
+}
+
+func (m *PocketCamera) ReadROM(address uint16) uint8 {
+	if address < ROM_BANK_SIZE {
+		return m.cart.rom[address]
+	}
+
+	bank := int(m.romBank) % (len(m.cart.rom) / ROM_BANK_SIZE)
+	return m.cart.rom[bank*ROM_BANK_SIZE+int(address-ROM_BANK_SIZE)]
+}
+
+func (m *PocketCamera) WriteROM(address uint16, value uint8) {
+	switch {
+	case address < 0x2000:
+		m.ramEnable = value&0x0F == 0x0A
+	case address < 0x4000:
+		m.romBank = value & 0x3F
+	case address < 0x6000:
+		m.ramBank = value & 0x1F
+	}
+}
+
+func (m *PocketCamera) ReadRAM(address uint16) uint8 {
+	if m.ramBank&0x10 != 0 {
+		if address&0x7F == 0 {
+			return m.registers[0]
+		}
+		return 0x00
+	}
+
+	if m.capturing > 0 || len(m.cart.ram) == 0 {
+		return 0x00
+	}
+	return m.cart.ram[(int(m.ramBank)*RAM_BANK_SIZE+int(address))%len(m.cart.ram)]
+}
+
+func (m *PocketCamera) WriteRAM(address uint16, value uint8) {
+	if m.ramBank&0x10 != 0 {
+		m.writeRegister(uint8(address&0x7F), value)
+		return
+	}
+
+	if !m.ramEnable || len(m.cart.ram) == 0 {
+		return
+	}
+	m.cart.ram[(int(m.ramBank)*RAM_BANK_SIZE+int(address))%len(m.cart.ram)] = value
+	m.cart.dirty = true
+}
+
+func (m *PocketCamera) writeRegister(register uint8, value uint8) {
+	if int(register) >= CAMERA_REGISTERS {
+		return
+	}
+
+	if register != 0 {
+		m.registers[register] = value
+		return
+	}
+
+	m.registers[0] = value & 0x07
+	if value&0x01 == 0 {
+		// clearing the start bit cancels the capture
+		m.capturing = 0
+		return
+	}
+
+	if m.capturing == 0 {
+		cycles := 32446 + 16*int(m.exposure())
+		if m.registers[1]&0x80 == 0 {
+			cycles += 512
+		}
+		m.capturing = cycles * 4
+	}
+}
+
+func (m *PocketCamera) exposure() uint16 {
+	return uint16(m.registers[2])<<8 | uint16(m.registers[3])
+}
+
+// edge enhancement ratios in 1/4 steps
+var cameraEdgeRatios = [8]int{2, 3, 4, 5, 8, 12, 16, 20}
+
+// capture takes a picture from the source and writes it to RAM as tiles
+func (m *PocketCamera) capture() {
+	var pixels [CAMERA_HEIGHT][CAMERA_WIDTH]int
+
+	// sensor, scaled by the exposure time
+	exposure := int(m.exposure())
+	if m.source != nil {
+		if img := m.source.Image(); img != nil {
+			bounds := img.Bounds()
+			for y := 0; y < CAMERA_HEIGHT; y++ {
+				for x := 0; x < CAMERA_WIDTH; x++ {
+					sx := bounds.Min.X + x*bounds.Dx()/CAMERA_WIDTH
+					sy := bounds.Min.Y + y*bounds.Dy()/CAMERA_HEIGHT
+					gray := color.GrayModel.Convert(img.At(sx, sy)).(color.Gray)
+					pixels[y][x] = int(gray.Y) * exposure / 0x1000
+				}
+			}
+		}
+	}
+
+	// edge enhancement, each pixel minus its neighbours along the selected axes
+	mode := m.registers[0] >> 1 & 0x03
+	ratio := cameraEdgeRatios[m.registers[4]>>4&0x07]
+	invert := m.registers[4]&0x08 != 0
+
+	var processed [CAMERA_HEIGHT][CAMERA_WIDTH]int
+	for y := 0; y < CAMERA_HEIGHT; y++ {
+		for x := 0; x < CAMERA_WIDTH; x++ {
+			value := pixels[y][x]
+			edge := 0
+			if mode&0x01 != 0 {
+				edge += 2*value - pixels[y][clampIndex(x-1, CAMERA_WIDTH)] - pixels[y][clampIndex(x+1, CAMERA_WIDTH)]
+			}
+			if mode&0x02 != 0 {
+				edge += 2*value - pixels[clampIndex(y-1, CAMERA_HEIGHT)][x] - pixels[clampIndex(y+1, CAMERA_HEIGHT)][x]
+			}
+			value += edge * ratio / 4
+
+			if value < 0 {
+				value = 0
+			} else if value > 0xFF {
+				value = 0xFF
+			}
+			if invert {
+				value = 0xFF - value
+			}
+			processed[y][x] = value
+		}
+	}
+
+	// dithering/contrast matrix, then out to RAM as 2bpp tiles
+	for y := 0; y < CAMERA_HEIGHT; y++ {
+		for x := 0; x < CAMERA_WIDTH; x++ {
+			thresholds := m.registers[CAMERA_MATRIX+((y%4)*4+x%4)*3:]
+			value := processed[y][x]
+
+			var shade uint8
+			switch {
+			case value >= int(thresholds[2]):
+				shade = 0
+			case value >= int(thresholds[1]):
+				shade = 1
+			case value >= int(thresholds[0]):
+				shade = 2
+			default:
+				shade = 3
+			}
+
+			tile := (y/8)*(CAMERA_WIDTH/8) + x/8
+			address := CAMERA_IMAGE + tile*16 + (y%8)*2
+			if address+1 >= len(m.cart.ram) {
+				continue
+			}
+
+			bit := uint8(0x80) >> uint(x%8)
+			m.cart.ram[address] = m.cart.ram[address]&^bit | -(shade&0x01)&bit
+			m.cart.ram[address+1] = m.cart.ram[address+1]&^bit | -(shade>>1)&bit
+		}
+	}
+	m.cart.dirty = true
+}
+
+// clampIndex keeps a coordinate inside 0 - size-1, edge pixels use themselves as their missing neighbour
+func clampIndex(value int, size int) int {
+	if value < 0 {
+		return 0
+	}
+	if value >= size {
+		return size - 1
+	}
+	return value
+}
+
+func (m *PocketCamera) saveState(s *stateWriter) {
+	s.write(m.ramEnable, m.romBank, m.ramBank, &m.registers, int32(m.capturing))
+}
+
+func (m *PocketCamera) loadState(s *stateReader) {
+	var capturing int32
+	s.read(&m.ramEnable, &m.romBank, &m.ramBank, &m.registers, &capturing)
+	m.capturing = int(capturing)
+}
@@ -1,4 +1,307 @@
 package gb
 
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+/*
+
+Cartridge Header (0x0100 - 0x014F)
+
+	0100 - 0103 Entry point
+	0104 - 0133 Nintendo logo
+	0134 - 0143 Title, upper case ASCII padded with 0s
+	013F - 0142 Manufacturer code (newer cartridges, shortens the title to 11 bytes)
+	0143        CGB flag, 0x80 = CGB enhanced, 0xC0 = CGB only
+	0144 - 0145 New licensee code
+	0146        SGB flag, 0x03 = SGB functions
+	0147        Cartridge type, selects the memory bank controller
+	0148        ROM size, 32KB << n
+	0149        RAM size
+	014A        Destination code, 0x00 = Japan, 0x01 = overseas
+	014B        Old licensee code
+	014C        Mask ROM version number
+	014D        Header checksum, checked by the boot ROM
+	014E - 014F Global checksum, big endian, not checked by hardware
+
+*/
+
+const HEADER_END = 0x0150
+
+type CartridgeHeader struct {
+	Title            string
+	ManufacturerCode string
+	CGBFlag          uint8
+	SGBFlag          uint8
+	Type             uint8
+	ROMSize          int // in bytes
+	RAMSize          int // in bytes
+	Destination      uint8
+	Version          uint8
+	HeaderChecksum   uint8
+	GlobalChecksum   uint16
+}
+
 type Cartridge struct {
+	Header CartridgeHeader
+	rom    []uint8
+	ram    []uint8
+	mapper Mapper // memory bank controller, handles all reads and writes
+	dirty  bool   // RAM written since the last battery save, set by the mapper when it stores a byte
+}
+
+// logo at 0x104 - 0x133, checked by the boot ROM
+var nintendoLogo = [48]uint8{
+	0xCE, 0xED, 0x66, 0x66, 0xCC, 0x0D, 0x00, 0x0B, 0x03, 0x73, 0x00, 0x83, 0x00, 0x0C, 0x00, 0x0D,
+	0x00, 0x08, 0x11, 0x1F, 0x88, 0x89, 0x00, 0x0E, 0xDC, 0xCC, 0x6E, 0xE6, 0xDD, 0xDD, 0xD9, 0x99,
+	0xBB, 0xBB, 0x67, 0x63, 0x6E, 0x0E, 0xEC, 0xCC, 0xDD, 0xDC, 0x99, 0x9F, 0xBB, 0xB9, 0x33, 0x3E,
+}
+
+// <----------------------------- CARTRIDGE TYPES -----------------------------> //
+
+type cartridgeType struct {
+	name    string
+	battery bool // external RAM (or RTC) is battery backed
+	rtc     bool // real time clock
+	rumble  bool // rumble motor
+}
+
+var cartridgeTypes = map[uint8]cartridgeType{
+	0x00: {"ROM ONLY", false, false, false},
+	0x01: {"MBC1", false, false, false},
+	0x02: {"MBC1+RAM", false, false, false},
+	0x03: {"MBC1+RAM+BATTERY", true, false, false},
+	0x05: {"MBC2", false, false, false},
+	0x06: {"MBC2+BATTERY", true, false, false},
+	0x08: {"ROM+RAM", false, false, false},
+	0x09: {"ROM+RAM+BATTERY", true, false, false},
+	0x0B: {"MMM01", false, false, false},
+	0x0C: {"MMM01+RAM", false, false, false},
+	0x0D: {"MMM01+RAM+BATTERY", true, false, false},
+	0x0F: {"MBC3+TIMER+BATTERY", true, true, false},
+	0x10: {"MBC3+TIMER+RAM+BATTERY", true, true, false},
+	0x11: {"MBC3", false, false, false},
+	0x12: {"MBC3+RAM", false, false, false},
+	0x13: {"MBC3+RAM+BATTERY", true, false, false},
+	0x19: {"MBC5", false, false, false},
+	0x1A: {"MBC5+RAM", false, false, false},
+	0x1B: {"MBC5+RAM+BATTERY", true, false, false},
+	0x1C: {"MBC5+RUMBLE", false, false, true},
+	0x1D: {"MBC5+RUMBLE+RAM", false, false, true},
+	0x1E: {"MBC5+RUMBLE+RAM+BATTERY", true, false, true},
+	0x20: {"MBC6", true, false, false},
+	0x22: {"MBC7+SENSOR+RUMBLE+RAM+BATTERY", true, false, true},
+	0xFC: {"POCKET CAMERA", true, false, false},
+	0xFD: {"BANDAI TAMA5", true, true, false},
+	0xFE: {"HuC3", true, true, false},
+	0xFF: {"HuC1+RAM+BATTERY", true, false, false},
+}
+
+// RAM sizes in bytes, indexed by the RAM size code at 0x149
+var ramSizes = map[uint8]int{
+	0x00: 0,
+	0x01: 0x800, // unofficial 2KB
+	0x02: 0x2000,
+	0x03: 0x8000,
+	0x04: 0x20000,
+	0x05: 0x10000,
+}
+
+// <----------------------------- ERRORS -----------------------------> //
+
+// TruncatedError is returned when the ROM is smaller than its header or the size the header declares
+type TruncatedError struct {
+	Size     int // bytes read
+	Expected int // bytes needed
+}
+
+func (e *TruncatedError) Error() string {
+	return fmt.Sprintf("cartridge truncated: got %d bytes, expected %d", e.Size, e.Expected)
+}
+
+// ChecksumError is returned when the header checksum at 0x14D does not match the header
+type ChecksumError struct {
+	Expected uint8 // checksum stored in the header
+	Actual   uint8 // checksum computed from the header
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("cartridge header checksum mismatch: header has 0x%02X, computed 0x%02X", e.Expected, e.Actual)
+}
+
+// UnsupportedMapperError is returned for cartridge types with no memory bank controller implementation
+type UnsupportedMapperError struct {
+	Type uint8
+}
+
+func (e *UnsupportedMapperError) Error() string {
+	if t, ok := cartridgeTypes[e.Type]; ok {
+		return fmt.Sprintf("unsupported cartridge type 0x%02X (%s)", e.Type, t.name)
+	}
+	return fmt.Sprintf("unknown cartridge type 0x%02X", e.Type)
+}
+
+// InvalidHeaderError is returned when a header field has a value that is not defined
+type InvalidHeaderError struct {
+	Field string
+	Value uint8
+}
+
+func (e *InvalidHeaderError) Error() string {
+	return fmt.Sprintf("invalid cartridge header: %s 0x%02X", e.Field, e.Value)
+}
+
+// <----------------------------- LOADING -----------------------------> //
+
+// LoadCartridge reads a ROM image, parses and validates its header
+func LoadCartridge(r io.Reader) (*Cartridge, error) {
+	rom, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rom) < HEADER_END {
+		return nil, &TruncatedError{Size: len(rom), Expected: HEADER_END}
+	}
+
+	header, err := parseHeader(rom)
+	if err != nil {
+		return nil, err
+	}
+
+	if menu, ok := mmm01Header(rom); ok {
+		header = menu
+	}
+
+	if len(rom) < header.ROMSize {
+		return nil, &TruncatedError{Size: len(rom), Expected: header.ROMSize}
+	}
+
+	cart := &Cartridge{
+		Header: header,
+		rom:    rom[:header.ROMSize],
+		ram:    make([]uint8, header.RAMSize),
+	}
+
+	cart.mapper, err = newMapper(cart)
+	if err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+func parseHeader(rom []uint8) (CartridgeHeader, error) {
+	header := CartridgeHeader{
+		CGBFlag:        rom[0x143],
+		SGBFlag:        rom[0x146],
+		Type:           rom[0x147],
+		Destination:    rom[0x14A],
+		Version:        rom[0x14C],
+		HeaderChecksum: rom[0x14D],
+		GlobalChecksum: uint16(rom[0x14E])<<8 | uint16(rom[0x14F]),
+	}
+
+	// the title runs up to the CGB flag when the cartridge has one
+	titleEnd := 0x144
+	if header.CGBFlag&0x80 != 0 {
+		titleEnd = 0x143
+
+		// newer cartridges use the last 4 bytes of the title for the manufacturer code
+		if code := string(rom[0x13F:0x143]); isManufacturerCode(code) {
+			header.ManufacturerCode = code
+			titleEnd = 0x13F
+		}
+	}
+
+	title := string(rom[0x134:titleEnd])
+	if end := strings.IndexByte(title, 0); end >= 0 {
+		title = title[:end]
+	}
+	header.Title = strings.TrimRight(title, " ")
+
+	if rom[0x148] > 0x08 {
+		return header, &InvalidHeaderError{Field: "ROM size", Value: rom[0x148]}
+	}
+	header.ROMSize = 0x8000 << rom[0x148]
+
+	ramSize, ok := ramSizes[rom[0x149]]
+	if !ok {
+		return header, &InvalidHeaderError{Field: "RAM size", Value: rom[0x149]}
+	}
+	header.RAMSize = ramSize
+
+	if checksum := headerChecksum(rom); checksum != header.HeaderChecksum {
+		return header, &ChecksumError{Expected: header.HeaderChecksum, Actual: checksum}
+	}
+
+	return header, nil
+}
+
+// manufacturer codes are 4 upper case letters or digits
+func isManufacturerCode(code string) bool {
+	for _, c := range code {
+		if !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// headerChecksum computes the checksum over 0x134 - 0x14C the same way the boot ROM does
+func headerChecksum(rom []uint8) uint8 {
+	var checksum uint8
+	for address := 0x134; address <= 0x14C; address++ {
+		checksum = checksum - rom[address] - 1
+	}
+	return checksum
+}
+
+// ValidGlobalChecksum reports whether the global checksum matches the sum of every other ROM byte,
+// hardware never checks this so plenty of working ROMs fail it
+func (cart *Cartridge) ValidGlobalChecksum() bool {
+	var sum uint16
+	for address, value := range cart.rom {
+		if address != 0x14E && address != 0x14F {
+			sum += uint16(value)
+		}
+	}
+	return sum == cart.Header.GlobalChecksum
+}
+
+// TypeName returns the cartridge type name, e.g. MBC1+RAM+BATTERY
+func (header CartridgeHeader) TypeName() string {
+	if t, ok := cartridgeTypes[header.Type]; ok {
+		return t.name
+	}
+	return fmt.Sprintf("UNKNOWN (0x%02X)", header.Type)
+}
+
+// HasBattery reports whether the cartridge keeps its RAM (or RTC) powered by a battery
+func (header CartridgeHeader) HasBattery() bool {
+	return cartridgeTypes[header.Type].battery
+}
+
+// <----------------------------- MEMORY -----------------------------> //
+
+// ReadROM reads from the ROM area (0x0000 - 0x7FFF) through the mapper
+func (cart *Cartridge) ReadROM(address uint16) uint8 {
+	return cart.mapper.ReadROM(address)
+}
+
+// WriteROM passes writes to the ROM area to the mapper's registers
+func (cart *Cartridge) WriteROM(address uint16, value uint8) {
+	cart.mapper.WriteROM(address, value)
+}
+
+// ReadRAM reads from external RAM (0xA000 - 0xBFFF) through the mapper, address is relative to 0xA000
+func (cart *Cartridge) ReadRAM(address uint16) uint8 {
+	return cart.mapper.ReadRAM(address)
+}
+
+// WriteRAM writes to external RAM (0xA000 - 0xBFFF) through the mapper, address is relative to 0xA000
+func (cart *Cartridge) WriteRAM(address uint16, value uint8) {
+	cart.mapper.WriteRAM(address, value)
 }
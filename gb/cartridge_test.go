@@ -0,0 +1,31 @@
+package gb
+
+import "testing"
+
+func TestWriteRAMDirty(t *testing.T) {
+	tests := []struct {
+		name     string
+		cartType uint8
+		enable   bool // write 0x0A to 0000 first
+		dirty    bool
+	}{
+		{"MBC1 RAM disabled", 0x03, false, false},
+		{"MBC1 RAM enabled", 0x03, true, true},
+		{"MBC3 RAM disabled", 0x13, false, false},
+		{"MBC3 RAM enabled", 0x13, true, true},
+		{"MBC5 RAM disabled", 0x1B, false, false},
+		{"MBC5 RAM enabled", 0x1B, true, true},
+	}
+
+	for _, test := range tests {
+		c := newROMConsole(t, testROM(test.cartType, 0x02), MODEL_DMG)
+		if test.enable {
+			c.mem.Write8(0x0000, 0x0A)
+		}
+		c.mem.Write8(0xA000, 0x42)
+
+		if c.cart.dirty != test.dirty {
+			t.Errorf("%s: dirty = %v, want %v", test.name, c.cart.dirty, test.dirty)
+		}
+	}
+}
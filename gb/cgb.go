@@ -0,0 +1,136 @@
+package gb
+
+import "image/color"
+
+/*
+
+CGB video
+
+	FF4F - VBK  VRAM bank for the CPU, bit 0 (see memory.go), the PPU reads both banks
+	FF68 - BCPS BG palette index
+		Bit 7 - Increment the index after writing BCPD
+		Bit 0-5 - Index into BG palette RAM
+	FF69 - BCPD BG palette data, the byte of BG palette RAM at the index
+	FF6A - OCPS Sprite palette index, like BCPS
+	FF6B - OCPD Sprite palette data, like BCPD
+
+There are 8 BG and 8 sprite palettes of 4 colors, each color 2 bytes little endian:
+bits 0-4 red, bits 5-9 green, bits 10-14 blue.
+
+VRAM bank 1 holds more tile data, and at 9800 - 9FFF the attributes of each tile in the tile maps
+
+	Bit 7 - BG and window color 1-3 is drawn over sprites
+	Bit 6 - Y flip
+	Bit 5 - X flip
+	Bit 3 - Tile VRAM bank
+	Bit 0-2 - Palette
+
+Sprites use attribute bits 0-2 for the palette and bit 3 for the tile VRAM bank. Overlapping sprites are
+drawn by OAM position alone, and with LCDC bit 0 clear sprites are drawn over everything, otherwise
+a BG color 1-3 is drawn over the sprite when either the tile attributes or the sprite ask for it.
+
+DMG mode (games without CGB support) draws like a DMG, then uses the shades from BGP, OBP0 and OBP1
+as colors of BG palette 0 and sprite palettes 0 and 1. The boot ROM picks those colors from the
+title checksum of Nintendo's games, without a boot ROM they're picked the same way (see cgb_compat.go).
+
+*/
+
+const BCPS_ADDRESS = 0xFF68
+const BCPD_ADDRESS = 0xFF69
+const OCPS_ADDRESS = 0xFF6A
+const OCPD_ADDRESS = 0xFF6B
+
+const PALETTE_INCREMENT = 0x80
+
+const (
+	BG_PALETTE  = 0x07
+	BG_BANK     = 0x08
+	BG_X_FLIP   = 0x20
+	BG_Y_FLIP   = 0x40
+	BG_PRIORITY = 0x80
+)
+
+const CGB_WHITE = 0x7FFF
+
+// palettes returns the palette RAM and index register for a palette register
+func (ppu *PPU) palettes(address uint16) (*[64]uint8, uint16) {
+	if address <= BCPD_ADDRESS {
+		return &ppu.bgPalettes, BCPS_ADDRESS
+	}
+	return &ppu.objPalettes, OCPS_ADDRESS
+}
+
+func (ppu *PPU) readPalette(address uint16) uint8 {
+	if !ppu.console.cgbRegisters() {
+		return 0xFF
+	}
+
+	ram, indexAddress := ppu.palettes(address)
+	index := ppu.register(indexAddress)
+	if address == indexAddress {
+		// bit 6 is unused
+		return index | 0x40
+	}
+	return ram[index&0x3F]
+}
+
+func (ppu *PPU) writePalette(address uint16, value uint8) {
+	if !ppu.console.cgbRegisters() {
+		return
+	}
+
+	ram, indexAddress := ppu.palettes(address)
+	if address == indexAddress {
+		ppu.mem.io[address-UNUSED_END] = value & 0xBF
+		return
+	}
+
+	index := ppu.register(indexAddress)
+	ram[index&0x3F] = value
+	if index&PALETTE_INCREMENT != 0 {
+		ppu.mem.io[indexAddress-UNUSED_END] = PALETTE_INCREMENT | (index+1)&0x3F
+	}
+}
+
+// paletteColor returns a 15-bit color from palette RAM
+func paletteColor(ram *[64]uint8, palette uint8, color uint8) uint16 {
+	index := int(palette&0x07)*8 + int(color)*2
+	return uint16(ram[index]) | uint16(ram[index+1])<<8
+}
+
+func setPaletteColor(ram *[64]uint8, palette uint8, color uint8, value uint16) {
+	index := int(palette&0x07)*8 + int(color)*2
+	ram[index] = uint8(value)
+	ram[index+1] = uint8(value >> 8)
+}
+
+// bootPalettes fills palette RAM the way the CGB boot ROM leaves it, white in CGB mode
+// and the game's colors in DMG mode
+func (ppu *PPU) bootPalettes() {
+	for palette := uint8(0); palette < 8; palette++ {
+		for color := uint8(0); color < 4; color++ {
+			setPaletteColor(&ppu.bgPalettes, palette, color, CGB_WHITE)
+			setPaletteColor(&ppu.objPalettes, palette, color, CGB_WHITE)
+		}
+	}
+
+	if ppu.console.cgbMode() {
+		return
+	}
+
+	combination := ppu.console.compatCombination()
+	for color := uint8(0); color < 4; color++ {
+		setPaletteColor(&ppu.bgPalettes, 0, color, compatColors[combination.bg+color])
+		setPaletteColor(&ppu.objPalettes, 0, color, compatColors[combination.obj0+color])
+		setPaletteColor(&ppu.objPalettes, 1, color, compatColors[combination.obj1+color])
+	}
+}
+
+// rgba converts a 15-bit CGB color to 8 bits per channel
+func rgba(value uint16) color.RGBA {
+	scale := func(c uint16) uint8 {
+		c &= 0x1F
+		return uint8(c<<3 | c>>2)
+	}
+	return color.RGBA{scale(value), scale(value >> 5), scale(value >> 10), 0xFF}
+}
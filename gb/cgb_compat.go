@@ -0,0 +1,181 @@
+package gb
+
+/*
+
+CGB boot ROM palettes for DMG games
+
+In DMG mode the CGB boot ROM colors BG palette 0 and sprite palettes 0 and 1 for the game. Only games
+with Nintendo's licensee code get their own colors, the rest get combination 0.
+
+	1. The title checksum is the sum of the title bytes, 0134 - 0143
+	2. It's looked up in titleChecksums, the first 65 entries are unique, the other 29 share
+	   a checksum with another game and only match when the 4th title letter (0137) is the
+	   entry's letter in titleLetters
+	3. The matching entry of checksumCombinations picks a combination of palettes
+	4. The combination gives the offsets of the sprite 0, sprite 1 and BG colors in compatColors,
+	   most start at a palette but a few start in the middle of one
+
+No match picks combination 0. The real boot ROM also lets the player pick a combination with the
+buttons while the logo shows, which can't happen without it.
+
+*/
+
+// 4-color palettes of the boot ROM
+var compatColors = [...]uint16{
+	0x7FFF, 0x32BF, 0x00D0, 0x0000,
+	0x639F, 0x4279, 0x15B0, 0x04CB,
+	0x7FFF, 0x6E31, 0x454A, 0x0000,
+	0x7FFF, 0x1BEF, 0x0200, 0x0000,
+	0x7FFF, 0x421F, 0x1CF2, 0x0000,
+	0x7FFF, 0x5294, 0x294A, 0x0000,
+	0x7FFF, 0x03FF, 0x012F, 0x0000,
+	0x7FFF, 0x03EF, 0x01D6, 0x0000,
+	0x7FFF, 0x42B5, 0x3DC8, 0x0000,
+	0x7E74, 0x03FF, 0x0180, 0x0000,
+	0x67FF, 0x77AC, 0x1A13, 0x2D6B,
+	0x7ED6, 0x4BFF, 0x2175, 0x0000,
+	0x53FF, 0x4A5F, 0x7E52, 0x0000,
+	0x4FFF, 0x7ED2, 0x3A4C, 0x1CE0,
+	0x03ED, 0x7FFF, 0x255F, 0x0000,
+	0x036A, 0x021F, 0x03FF, 0x7FFF,
+	0x7FFF, 0x01DF, 0x0112, 0x0000,
+	0x231F, 0x035F, 0x00F2, 0x0009,
+	0x7FFF, 0x03EA, 0x011F, 0x0000,
+	0x299F, 0x001A, 0x000C, 0x0000,
+	0x7FFF, 0x027F, 0x001F, 0x0000,
+	0x7FFF, 0x03E0, 0x0206, 0x0120,
+	0x7FFF, 0x7EEB, 0x001F, 0x7C00,
+	0x7FFF, 0x3FFF, 0x7E00, 0x001F,
+	0x7FFF, 0x03FF, 0x001F, 0x0000,
+	0x03FF, 0x001F, 0x000C, 0x0000,
+	0x7FFF, 0x033F, 0x0193, 0x0000,
+	0x0000, 0x4200, 0x037F, 0x7FFF,
+	0x7FFF, 0x7E8C, 0x7C00, 0x0000,
+	0x7FFF, 0x1BEF, 0x6180, 0x0000,
+}
+
+// compatCombination holds offsets into compatColors
+type compatCombination struct {
+	obj0, obj1, bg uint8
+}
+
+// wholePalettes combines palettes from their start
+func wholePalettes(obj0, obj1, bg uint8) compatCombination {
+	return compatCombination{obj0 * 4, obj1 * 4, bg * 4}
+}
+
+var compatCombinations = [...]compatCombination{
+	wholePalettes(4, 4, 29),
+	wholePalettes(18, 18, 18),
+	wholePalettes(20, 20, 20),
+	wholePalettes(24, 24, 24),
+	wholePalettes(9, 9, 9),
+	wholePalettes(0, 0, 0),
+	wholePalettes(27, 27, 27),
+	wholePalettes(5, 5, 5),
+	wholePalettes(12, 12, 12),
+	wholePalettes(26, 26, 26),
+	wholePalettes(16, 8, 8),
+	wholePalettes(4, 28, 28),
+	wholePalettes(4, 2, 2),
+	wholePalettes(3, 4, 4),
+	wholePalettes(4, 29, 29),
+	wholePalettes(28, 4, 28),
+	wholePalettes(2, 17, 2),
+	wholePalettes(16, 16, 8),
+	wholePalettes(4, 4, 7),
+	wholePalettes(4, 4, 18),
+	wholePalettes(4, 4, 20),
+	wholePalettes(19, 19, 9),
+	{4*4 - 1, 4*4 - 1, 11 * 4},
+	wholePalettes(17, 17, 2),
+	wholePalettes(4, 4, 2),
+	wholePalettes(4, 4, 3),
+	wholePalettes(28, 28, 0),
+	wholePalettes(3, 3, 0),
+	wholePalettes(0, 0, 1),
+	wholePalettes(18, 22, 18),
+	wholePalettes(20, 22, 20),
+	wholePalettes(24, 22, 24),
+	wholePalettes(16, 22, 8),
+	wholePalettes(17, 4, 13),
+	{28*4 - 1, 0 * 4, 14 * 4},
+	{28*4 - 1, 4 * 4, 15 * 4},
+	wholePalettes(19, 22, 9),
+	wholePalettes(16, 28, 10),
+	wholePalettes(4, 23, 28),
+	wholePalettes(17, 22, 2),
+	wholePalettes(4, 0, 2),
+	wholePalettes(4, 28, 3),
+	wholePalettes(28, 3, 0),
+	wholePalettes(3, 28, 4),
+	wholePalettes(21, 28, 4),
+	wholePalettes(3, 28, 0),
+	wholePalettes(25, 3, 28),
+	wholePalettes(0, 28, 8),
+	wholePalettes(4, 3, 28),
+	wholePalettes(28, 3, 6),
+	wholePalettes(4, 28, 29),
+}
+
+// title checksums of the games the boot ROM knows
+var titleChecksums = [...]uint8{
+	0x00, 0x88, 0x16, 0x36, 0xD1, 0xDB, 0xF2, 0x3C, 0x8C, 0x92, 0x3D, 0x5C, 0x58, 0xC9, 0x3E, 0x70,
+	0x1D, 0x59, 0x69, 0x19, 0x35, 0xA8, 0x14, 0xAA, 0x75, 0x95, 0x99, 0x34, 0x6F, 0x15, 0xFF, 0x97,
+	0x4B, 0x90, 0x17, 0x10, 0x39, 0xF7, 0xF6, 0xA2, 0x49, 0x4E, 0x43, 0x68, 0xE0, 0x8B, 0xF0, 0xCE,
+	0x0C, 0x29, 0xE8, 0xB7, 0x86, 0x9A, 0x52, 0x01, 0x9D, 0x71, 0x9C, 0xBD, 0x5D, 0x6D, 0x67, 0x3F,
+	0x6B,
+
+	// shared checksums, told apart by the 4th title letter
+	0xB3, 0x46, 0x28, 0xA5, 0xC6, 0xD3, 0x27, 0x61, 0x18, 0x66, 0x6A, 0xBF, 0x0D, 0xF4,
+	0xB3, 0x46, 0x28, 0xA5, 0xC6, 0xD3, 0x27, 0x61, 0x18, 0x66, 0x6A, 0xBF, 0x0D, 0xF4, 0xB3,
+}
+
+// first entry of titleChecksums that needs the 4th title letter to match
+const FIRST_SHARED_CHECKSUM = 65
+
+// 4th title letters of the shared checksum entries
+const titleLetters = "BEFAARBEKEK R-URAR INAILICE R"
+
+// combination for each entry of titleChecksums
+var checksumCombinations = [...]uint8{
+	0, 4, 5, 35, 34, 3, 31, 15, 10, 5, 19, 36, 7, 37, 30, 44,
+	21, 32, 31, 20, 5, 33, 13, 14, 5, 29, 5, 18, 9, 3, 2, 26,
+	25, 25, 41, 42, 26, 45, 42, 45, 36, 38, 26, 42, 30, 41, 34, 34,
+	5, 42, 6, 5, 33, 25, 42, 42, 40, 2, 16, 25, 42, 42, 5, 0,
+	39,
+
+	36, 22, 25, 6, 32, 12, 36, 11, 39, 18, 39, 24, 31, 50,
+	17, 46, 6, 27, 0, 47, 41, 41, 0, 0, 19, 34, 23, 18, 29,
+}
+
+// titleChecksum returns the sum of the title bytes, which the CGB boot ROM leaves in B
+func (c *Console) titleChecksum() uint8 {
+	var sum uint8
+	for _, value := range c.cart.rom[0x134:0x144] {
+		sum += value
+	}
+	return sum
+}
+
+// compatCombination returns the palette combination the CGB boot ROM picks for the game
+func (c *Console) compatCombination() compatCombination {
+	if !c.nintendoLicensed() {
+		return compatCombinations[0]
+	}
+
+	checksum := c.titleChecksum()
+	letter := c.cart.rom[0x137]
+
+	for i, value := range titleChecksums {
+		if value != checksum {
+			continue
+		}
+		if i >= FIRST_SHARED_CHECKSUM && titleLetters[i-FIRST_SHARED_CHECKSUM] != letter {
+			continue
+		}
+		return compatCombinations[checksumCombinations[i]]
+	}
+
+	return compatCombinations[0]
+}
@@ -1,28 +1,312 @@
 package gb
 
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
 // The Console puts all the Gameboy parts together.
 
 type Console struct {
-	cpu *CPU // Gameboy CPU
-	ppu *PPU // Gameboy PPU
-	apu *APU // Gameboy APU
+	cpu    *CPU       // Gameboy CPU
+	ppu    *PPU       // Gameboy PPU
+	apu    *APU       // Gameboy APU
+	mem    *MemoryMap // memory map shared by all the parts
+	cart   *Cartridge // inserted cartridge
+	timer  *Timer     // DIV/TIMA timer
+	joypad *Joypad    // button input
+	model  Model      // hardware model
+
+	frames     uint64 // frames run since power on
+	frameTicks int    // cycles into the current frame
+
+	savePath   string // battery save file, empty when the cartridge has no battery
+	sinceFlush int    // cycles since the battery save was last flushed
+	flushErr   error  // latest failed flush, nil once a flush succeeds
+	stateDir   string // directory for save slots, empty for the default
+	stateErr   error  // state load that couldn't be rolled back, which locked the console
+
+	palette atomic.Value // Palette for Frame, set from any goroutine
+}
+
+// Option configures a console created by NewConsole
+type Option func(*options)
+
+type options struct {
+	bootROM  string // path to a boot ROM, empty to skip the boot ROM
+	model    Model
+	renderer Renderer
+	palette  *Palette // nil for PALETTE_GRAY
+	rtcClock Clock    // nil for emulated cycles
+}
+
+// WithBootROM runs the boot ROM at path on power on, it must be the boot ROM of the model,
+// with the model picked automatically a CGB boot ROM makes the console a CGB
+func WithBootROM(path string) Option {
+	return func(o *options) {
+		o.bootROM = path
+	}
+}
+
+// WithModel sets the hardware model to emulate, by default it's picked from the cartridge header
+func WithModel(model Model) Option {
+	return func(o *options) {
+		o.model = model
+	}
+}
+
+// WithRenderer picks how the PPU draws, RENDERER_SCANLINE by default. Both draw ordinary games the same,
+// RENDERER_FIFO also gets mid-line register writes and mode 3 timing right at some cost in speed
+func WithRenderer(renderer Renderer) Option {
+	return func(o *options) {
+		o.renderer = renderer
+	}
+}
+
+// WithPalette sets the colors Frame draws the 4 DMG shades with, see SetPalette
+func WithPalette(palette Palette) Option {
+	return func(o *options) {
+		o.palette = &palette
+	}
+}
 
+// WithRTCClock makes the cartridge's real time clock follow the given clock, see SetRTCClock.
+// It's set before the battery save is loaded, so the clock catches up on the time since the save
+func WithRTCClock(clock Clock) Option {
+	return func(o *options) {
+		o.rtcClock = clock
+	}
 }
 
-func NewConsole(path string) (*Console, error) {
+// NewConsole loads the ROM at path and creates a console with it inserted
+func NewConsole(path string, opts ...Option) (*Console, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var boot []uint8
+	if o.bootROM != "" {
+		var err error
+		if boot, err = loadBootROM(o.bootROM); err != nil {
+			return nil, err
+		}
+	}
+
 	// load cartridge from path
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cart, err := LoadCartridge(file)
+	if err != nil {
+		return nil, err
+	}
+
+	model, err := pickModel(o.model, boot, cart.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newConsole(cart, boot, model, o.renderer)
+	if o.palette != nil {
+		c.SetPalette(*o.palette)
+	}
+	if o.rtcClock != nil {
+		c.SetRTCClock(o.rtcClock)
+	}
+
+	if cart.Header.HasBattery() {
+		c.savePath = strings.TrimSuffix(path, filepath.Ext(path)) + ".sav"
+		if err := c.Load(); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// pickModel resolves MODEL_AUTO and checks the boot ROM, if any, belongs to the model
+func pickModel(model Model, boot []uint8, header CartridgeHeader) (Model, error) {
+	if model == MODEL_AUTO {
+		model = detectModel(header)
+
+		// CGB games fall back to DMG mode with a DMG family boot ROM
+		if boot != nil && len(boot) == CGB_BOOT_ROM_SIZE {
+			model = MODEL_CGB
+		} else if boot != nil && model.IsCGB() {
+			model = MODEL_DMG
+		}
+	}
+
+	if model > MODEL_AGB {
+		return model, fmt.Errorf("unknown model %v", model)
+	}
+
+	if boot != nil && len(boot) != model.bootROMSize() {
+		return model, &BootROMSizeError{Size: len(boot), Model: model}
+	}
+
+	return model, nil
+}
+
+// newConsole creates all the Gameboy parts, connects them through a shared memory map and powers on,
+// boot is the boot ROM or nil
+func newConsole(cart *Cartridge, boot []uint8, model Model, renderer Renderer) *Console {
+	c := &Console{cart: cart, model: model}
+	c.palette.Store(PALETTE_GRAY)
 
-	return nil, nil
+	c.mem = &MemoryMap{console: c, cart: cart, boot: boot}
+	c.cpu = NewCPU(c.mem)
+	c.ppu = NewPPU(c.mem, c, renderer)
+	c.apu = &APU{}
+	c.timer = &Timer{mem: c.mem}
+	c.joypad = &Joypad{mem: c.mem}
+
+	if m, ok := cart.mapper.(tiltMapper); ok {
+		m.SetTiltSource(c.joypad.Tilt)
+	}
+
+	c.reset()
+
+	return c
 }
 
+// Step runs a single CPU instruction (or interrupt dispatch) and advances the rest of the console
+// by the same number of clock cycles, returns the number of cycles taken
 func (c *Console) Step() int {
-	return 0
+	// everything but the joypad and cartridge is stopped, report the time as passing so frame loops keep running
+	ticks := 4
+
+	if !c.cpu.stopped {
+		start := c.cpu.ticks
+
+		c.cpu.Step()
+		c.cpu.HandleInterrupts()
+
+		ticks = int(c.cpu.ticks - start)
+		c.timer.Step(ticks)
+	}
+
+	// cartridge hardware like the RTC runs in real time, at half the CPU's rate in double speed
+	realTicks := ticks
+	if c.cpu.doubleSpeed {
+		realTicks /= 2
+	}
+
+	// STOP stops the LCD along with the CPU
+	if !c.cpu.stopped {
+		c.ppu.Step(realTicks)
+	}
+
+	if m, ok := c.cart.mapper.(clockedMapper); ok {
+		m.Step(realTicks)
+	}
+
+	c.frameTicks += realTicks
+	if c.frameTicks >= DOTS_PER_FRAME {
+		c.frameTicks -= DOTS_PER_FRAME
+		c.frames++
+	}
+
+	// flush the battery save every few seconds, so a crash loses little progress, failed flushes are retried
+	c.sinceFlush += realTicks
+	if c.sinceFlush >= SAVE_FLUSH_SECONDS*int(CLOCK_SPEED) {
+		c.sinceFlush = 0
+		c.flushErr = c.flush()
+	}
+
+	return ticks
 }
 
-func (c *Console) Save() {
+// StepFrame runs until the PPU finishes a frame, or a frame's worth of time passes while the LCD is off,
+// returns the number of cycles taken
+func (c *Console) StepFrame() int {
+	ticks := 0
+	start := c.frames
+
+	for !c.ppu.FrameReady() {
+		ticks += c.Step()
+
+		// the LCD is off or stopped, so no frame is coming
+		if c.frames != start && (!c.ppu.enabled() || c.cpu.stopped) {
+			break
+		}
+	}
+
+	return ticks
+}
 
+// Locked returns a LockupError once the game has run an undefined opcode, which hangs the CPU until the
+// console is powered off, the rest of the console keeps running. After a state load that couldn't be
+// rolled back it returns the StateRollbackError instead
+func (c *Console) Locked() error {
+	if c.stateErr != nil {
+		return c.stateErr
+	}
+	return c.cpu.Locked()
 }
 
-func (c *Console) Load() {
+// Frames returns the number of frames run since power on
+func (c *Console) Frames() uint64 {
+	return c.frames
+}
+
+// Press presses a button, which wakes the CPU from STOP if the game selected the button's group in JOYP
+func (c *Console) Press(button Button) {
+	c.joypad.Press(button)
+}
+
+// Release releases a button
+func (c *Console) Release(button Button) {
+	c.joypad.Release(button)
+}
+
+// SetRTCClock makes the cartridge's real time clock follow the given clock instead of emulated cycles,
+// nil switches back to emulated cycles, it has no effect on cartridges without an RTC
+func (c *Console) SetRTCClock(clock Clock) {
+	if m, ok := c.cart.mapper.(wallClockMapper); ok {
+		m.SetClock(clock)
+	}
+}
+
+// SetTilt sets how far the console is tilted, x and y are in g with positive x tilted right and
+// positive y tilted towards the player, only cartridges with an accelerometer read it
+func (c *Console) SetTilt(x, y float64) {
+	c.joypad.SetTilt(x, y)
+}
+
+// SetCameraSource sets the picture seen by the Pocket Camera's sensor, it has no effect on other cartridges
+func (c *Console) SetCameraSource(source CameraSource) {
+	if m, ok := c.cart.mapper.(cameraMapper); ok {
+		m.SetCameraSource(source)
+	}
+}
+
+// SetIRCallback sets the function called when the cartridge's IR LED turns on or off,
+// it is never called for cartridges without an IR port
+func (c *Console) SetIRCallback(callback func(on bool)) {
+	if m, ok := c.cart.mapper.(irMapper); ok {
+		m.IR().SetCallback(callback)
+	}
+}
+
+// SetIRLight sets whether the cartridge's IR receiver sees light, it has no effect on cartridges without an IR port
+func (c *Console) SetIRLight(on bool) {
+	if m, ok := c.cart.mapper.(irMapper); ok {
+		m.IR().SetLight(on)
+	}
+}
 
+// SetRumbleCallback sets the function called when the cartridge's rumble motor turns on or off,
+// it is never called for cartridges without rumble
+func (c *Console) SetRumbleCallback(callback func(on bool)) {
+	if m, ok := c.cart.mapper.(rumbleMapper); ok {
+		m.SetRumbleCallback(callback)
+	}
 }
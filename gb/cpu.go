@@ -33,12 +33,21 @@ type Instruction struct {
 }
 
 type CPU struct {
-	regs       Registers
-	mem        MemoryMap
-	table      [256]Instruction
-	ticksTable [256]uint8
-	ticks      uint32
-	stopped    bool
+	regs         Registers
+	mem          *MemoryMap
+	table        [256]Instruction
+	ticksTable   [256]uint8
+	cbTable      [256]Instruction
+	cbTicksTable [256]uint8
+	ticks        uint32
+	stopped      bool
+	locked       bool  // set by undefined opcodes, only a reset recovers
+	ime          bool  // interrupt master enable
+	imeDelay     uint8 // EI enables IME after the following instruction
+	halted       bool  // set by HALT, cleared when an interrupt is pending
+	haltBug      bool  // HALT with IME off and an interrupt pending, the next opcode fetch does not increment PC
+	doubleSpeed  bool  // CGB double speed mode (KEY1 bit 7)
+	speedSwitch  bool  // CGB speed switch armed, performed by the next STOP (KEY1 bit 0)
 }
 
 // <----------------------------- REGISTERS -----------------------------> //
@@ -58,6 +67,10 @@ The Gameboy CPU has the following registers:
 	(i.e. 2 bytes) at the same time
 */
 
+func (r *Registers) GetAF() uint16 {
+	return (uint16(r.a) << 8) | uint16(r.f)
+}
+
 func (r *Registers) GetBC() uint16 {
 	return (uint16(r.b) << 8) | uint16(r.c)
 }
@@ -70,6 +83,11 @@ func (r *Registers) GetHL() uint16 {
 	return (uint16(r.h) << 8) | uint16(r.l)
 }
 
+func (r *Registers) SetAF(value uint16) {
+	r.a = uint8((value & 0xFF00) >> 8)
+	r.f = uint8(value & 0xF0) // lower nibble of flags is always 0
+}
+
 func (r *Registers) SetBC(value uint16) {
 	r.b = uint8((value & 0xFF00) >> 8)
 	r.c = uint8(value & 0xFF)
@@ -151,28 +169,43 @@ func (cpu *CPU) ADD(address *uint8, value uint8) {
 	// Add the value to the accumulator and set the flags
 	result := uint16(*address) + uint16(value)
 
+	// half carry uses the operands, so check before overwriting address
+	cpu.regs.SetHalfCarry(((*address & 0x0F) + (value & 0x0F)) > 0xF)
+
 	// set address to the result
 	*address = uint8(result & 0xFF)
 
 	cpu.regs.SetCarry((result & 0xff00) != 0)
 	cpu.regs.SetZero(*address == 0)
-	cpu.regs.SetHalfCarry(((*address & 0x0F) + (value & 0x0F)) > 0xF)
 	cpu.regs.SetSubtract(false)
 
 }
 
-// ADD - Add (w/ 16-bit address)
-func (cpu *CPU) ADD_16(address *uint16, value uint16) {
-	// Add the value to the accumulator and set the flags
-	result := uint32(*address + value)
+// ADD_16 - Add to HL (w/ 16-bit value), the zero flag is not affected
+func (cpu *CPU) ADD_16(value uint16) {
+	hl := cpu.regs.GetHL()
 
-	// set address to the result
-	*address = uint16(result & 0xFFFF)
+	// widen before adding so the carry out of bit 15 is kept
+	result := uint32(hl) + uint32(value)
 
+	cpu.regs.SetSubtract(false)
+	cpu.regs.SetHalfCarry(((hl & 0x0FFF) + (value & 0x0FFF)) > 0x0FFF)
 	cpu.regs.SetCarry((result & 0xFFFF0000) != 0)
-	cpu.regs.SetZero(*address == 0)
-	cpu.regs.SetHalfCarry(((*address & 0x0F) + (value & 0x0F)) > 0xF)
+
+	cpu.regs.SetHL(uint16(result & 0xFFFF))
+}
+
+// ADD_SP - Add signed 8-bit immediate to SP, returns the result (used by ADD SP, r8 and LD HL, SP+r8)
+func (cpu *CPU) ADD_SP(value uint8) uint16 {
+	result := uint16(int32(cpu.regs.sp) + int32(int8(value)))
+
+	// half carry and carry are computed on the low byte as an unsigned add
+	cpu.regs.SetZero(false)
 	cpu.regs.SetSubtract(false)
+	cpu.regs.SetHalfCarry(((cpu.regs.sp & 0x0F) + (uint16(value) & 0x0F)) > 0x0F)
+	cpu.regs.SetCarry(((cpu.regs.sp & 0xFF) + uint16(value)) > 0xFF)
+
+	return result
 }
 
 // ADC - Add with Carry
@@ -181,7 +214,7 @@ func (cpu *CPU) ADC(value uint8) {
 	// add value of carry flag to value, accounting for overflow with uint16
 	result := uint16(value) + uint16(cpu.regs.a) + uint16(cpu.regs.GetCarry())
 
-	cpu.regs.SetZero(result == 0)
+	cpu.regs.SetZero((result & 0xFF) == 0)
 	cpu.regs.SetSubtract(false)
 	cpu.regs.SetHalfCarry(((cpu.regs.a & 0x0F) + (value & 0x0F) + cpu.regs.GetCarry()) > 0xF)
 	cpu.regs.SetCarry((result & 0xff00) != 0)
@@ -205,13 +238,15 @@ func (cpu *CPU) SUB(value uint8) {
 // SBC - Subtract with Carry
 func (cpu *CPU) SBC(value uint8) {
 
-	newValue := value + cpu.regs.GetCarry()
+	// value + carry can overflow a byte, so compare using wider integers
+	carry := int(cpu.regs.GetCarry())
+	result := int(cpu.regs.a) - int(value) - carry
 
-	cpu.regs.SetCarry(cpu.regs.a < newValue)
-	cpu.regs.SetHalfCarry((cpu.regs.a & 0x0F) < (newValue & 0x0F))
+	cpu.regs.SetCarry(result < 0)
+	cpu.regs.SetHalfCarry(int(cpu.regs.a&0x0F)-int(value&0x0F)-carry < 0)
 	cpu.regs.SetSubtract(true)
 
-	cpu.regs.a -= newValue
+	cpu.regs.a = uint8(result)
 	cpu.regs.SetZero(cpu.regs.a == 0)
 
 }
@@ -275,9 +310,12 @@ func (cpu *CPU) DEC(value uint8) uint8 {
 	return value
 }
 
-// <----------------------------- OPCODES + INSTRUCTIONS -----------------------------> //
+// idu - 16-bit increment/decrement of a register, which corrupts OAM when it holds an OAM address
+func (cpu *CPU) idu(value uint16) {
+	cpu.mem.console.ppu.oamBugWrite(value)
+}
 
-// TODO: check all the + and - instructions
+// <----------------------------- OPCODES + INSTRUCTIONS -----------------------------> //
 
 // 0x00 - NOP
 func (cpu *CPU) NOP(stepInfo *OperandInfo) {}
@@ -297,6 +335,7 @@ func (cpu *CPU) LD_BC_A(stepInfo *OperandInfo) {
 // 0x03 - INC BC
 func (cpu *CPU) INC_BC(stepInfo *OperandInfo) {
 	NN := cpu.regs.GetBC()
+	cpu.idu(NN)
 	NN++
 	cpu.regs.SetBC(NN)
 }
@@ -337,8 +376,7 @@ func (cpu *CPU) LD_a16_SP(stepInfo *OperandInfo) {
 
 // 0x09 - ADD HL, BC
 func (cpu *CPU) ADD_HL_BC(stepInfo *OperandInfo) {
-	// TODO: why is this erroring?
-	// cpu.ADD_16(&cpu.regs.GetHL(), cpu.regs.GetBC())
+	cpu.ADD_16(cpu.regs.GetBC())
 }
 
 // 0x0A - LD A, (BC)
@@ -349,6 +387,7 @@ func (cpu *CPU) LD_A_BC(stepInfo *OperandInfo) {
 // 0x0B - DEC BC
 func (cpu *CPU) DEC_BC(stepInfo *OperandInfo) {
 	NN := cpu.regs.GetBC()
+	cpu.idu(NN)
 	NN--
 	cpu.regs.SetBC(NN)
 }
@@ -383,6 +422,17 @@ func (cpu *CPU) RRCA(stepInfo *OperandInfo) {
 
 // 0x10 - STOP
 func (cpu *CPU) STOP(stepInfo *OperandInfo) {
+	// STOP resets the divider
+	cpu.mem.Write8(DIV_ADDRESS, 0)
+
+	// on CGB, STOP with KEY1 armed switches CPU speed instead of stopping
+	if cpu.speedSwitch {
+		cpu.speedSwitch = false
+		cpu.doubleSpeed = !cpu.doubleSpeed
+		return
+	}
+
+	// stopped until a selected button is pressed, see Joypad.checkLines
 	cpu.stopped = true
 }
 
@@ -400,6 +450,7 @@ func (cpu *CPU) LD_DE_A(stepInfo *OperandInfo) {
 // 0x13 - INC DE
 func (cpu *CPU) INC_DE(stepInfo *OperandInfo) {
 	NN := cpu.regs.GetDE()
+	cpu.idu(NN)
 	NN++
 	cpu.regs.SetDE(NN)
 }
@@ -421,13 +472,13 @@ func (cpu *CPU) LD_D_d8(stepInfo *OperandInfo) {
 
 // 0x17 - RLA (rotate left through carry)
 func (cpu *CPU) RLA(stepInfo *OperandInfo) {
+	carry := cpu.regs.GetCarry()
 
-	// TODO: CHECK THIS
-
-	// set the carry flag to bit 0
+	// set the carry flag to bit 7
 	cpu.regs.SetCarry((cpu.regs.a & 0x80) != 0)
 
-	cpu.regs.a = (cpu.regs.a << 1) | (cpu.regs.a >> 7)
+	// old carry goes into bit 0
+	cpu.regs.a = (cpu.regs.a << 1) | carry
 
 	cpu.regs.SetZero(false)
 	cpu.regs.SetSubtract(false)
@@ -435,19 +486,17 @@ func (cpu *CPU) RLA(stepInfo *OperandInfo) {
 
 }
 
-// 0x18 - JR r8 (r8 means 8 bit immediate value, operand will be from PC)
+// 0x18 - JR r8 (r8 means 8 bit signed immediate value, operand will be from PC)
 func (cpu *CPU) JR_r8(stepInfo *OperandInfo) {
-	cpu.regs.pc += uint16(stepInfo.operand8)
+	// relative to the address of the next instruction
+	cpu.regs.pc = cpu.regs.pc + uint16(int8(stepInfo.operand8))
 }
 
 // 0x19 - ADD HL, DE
 func (cpu *CPU) ADD_HL_DE(stepInfo *OperandInfo) {
-	// TODO: why is this erroring?
-	// cpu.ADD_16(&cpu.regs.GetHL(), cpu.regs.GetDE())
+	cpu.ADD_16(cpu.regs.GetDE())
 }
 
-// cpu.ADD_16(cpu.regs.GetBC(), cpu.regs.GetHL())
-
 // 0x1A - LD A, (DE)
 func (cpu *CPU) LD_A_DE(stepInfo *OperandInfo) {
 	cpu.regs.a = cpu.mem.Read8(cpu.regs.GetDE())
@@ -456,6 +505,7 @@ func (cpu *CPU) LD_A_DE(stepInfo *OperandInfo) {
 // 0x1B - DEC DE
 func (cpu *CPU) DEC_DE(stepInfo *OperandInfo) {
 	NN := cpu.regs.GetDE()
+	cpu.idu(NN)
 	NN--
 	cpu.regs.SetDE(NN)
 }
@@ -477,14 +527,26 @@ func (cpu *CPU) LD_E_d8(stepInfo *OperandInfo) {
 
 // 0x1F - RRA (rotate right through carry)
 func (cpu *CPU) RRA(stepInfo *OperandInfo) {
-	// TODO: check this
+	carry := cpu.regs.GetCarry()
+
+	// set the carry flag to bit 0
+	cpu.regs.SetCarry((cpu.regs.a & 0x01) != 0)
+
+	// old carry goes into bit 7
+	cpu.regs.a = (cpu.regs.a >> 1) | (carry << 7)
+
+	cpu.regs.SetZero(false)
+	cpu.regs.SetSubtract(false)
+	cpu.regs.SetHalfCarry(false)
 }
 
 // 0x20 - JR NZ, r8 (r8 means 8 bit immediate value, operand will be from PC)
 func (cpu *CPU) JR_NZ_r8(stepInfo *OperandInfo) {
-	// TODO: check this
 	if cpu.regs.GetZero() == 0 {
-		cpu.regs.pc += uint16(stepInfo.operand8)
+		cpu.regs.pc = cpu.regs.pc + uint16(int8(stepInfo.operand8))
+		cpu.ticks += 12
+	} else {
+		cpu.ticks += 8
 	}
 }
 
@@ -502,6 +564,7 @@ func (cpu *CPU) LDi_HLp_A(stepInfo *OperandInfo) {
 // 0x23 - INC HL
 func (cpu *CPU) INC_HL(stepInfo *OperandInfo) {
 	NN := cpu.regs.GetHL()
+	cpu.idu(NN)
 	NN++
 	cpu.regs.SetHL(NN)
 }
@@ -523,22 +586,52 @@ func (cpu *CPU) LD_H_d8(stepInfo *OperandInfo) {
 
 // 0x27 - DAA (decimal adjust accumulator)
 func (cpu *CPU) DAA(stepInfo *OperandInfo) {
-	// TODO: this
+	// adjust A back to binary coded decimal using the flags left by the previous add or subtract
+	var adjust uint8
+	carry := cpu.regs.GetCarry() == 1
+
+	if cpu.regs.GetSubtract() == 0 {
+		if carry || cpu.regs.a > 0x99 {
+			adjust |= 0x60
+			carry = true
+		}
+		if cpu.regs.GetHalfCarry() == 1 || (cpu.regs.a&0x0F) > 0x09 {
+			adjust |= 0x06
+		}
+		cpu.regs.a += adjust
+	} else {
+		if carry {
+			adjust |= 0x60
+		}
+		if cpu.regs.GetHalfCarry() == 1 {
+			adjust |= 0x06
+		}
+		cpu.regs.a -= adjust
+	}
 
+	cpu.regs.SetZero(cpu.regs.a == 0)
+	cpu.regs.SetHalfCarry(false)
+	cpu.regs.SetCarry(carry)
 }
 
 // 0x28 - JR Z, r8
 func (cpu *CPU) JR_Z_r8(stepInfo *OperandInfo) {
-
+	if cpu.regs.GetZero() == 1 {
+		cpu.regs.pc = cpu.regs.pc + uint16(int8(stepInfo.operand8))
+		cpu.ticks += 12
+	} else {
+		cpu.ticks += 8
+	}
 }
 
 // 0x29 - ADD HL, HL
 func (cpu *CPU) ADD_HL_HL(stepInfo *OperandInfo) {
-
+	cpu.ADD_16(cpu.regs.GetHL())
 }
 
 // 0x2A - LD A, (HL+)
 func (cpu *CPU) LDi_A_HLp(stepInfo *OperandInfo) {
+	cpu.mem.console.ppu.oamBugIncrease(cpu.regs.GetHL())
 	cpu.regs.a = cpu.mem.Read8(cpu.regs.GetHL())
 	cpu.regs.SetHL(cpu.regs.GetHL() + 1)
 }
@@ -546,6 +639,7 @@ func (cpu *CPU) LDi_A_HLp(stepInfo *OperandInfo) {
 // 0x2B - DEC HL
 func (cpu *CPU) DEC_HL(stepInfo *OperandInfo) {
 	NN := cpu.regs.GetHL()
+	cpu.idu(NN)
 	NN--
 	cpu.regs.SetHL(NN)
 }
@@ -567,12 +661,20 @@ func (cpu *CPU) LD_L_d8(stepInfo *OperandInfo) {
 
 // 0x2F - CPL (complement accumulator)
 func (cpu *CPU) CPL(stepInfo *OperandInfo) {
-	// TODO: this
+	cpu.regs.a = ^cpu.regs.a
+
+	cpu.regs.SetSubtract(true)
+	cpu.regs.SetHalfCarry(true)
 }
 
 // 0x30 - JR NC, r8
 func (cpu *CPU) JR_NC_r8(stepInfo *OperandInfo) {
-	// TODO: this
+	if cpu.regs.GetCarry() == 0 {
+		cpu.regs.pc = cpu.regs.pc + uint16(int8(stepInfo.operand8))
+		cpu.ticks += 12
+	} else {
+		cpu.ticks += 8
+	}
 }
 
 // 0x31 - LD SP, d16
@@ -589,6 +691,7 @@ func (cpu *CPU) LD_HLm_A(stepInfo *OperandInfo) {
 
 // 0x33 - INC SP
 func (cpu *CPU) INC_SP(stepInfo *OperandInfo) {
+	cpu.idu(cpu.regs.sp)
 	cpu.regs.sp++
 }
 
@@ -612,28 +715,35 @@ func (cpu *CPU) LD_HLp_d8(stepInfo *OperandInfo) {
 // 0x37 - SCF (set carry flag)
 func (cpu *CPU) SCF(stepInfo *OperandInfo) {
 	cpu.regs.SetCarry(true)
-	cpu.regs.SetZero(false)
+	cpu.regs.SetSubtract(false)
 	cpu.regs.SetHalfCarry(false)
 }
 
 // 0x38 - JR C, r8
 func (cpu *CPU) JR_C_r8(stepInfo *OperandInfo) {
-	// TODO: this
+	if cpu.regs.GetCarry() == 1 {
+		cpu.regs.pc = cpu.regs.pc + uint16(int8(stepInfo.operand8))
+		cpu.ticks += 12
+	} else {
+		cpu.ticks += 8
+	}
 }
 
 // 0x39 - ADD HL, SP
 func (cpu *CPU) ADD_HL_SP(stepInfo *OperandInfo) {
-	// TODO: use add functions?
+	cpu.ADD_16(cpu.regs.sp)
 }
 
 // 0x3A - LD A, (HL-)
 func (cpu *CPU) LD_A_HLm(stepInfo *OperandInfo) {
+	cpu.mem.console.ppu.oamBugIncrease(cpu.regs.GetHL())
 	cpu.regs.a = cpu.mem.Read8(cpu.regs.GetHL())
 	cpu.regs.SetHL(cpu.regs.GetHL() - 1)
 }
 
 // 0x3B - DEC SP
 func (cpu *CPU) DEC_SP(stepInfo *OperandInfo) {
+	cpu.idu(cpu.regs.sp)
 	cpu.regs.sp--
 }
 
@@ -654,7 +764,9 @@ func (cpu *CPU) LD_A_d8(stepInfo *OperandInfo) {
 
 // 0x3F - CCF (complement carry flag)
 func (cpu *CPU) CCF(stepInfo *OperandInfo) {
-	// TODO: ??
+	cpu.regs.SetCarry(cpu.regs.GetCarry() == 0)
+	cpu.regs.SetSubtract(false)
+	cpu.regs.SetHalfCarry(false)
 }
 
 // 0x40 - LD B, B
@@ -929,9 +1041,15 @@ func (cpu *CPU) LD_HLp_L(stepInfo *OperandInfo) {
 
 // 0x76 - HALT
 func (cpu *CPU) HALT(stepInfo *OperandInfo) {
-	// TODO: this
-	// halt execution until an interrupt occurs, use interrupt information to determine if an interrupt is pending
-	// else increment pc
+	// with IME off and an interrupt already pending HALT exits immediately,
+	// and the CPU fails to increment PC after reading the next opcode
+	if !cpu.ime && cpu.mem.PendingInterrupts() != 0 {
+		cpu.haltBug = true
+		return
+	}
+
+	// halt execution until an interrupt is pending, see HandleInterrupts
+	cpu.halted = true
 }
 
 // 0x77 - LD (HL+), A
@@ -1294,37 +1412,33 @@ func (cpu *CPU) CP_HL(stepInfo *OperandInfo) {
 	cpu.CP(cpu.mem.Read8(cpu.regs.GetHL()))
 }
 
-//  0xBF - CP A
+// 0xBF - CP A
 func (cpu *CPU) CP_A(stepInfo *OperandInfo) {
 	cpu.CP(cpu.regs.a)
 }
 
 // 0xC0 - RET NZ
 func (cpu *CPU) RET_NZ(stepInfo *OperandInfo) {
-	// TODO: check this
 	if cpu.regs.GetZero() == 0 {
-		cpu.regs.pc = cpu.mem.Read16(cpu.regs.sp)
-		cpu.regs.sp += 2
+		cpu.regs.pc = cpu.mem.PopStack16(&cpu.regs.sp)
+		cpu.ticks += 20
+	} else {
+		cpu.ticks += 8
 	}
-
-	cpu.regs.pc++
 }
 
 // 0xC1 - POP BC
 func (cpu *CPU) POP_BC(stepInfo *OperandInfo) {
-	// TODO: check this
-	cpu.regs.SetBC(cpu.mem.Read16(cpu.regs.sp))
-	cpu.regs.sp += 2
-	cpu.regs.pc++
+	cpu.regs.SetBC(cpu.mem.PopStack16(&cpu.regs.sp))
 }
 
 // 0xC2 - JP NZ,nn
 func (cpu *CPU) JP_NZ_NN(stepInfo *OperandInfo) {
-	// TODO: check this
 	if cpu.regs.GetZero() == 0 {
 		cpu.regs.pc = stepInfo.operand16
+		cpu.ticks += 16
 	} else {
-		cpu.regs.pc += 3
+		cpu.ticks += 12
 	}
 }
 
@@ -1339,7 +1453,7 @@ func (cpu *CPU) CALL_NZ_a16(stepInfo *OperandInfo) {
 	if cpu.regs.GetZero() == 1 {
 		cpu.ticks += 12
 	} else {
-		cpu.mem.WriteToStack16(cpu.regs.pc, &cpu.regs.sp)
+		cpu.mem.PushStack16(cpu.regs.pc, &cpu.regs.sp)
 		cpu.regs.pc = stepInfo.operand16
 		cpu.ticks += 24
 	}
@@ -1348,31 +1462,342 @@ func (cpu *CPU) CALL_NZ_a16(stepInfo *OperandInfo) {
 
 // 0xC5 - PUSH BC
 func (cpu *CPU) PUSH_BC(stepInfo *OperandInfo) {
-	// TODO: this
+	cpu.mem.PushStack16(cpu.regs.GetBC(), &cpu.regs.sp)
 }
 
 // 0xC6 - ADD A, d8
 func (cpu *CPU) ADD_A_d8(stepInfo *OperandInfo) {
-	// TODO: this
+	cpu.ADD(&cpu.regs.a, stepInfo.operand8)
 }
 
-// 0xC7 -
+// 0xC7 - RST 00H
+func (cpu *CPU) RST_00H(stepInfo *OperandInfo) {
+	cpu.mem.PushStack16(cpu.regs.pc, &cpu.regs.sp)
+	cpu.regs.pc = 0x0000
+}
 
 // 0xC8 - RET Z
+func (cpu *CPU) RET_Z(stepInfo *OperandInfo) {
+	if cpu.regs.GetZero() == 1 {
+		cpu.regs.pc = cpu.mem.PopStack16(&cpu.regs.sp)
+		cpu.ticks += 20
+	} else {
+		cpu.ticks += 8
+	}
+}
 
 // 0xC9 - RET
+func (cpu *CPU) RET(stepInfo *OperandInfo) {
+	cpu.regs.pc = cpu.mem.PopStack16(&cpu.regs.sp)
+}
 
 // 0xCA - JP Z, nn
+func (cpu *CPU) JP_Z_NN(stepInfo *OperandInfo) {
+	if cpu.regs.GetZero() == 1 {
+		cpu.regs.pc = stepInfo.operand16
+		cpu.ticks += 16
+	} else {
+		cpu.ticks += 12
+	}
+}
+
+// 0xCB - PREFIX CB
+func (cpu *CPU) PREFIX_CB(stepInfo *OperandInfo) {
+	// the operand is the opcode into the CB table
+	opcode := stepInfo.operand8
+
+	cpu.cbTable[opcode].execute(stepInfo)
+	cpu.ticks += uint32(cpu.cbTicksTable[opcode])
+}
+
+// 0xCC - CALL Z, a16
+func (cpu *CPU) CALL_Z_a16(stepInfo *OperandInfo) {
+	if cpu.regs.GetZero() == 1 {
+		cpu.mem.PushStack16(cpu.regs.pc, &cpu.regs.sp)
+		cpu.regs.pc = stepInfo.operand16
+		cpu.ticks += 24
+	} else {
+		cpu.ticks += 12
+	}
+}
+
+// 0xCD - CALL a16
+func (cpu *CPU) CALL_a16(stepInfo *OperandInfo) {
+	cpu.mem.PushStack16(cpu.regs.pc, &cpu.regs.sp)
+	cpu.regs.pc = stepInfo.operand16
+}
+
+// 0xCE - ADC A, d8
+func (cpu *CPU) ADC_A_d8(stepInfo *OperandInfo) {
+	cpu.ADC(stepInfo.operand8)
+}
+
+// 0xCF - RST 08H
+func (cpu *CPU) RST_08H(stepInfo *OperandInfo) {
+	cpu.mem.PushStack16(cpu.regs.pc, &cpu.regs.sp)
+	cpu.regs.pc = 0x0008
+}
+
+// 0xD0 - RET NC
+func (cpu *CPU) RET_NC(stepInfo *OperandInfo) {
+	if cpu.regs.GetCarry() == 0 {
+		cpu.regs.pc = cpu.mem.PopStack16(&cpu.regs.sp)
+		cpu.ticks += 20
+	} else {
+		cpu.ticks += 8
+	}
+}
+
+// 0xD1 - POP DE
+func (cpu *CPU) POP_DE(stepInfo *OperandInfo) {
+	cpu.regs.SetDE(cpu.mem.PopStack16(&cpu.regs.sp))
+}
+
+// 0xD2 - JP NC, nn
+func (cpu *CPU) JP_NC_NN(stepInfo *OperandInfo) {
+	if cpu.regs.GetCarry() == 0 {
+		cpu.regs.pc = stepInfo.operand16
+		cpu.ticks += 16
+	} else {
+		cpu.ticks += 12
+	}
+}
+
+// 0xD4 - CALL NC, a16
+func (cpu *CPU) CALL_NC_a16(stepInfo *OperandInfo) {
+	if cpu.regs.GetCarry() == 0 {
+		cpu.mem.PushStack16(cpu.regs.pc, &cpu.regs.sp)
+		cpu.regs.pc = stepInfo.operand16
+		cpu.ticks += 24
+	} else {
+		cpu.ticks += 12
+	}
+}
+
+// 0xD5 - PUSH DE
+func (cpu *CPU) PUSH_DE(stepInfo *OperandInfo) {
+	cpu.mem.PushStack16(cpu.regs.GetDE(), &cpu.regs.sp)
+}
+
+// 0xD6 - SUB d8
+func (cpu *CPU) SUB_d8(stepInfo *OperandInfo) {
+	cpu.SUB(stepInfo.operand8)
+}
+
+// 0xD7 - RST 10H
+func (cpu *CPU) RST_10H(stepInfo *OperandInfo) {
+	cpu.mem.PushStack16(cpu.regs.pc, &cpu.regs.sp)
+	cpu.regs.pc = 0x0010
+}
 
+// 0xD8 - RET C
+func (cpu *CPU) RET_C(stepInfo *OperandInfo) {
+	if cpu.regs.GetCarry() == 1 {
+		cpu.regs.pc = cpu.mem.PopStack16(&cpu.regs.sp)
+		cpu.ticks += 20
+	} else {
+		cpu.ticks += 8
+	}
+}
+
+// 0xD9 - RETI
+func (cpu *CPU) RETI(stepInfo *OperandInfo) {
+	cpu.regs.pc = cpu.mem.PopStack16(&cpu.regs.sp)
+	cpu.ime = true
+}
+
+// 0xDA - JP C, nn
+func (cpu *CPU) JP_C_NN(stepInfo *OperandInfo) {
+	if cpu.regs.GetCarry() == 1 {
+		cpu.regs.pc = stepInfo.operand16
+		cpu.ticks += 16
+	} else {
+		cpu.ticks += 12
+	}
+}
+
+// 0xDC - CALL C, a16
+func (cpu *CPU) CALL_C_a16(stepInfo *OperandInfo) {
+	if cpu.regs.GetCarry() == 1 {
+		cpu.mem.PushStack16(cpu.regs.pc, &cpu.regs.sp)
+		cpu.regs.pc = stepInfo.operand16
+		cpu.ticks += 24
+	} else {
+		cpu.ticks += 12
+	}
+}
+
+// 0xDE - SBC A, d8
+func (cpu *CPU) SBC_A_d8(stepInfo *OperandInfo) {
+	cpu.SBC(stepInfo.operand8)
+}
+
+// 0xDF - RST 18H
+func (cpu *CPU) RST_18H(stepInfo *OperandInfo) {
+	cpu.mem.PushStack16(cpu.regs.pc, &cpu.regs.sp)
+	cpu.regs.pc = 0x0018
+}
+
+// 0xE0 - LDH (a8), A
+func (cpu *CPU) LDH_a8_A(stepInfo *OperandInfo) {
+	cpu.mem.Write8(0xFF00+uint16(stepInfo.operand8), cpu.regs.a)
+}
+
+// 0xE1 - POP HL
+func (cpu *CPU) POP_HL(stepInfo *OperandInfo) {
+	cpu.regs.SetHL(cpu.mem.PopStack16(&cpu.regs.sp))
+}
+
+// 0xE2 - LD (C), A
+func (cpu *CPU) LD_Cp_A(stepInfo *OperandInfo) {
+	cpu.mem.Write8(0xFF00+uint16(cpu.regs.c), cpu.regs.a)
+}
+
+// 0xE5 - PUSH HL
+func (cpu *CPU) PUSH_HL(stepInfo *OperandInfo) {
+	cpu.mem.PushStack16(cpu.regs.GetHL(), &cpu.regs.sp)
+}
+
+// 0xE6 - AND d8
+func (cpu *CPU) AND_d8(stepInfo *OperandInfo) {
+	cpu.AND(stepInfo.operand8)
+}
+
+// 0xE7 - RST 20H
+func (cpu *CPU) RST_20H(stepInfo *OperandInfo) {
+	cpu.mem.PushStack16(cpu.regs.pc, &cpu.regs.sp)
+	cpu.regs.pc = 0x0020
+}
+
+// 0xE8 - ADD SP, r8
+func (cpu *CPU) ADD_SP_r8(stepInfo *OperandInfo) {
+	cpu.regs.sp = cpu.ADD_SP(stepInfo.operand8)
+}
+
+// 0xE9 - JP (HL)
+func (cpu *CPU) JP_HL(stepInfo *OperandInfo) {
+	cpu.regs.pc = cpu.regs.GetHL()
+}
+
+// 0xEA - LD (a16), A
+func (cpu *CPU) LD_a16_A(stepInfo *OperandInfo) {
+	cpu.mem.Write8(stepInfo.operand16, cpu.regs.a)
+}
+
+// 0xEE - XOR d8
+func (cpu *CPU) XOR_d8(stepInfo *OperandInfo) {
+	cpu.XOR(stepInfo.operand8)
+}
+
+// 0xEF - RST 28H
+func (cpu *CPU) RST_28H(stepInfo *OperandInfo) {
+	cpu.mem.PushStack16(cpu.regs.pc, &cpu.regs.sp)
+	cpu.regs.pc = 0x0028
+}
+
+// 0xF0 - LDH A, (a8)
+func (cpu *CPU) LDH_A_a8(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.mem.Read8(0xFF00 + uint16(stepInfo.operand8))
+}
+
+// 0xF1 - POP AF
+func (cpu *CPU) POP_AF(stepInfo *OperandInfo) {
+	cpu.regs.SetAF(cpu.mem.PopStack16(&cpu.regs.sp))
+}
+
+// 0xF2 - LD A, (C)
+func (cpu *CPU) LD_A_Cp(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.mem.Read8(0xFF00 + uint16(cpu.regs.c))
+}
+
+// 0xF3 - DI
+func (cpu *CPU) DI(stepInfo *OperandInfo) {
+	cpu.ime = false
+	cpu.imeDelay = 0
+}
+
+// 0xF5 - PUSH AF
+func (cpu *CPU) PUSH_AF(stepInfo *OperandInfo) {
+	cpu.mem.PushStack16(cpu.regs.GetAF(), &cpu.regs.sp)
+}
+
+// 0xF6 - OR d8
+func (cpu *CPU) OR_d8(stepInfo *OperandInfo) {
+	cpu.OR(stepInfo.operand8)
+}
+
+// 0xF7 - RST 30H
+func (cpu *CPU) RST_30H(stepInfo *OperandInfo) {
+	cpu.mem.PushStack16(cpu.regs.pc, &cpu.regs.sp)
+	cpu.regs.pc = 0x0030
+}
+
+// 0xF8 - LD HL, SP+r8
+func (cpu *CPU) LD_HL_SPr8(stepInfo *OperandInfo) {
+	cpu.regs.SetHL(cpu.ADD_SP(stepInfo.operand8))
+}
+
+// 0xF9 - LD SP, HL
+func (cpu *CPU) LD_SP_HL(stepInfo *OperandInfo) {
+	cpu.regs.sp = cpu.regs.GetHL()
+}
+
+// 0xFA - LD A, (a16)
+func (cpu *CPU) LD_A_a16(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.mem.Read8(stepInfo.operand16)
+}
+
+// 0xFB - EI
+func (cpu *CPU) EI(stepInfo *OperandInfo) {
+	// IME is set after the instruction following EI, see Step
+	if !cpu.ime && cpu.imeDelay == 0 {
+		cpu.imeDelay = 2
+	}
+}
+
+// 0xFE - CP d8
+func (cpu *CPU) CP_d8(stepInfo *OperandInfo) {
+	cpu.CP(stepInfo.operand8)
+}
+
+// 0xFF - RST 38H
+func (cpu *CPU) RST_38H(stepInfo *OperandInfo) {
+	cpu.mem.PushStack16(cpu.regs.pc, &cpu.regs.sp)
+	cpu.regs.pc = 0x0038
+}
+
+// Undefined opcodes (0xD3, 0xDB, 0xDD, 0xE3, 0xE4, 0xEB, 0xEC, 0xED, 0xF4, 0xFC, 0xFD)
+// hang the CPU until it is reset
 func (cpu *CPU) UNKNOWN(stepInfo *OperandInfo) {
-	fmt.Printf("Unknown opcode!\n")
+	cpu.locked = true
+}
+
+// LockupError reports the undefined opcode that hung the CPU
+type LockupError struct {
+	Address uint16 // address of the opcode
+	Opcode  uint8
+}
+
+func (e *LockupError) Error() string {
+	return fmt.Sprintf("cpu locked up by undefined opcode 0x%02X at 0x%04X", e.Opcode, e.Address)
+}
+
+// Locked returns a LockupError once an undefined opcode has hung the CPU, nil otherwise
+func (cpu *CPU) Locked() error {
+	if !cpu.locked {
+		return nil
+	}
+
+	// a locked CPU never moves PC past the opcode that locked it
+	address := cpu.regs.pc - 1
+	return &LockupError{Address: address, Opcode: cpu.mem.Read8(address)}
 }
 
 // <----------------------------- EXECUTION -----------------------------> //
 
 func (cpu *CPU) CreateTable() {
 	cpu.table = [256]Instruction{
-		{"NOP", 0, cpu.NOP},                  // 0x00
+		{"NOP", 1, cpu.NOP},                  // 0x00
 		{"LD BC, d16", 3, cpu.LD_BC_d16},     // 0x01
 		{"LD (BC), A", 1, cpu.LD_BC_A},       // 0x02
 		{"INC BC", 1, cpu.INC_BC},            // 0x03
@@ -1388,7 +1813,7 @@ func (cpu *CPU) CreateTable() {
 		{"DEC C", 1, cpu.DEC_C},              // 0x0D
 		{"LD C, d8", 2, cpu.LD_C_d8},         // 0x0E
 		{"RRCA", 1, cpu.RRCA},                // 0x0F
-		{"STOP", 1, cpu.STOP},                // 0x10
+		{"STOP", 2, cpu.STOP},                // 0x10
 		{"LD DE, d16", 3, cpu.LD_DE_d16},     // 0x11
 		{"LD (DE), A", 1, cpu.LD_DE_A},       // 0x12
 		{"INC DE", 1, cpu.INC_DE},            // 0x13
@@ -1567,29 +1992,89 @@ func (cpu *CPU) CreateTable() {
 		{"RET NZ", 1, cpu.RET_NZ},            // 0xC0
 		{"POP BC", 1, cpu.POP_BC},            // 0xC1
 		{"JP NZ, nn", 3, cpu.JP_NZ_NN},       // 0xC2
-		{"CALL NZ, a16", 3, cpu.CALL_NZ_a16}, // 0xC3
-
+		{"JP nn", 3, cpu.JP_NN},              // 0xC3
+		{"CALL NZ, a16", 3, cpu.CALL_NZ_a16}, // 0xC4
+		{"PUSH BC", 1, cpu.PUSH_BC},          // 0xC5
+		{"ADD A, d8", 2, cpu.ADD_A_d8},       // 0xC6
+		{"RST 00H", 1, cpu.RST_00H},          // 0xC7
+		{"RET Z", 1, cpu.RET_Z},              // 0xC8
+		{"RET", 1, cpu.RET},                  // 0xC9
+		{"JP Z, nn", 3, cpu.JP_Z_NN},         // 0xCA
+		{"PREFIX CB", 2, cpu.PREFIX_CB},      // 0xCB
+		{"CALL Z, a16", 3, cpu.CALL_Z_a16},   // 0xCC
+		{"CALL a16", 3, cpu.CALL_a16},        // 0xCD
+		{"ADC A, d8", 2, cpu.ADC_A_d8},       // 0xCE
+		{"RST 08H", 1, cpu.RST_08H},          // 0xCF
+		{"RET NC", 1, cpu.RET_NC},            // 0xD0
+		{"POP DE", 1, cpu.POP_DE},            // 0xD1
+		{"JP NC, nn", 3, cpu.JP_NC_NN},       // 0xD2
+		{"UNDEFINED", 1, cpu.UNKNOWN},        // 0xD3
+		{"CALL NC, a16", 3, cpu.CALL_NC_a16}, // 0xD4
+		{"PUSH DE", 1, cpu.PUSH_DE},          // 0xD5
+		{"SUB d8", 2, cpu.SUB_d8},            // 0xD6
+		{"RST 10H", 1, cpu.RST_10H},          // 0xD7
+		{"RET C", 1, cpu.RET_C},              // 0xD8
+		{"RETI", 1, cpu.RETI},                // 0xD9
+		{"JP C, nn", 3, cpu.JP_C_NN},         // 0xDA
+		{"UNDEFINED", 1, cpu.UNKNOWN},        // 0xDB
+		{"CALL C, a16", 3, cpu.CALL_C_a16},   // 0xDC
+		{"UNDEFINED", 1, cpu.UNKNOWN},        // 0xDD
+		{"SBC A, d8", 2, cpu.SBC_A_d8},       // 0xDE
+		{"RST 18H", 1, cpu.RST_18H},          // 0xDF
+		{"LDH (a8), A", 2, cpu.LDH_a8_A},     // 0xE0
+		{"POP HL", 1, cpu.POP_HL},            // 0xE1
+		{"LD (C), A", 1, cpu.LD_Cp_A},        // 0xE2
+		{"UNDEFINED", 1, cpu.UNKNOWN},        // 0xE3
+		{"UNDEFINED", 1, cpu.UNKNOWN},        // 0xE4
+		{"PUSH HL", 1, cpu.PUSH_HL},          // 0xE5
+		{"AND d8", 2, cpu.AND_d8},            // 0xE6
+		{"RST 20H", 1, cpu.RST_20H},          // 0xE7
+		{"ADD SP, r8", 2, cpu.ADD_SP_r8},     // 0xE8
+		{"JP (HL)", 1, cpu.JP_HL},            // 0xE9
+		{"LD (a16), A", 3, cpu.LD_a16_A},     // 0xEA
+		{"UNDEFINED", 1, cpu.UNKNOWN},        // 0xEB
+		{"UNDEFINED", 1, cpu.UNKNOWN},        // 0xEC
+		{"UNDEFINED", 1, cpu.UNKNOWN},        // 0xED
+		{"XOR d8", 2, cpu.XOR_d8},            // 0xEE
+		{"RST 28H", 1, cpu.RST_28H},          // 0xEF
+		{"LDH A, (a8)", 2, cpu.LDH_A_a8},     // 0xF0
+		{"POP AF", 1, cpu.POP_AF},            // 0xF1
+		{"LD A, (C)", 1, cpu.LD_A_Cp},        // 0xF2
+		{"DI", 1, cpu.DI},                    // 0xF3
+		{"UNDEFINED", 1, cpu.UNKNOWN},        // 0xF4
+		{"PUSH AF", 1, cpu.PUSH_AF},          // 0xF5
+		{"OR d8", 2, cpu.OR_d8},              // 0xF6
+		{"RST 30H", 1, cpu.RST_30H},          // 0xF7
+		{"LD HL, SP+r8", 2, cpu.LD_HL_SPr8},  // 0xF8
+		{"LD SP, HL", 1, cpu.LD_SP_HL},       // 0xF9
+		{"LD A, (a16)", 3, cpu.LD_A_a16},     // 0xFA
+		{"EI", 1, cpu.EI},                    // 0xFB
+		{"UNDEFINED", 1, cpu.UNKNOWN},        // 0xFC
+		{"UNDEFINED", 1, cpu.UNKNOWN},        // 0xFD
+		{"CP d8", 2, cpu.CP_d8},              // 0xFE
+		{"RST 38H", 1, cpu.RST_38H},          // 0xFF
 	}
 }
 
-func (cpu *CPU) CreateTicks(opcode uint8) {
+func (cpu *CPU) CreateTicks() {
+	// ticks are in clock cycles, conditional instructions are 0 and add their own ticks
 	cpu.ticksTable = [256]uint8{
-		2, 6, 4, 4, 2, 2, 4, 4, 10, 4, 4, 4, 2, 2, 4, 4, // 0x0_
-		2, 6, 4, 4, 2, 2, 4, 4, 4, 4, 4, 4, 2, 2, 4, 4, // 0x1_
-		0, 6, 4, 4, 2, 2, 4, 2, 0, 4, 4, 4, 2, 2, 4, 2, // 0x2_
-		4, 6, 4, 4, 6, 6, 6, 2, 0, 4, 4, 4, 2, 2, 4, 2, // 0x3_
-		2, 2, 2, 2, 2, 2, 4, 2, 2, 2, 2, 2, 2, 2, 4, 2, // 0x4_
-		2, 2, 2, 2, 2, 2, 4, 2, 2, 2, 2, 2, 2, 2, 4, 2, // 0x5_
-		2, 2, 2, 2, 2, 2, 4, 2, 2, 2, 2, 2, 2, 2, 4, 2, // 0x6_
-		4, 4, 4, 4, 4, 4, 2, 4, 2, 2, 2, 2, 2, 2, 4, 2, // 0x7_
-		2, 2, 2, 2, 2, 2, 4, 2, 2, 2, 2, 2, 2, 2, 4, 2, // 0x8_
-		2, 2, 2, 2, 2, 2, 4, 2, 2, 2, 2, 2, 2, 2, 4, 2, // 0x9_
-		2, 2, 2, 2, 2, 2, 4, 2, 2, 2, 2, 2, 2, 2, 4, 2, // 0xa_
-		2, 2, 2, 2, 2, 2, 4, 2, 2, 2, 2, 2, 2, 2, 4, 2, // 0xb_
-		0, 6, 0, 6, 0, 8, 4, 8, 0, 2, 0, 0, 0, 6, 4, 8, // 0xc_
-		0, 6, 0, 0, 0, 8, 4, 8, 0, 8, 0, 0, 0, 0, 4, 8, // 0xd_
-		6, 6, 4, 0, 0, 8, 4, 8, 8, 2, 8, 0, 0, 0, 4, 8, // 0xe_
-		6, 6, 4, 2, 0, 8, 4, 8, 6, 4, 8, 2, 0, 0, 4, 8, // 0xf_
+		4, 12, 8, 8, 4, 4, 8, 4, 20, 8, 8, 8, 4, 4, 8, 4, // 0x0_
+		4, 12, 8, 8, 4, 4, 8, 4, 12, 8, 8, 8, 4, 4, 8, 4, // 0x1_
+		0, 12, 8, 8, 4, 4, 8, 4, 0, 8, 8, 8, 4, 4, 8, 4, // 0x2_
+		0, 12, 8, 8, 12, 12, 12, 4, 0, 8, 8, 8, 4, 4, 8, 4, // 0x3_
+		4, 4, 4, 4, 4, 4, 8, 4, 4, 4, 4, 4, 4, 4, 8, 4, // 0x4_
+		4, 4, 4, 4, 4, 4, 8, 4, 4, 4, 4, 4, 4, 4, 8, 4, // 0x5_
+		4, 4, 4, 4, 4, 4, 8, 4, 4, 4, 4, 4, 4, 4, 8, 4, // 0x6_
+		8, 8, 8, 8, 8, 8, 4, 8, 4, 4, 4, 4, 4, 4, 8, 4, // 0x7_
+		4, 4, 4, 4, 4, 4, 8, 4, 4, 4, 4, 4, 4, 4, 8, 4, // 0x8_
+		4, 4, 4, 4, 4, 4, 8, 4, 4, 4, 4, 4, 4, 4, 8, 4, // 0x9_
+		4, 4, 4, 4, 4, 4, 8, 4, 4, 4, 4, 4, 4, 4, 8, 4, // 0xa_
+		4, 4, 4, 4, 4, 4, 8, 4, 4, 4, 4, 4, 4, 4, 8, 4, // 0xb_
+		0, 12, 0, 16, 0, 16, 8, 16, 0, 16, 0, 0, 0, 24, 8, 16, // 0xc_
+		0, 12, 0, 0, 0, 16, 8, 16, 0, 16, 0, 0, 0, 0, 8, 16, // 0xd_
+		12, 12, 8, 0, 0, 16, 8, 16, 16, 4, 16, 0, 0, 0, 8, 16, // 0xe_
+		12, 12, 8, 4, 0, 16, 8, 16, 12, 8, 16, 4, 0, 0, 8, 16, // 0xf_
 	}
 }
 
@@ -1603,70 +2088,149 @@ func (cpu *CPU) Step() {
 		return
 	}
 
+	// a halted or locked CPU keeps the clock running for the rest of the console
+	if cpu.halted || cpu.locked {
+		cpu.ticks += 4
+		return
+	}
+
 	// Use the program counter to read the instruction byte from memory.
 	opcode = cpu.mem.Read8(cpu.regs.pc)
 
-	// Increment the program counter
-	cpu.regs.pc++
+	// the HALT bug reads the byte after HALT twice
+	if cpu.haltBug {
+		cpu.haltBug = false
+		cpu.regs.pc--
+	}
 
 	// Translate the byte to an instruction
 	instruction := cpu.table[opcode]
 
-	// If we can successfully translate the instruction, call our execute method
-	// else panic
-
-	// check if the instruction is valid/not undefined
-	// if instruction == (Instruction{}) {
-	// 	return
-	// }
+	// Read the immediate operand that follows the opcode, if the instruction has one
+	operands := OperandInfo{}
 
 	switch instruction.instuctionLength {
-	case 0:
 	case 1:
-		instruction.execute(&OperandInfo{})
-
 	case 2:
-		operand := cpu.mem.Read8(cpu.regs.pc)
-		cpu.regs.pc += uint16(operand) // ???
-		instruction.execute(&OperandInfo{operand8: operand})
-
+		operands.operand8 = cpu.mem.Read8(cpu.regs.pc + 1)
 	case 3:
-		operand := cpu.mem.Read16(cpu.regs.pc)
-		cpu.regs.pc += operand // ???
-		instruction.execute(&OperandInfo{operand16: operand})
-
+		operands.operand16 = cpu.mem.Read16(cpu.regs.pc + 1)
 	default:
 		panic("Invalid instruction length")
 	}
 
+	// Move the program counter past the opcode and operand bytes,
+	// jumps, calls and returns overwrite it when they execute
+	cpu.regs.pc += uint16(instruction.instuctionLength)
+
+	instruction.execute(&operands)
+
 	// set ticks using ticks table
 	cpu.ticks += uint32(cpu.ticksTable[opcode])
 
+	// count down the EI delay
+	if cpu.imeDelay > 0 {
+		cpu.imeDelay--
+		if cpu.imeDelay == 0 {
+			cpu.ime = true
+		}
+	}
+
 }
 
-//
+// HandleInterrupts wakes the CPU from HALT and services the highest priority pending interrupt
 func (cpu *CPU) HandleInterrupts() {
+	pending := cpu.mem.PendingInterrupts()
 
+	if pending == 0 {
+		return
+	}
+
+	// a pending interrupt always ends HALT, even when IME is off
+	cpu.halted = false
+
+	if !cpu.ime {
+		return
+	}
+
+	for interrupt := INT_VBLANK; interrupt <= INT_JOYPAD; interrupt++ {
+		if pending&(1<<interrupt) == 0 {
+			continue
+		}
+
+		cpu.ime = false
+		cpu.mem.ClearInterrupt(interrupt)
+
+		cpu.mem.PushStack16(cpu.regs.pc, &cpu.regs.sp)
+		cpu.regs.pc = interrupt.Vector()
+
+		cpu.ticks += INTERRUPT_TICKS
+		return
+	}
 }
 
-// Reset sets the CPU to a default state
-func (cpu *CPU) Reset() {
+// ReadKEY1 returns the CGB speed switch register
+func (cpu *CPU) ReadKEY1() uint8 {
+	value := uint8(0x7E)
+
+	if cpu.doubleSpeed {
+		value |= 0x80
+	}
+	if cpu.speedSwitch {
+		value |= 0x01
+	}
+
+	return value
+}
 
-	// TODO: create set af register function for this
-	// cpu.regs.af = 0x01B0
+// WriteKEY1 arms the speed switch, only bit 0 is writable
+func (cpu *CPU) WriteKEY1(value uint8) {
+	cpu.speedSwitch = value&0x01 != 0
+}
+
+// NewCPU creates a CPU using the given memory map, with its instruction tables built and registers reset
+func NewCPU(mem *MemoryMap) *CPU {
+	cpu := &CPU{mem: mem}
 
-	cpu.regs.SetBC(0x0013)
-	cpu.regs.SetDE(0x00D8)
-	cpu.regs.SetHL(0x014D)
+	cpu.CreateTable()
+	cpu.CreateCBTable()
+	cpu.CreateTicks()
+	cpu.CreateCBTicks()
+	cpu.Reset()
 
-	cpu.regs.sp = 0xFFFE
-	cpu.regs.pc = 0x0100
+	return cpu
+}
+
+// Reset puts the CPU in its power on state, every register cleared and PC at the start of the boot ROM,
+// without a boot ROM the console sets the registers the boot ROM would have left (see boot.go)
+func (cpu *CPU) Reset() {
+	cpu.regs = Registers{}
 
 	cpu.stopped = false
+	cpu.locked = false
+	cpu.ime = false
+	cpu.imeDelay = 0
+	cpu.halted = false
+	cpu.haltBug = false
+	cpu.doubleSpeed = false
+	cpu.speedSwitch = false
 	cpu.ticks = 0
-
 }
 
 var CLOCK_SPEED uint32 = 4194304
 var FRAME_RATE uint32 = 60
 var CYCLES_PER_FRAME uint32 = CLOCK_SPEED / FRAME_RATE
+
+// <----------------------------- SAVE STATES -----------------------------> //
+
+func (cpu *CPU) saveState(s *stateWriter) {
+	r := &cpu.regs
+	s.write(r.a, r.b, r.c, r.d, r.e, r.h, r.l, r.f, r.pc, r.sp)
+	s.write(cpu.ticks, cpu.stopped, cpu.locked, cpu.ime, cpu.imeDelay, cpu.halted, cpu.haltBug, cpu.doubleSpeed, cpu.speedSwitch)
+}
+
+func (cpu *CPU) loadState(s *stateReader) {
+	r := &cpu.regs
+	s.read(&r.a, &r.b, &r.c, &r.d, &r.e, &r.h, &r.l, &r.f, &r.pc, &r.sp)
+	s.read(&cpu.ticks, &cpu.stopped, &cpu.locked, &cpu.ime, &cpu.imeDelay, &cpu.halted, &cpu.haltBug, &cpu.doubleSpeed, &cpu.speedSwitch)
+}
@@ -0,0 +1,1696 @@
+package gb
+
+// CB-prefixed instructions, the second byte of the instruction selects from a second table of 256 instructions.
+
+/*
+	The CB table is laid out as:
+
+		- 0x00 - 0x3F: rotates, shifts and swap
+		- 0x40 - 0x7F: BIT b, r (test bit)
+		- 0x80 - 0xBF: RES b, r (reset bit)
+		- 0xC0 - 0xFF: SET b, r (set bit)
+
+	with the low 3 bits of the opcode selecting the register: B, C, D, E, H, L, (HL), A
+*/
+
+// <----------------------------- CB INSTRUCTIONS -----------------------------> //
+
+// RLC - Rotate left, old bit 7 to carry
+func (cpu *CPU) RLC(value uint8) uint8 {
+	result := (value << 1) | (value >> 7)
+
+	cpu.regs.SetZero(result == 0)
+	cpu.regs.SetSubtract(false)
+	cpu.regs.SetHalfCarry(false)
+	cpu.regs.SetCarry((value & 0x80) != 0)
+
+	return result
+}
+
+// RRC - Rotate right, old bit 0 to carry
+func (cpu *CPU) RRC(value uint8) uint8 {
+	result := (value >> 1) | (value << 7)
+
+	cpu.regs.SetZero(result == 0)
+	cpu.regs.SetSubtract(false)
+	cpu.regs.SetHalfCarry(false)
+	cpu.regs.SetCarry((value & 0x01) != 0)
+
+	return result
+}
+
+// RL - Rotate left through carry
+func (cpu *CPU) RL(value uint8) uint8 {
+	result := (value << 1) | cpu.regs.GetCarry()
+
+	cpu.regs.SetZero(result == 0)
+	cpu.regs.SetSubtract(false)
+	cpu.regs.SetHalfCarry(false)
+	cpu.regs.SetCarry((value & 0x80) != 0)
+
+	return result
+}
+
+// RR - Rotate right through carry
+func (cpu *CPU) RR(value uint8) uint8 {
+	result := (value >> 1) | (cpu.regs.GetCarry() << 7)
+
+	cpu.regs.SetZero(result == 0)
+	cpu.regs.SetSubtract(false)
+	cpu.regs.SetHalfCarry(false)
+	cpu.regs.SetCarry((value & 0x01) != 0)
+
+	return result
+}
+
+// SLA - Shift left into carry, bit 0 is reset
+func (cpu *CPU) SLA(value uint8) uint8 {
+	result := value << 1
+
+	cpu.regs.SetZero(result == 0)
+	cpu.regs.SetSubtract(false)
+	cpu.regs.SetHalfCarry(false)
+	cpu.regs.SetCarry((value & 0x80) != 0)
+
+	return result
+}
+
+// SRA - Shift right into carry, bit 7 is unchanged
+func (cpu *CPU) SRA(value uint8) uint8 {
+	result := (value >> 1) | (value & 0x80)
+
+	cpu.regs.SetZero(result == 0)
+	cpu.regs.SetSubtract(false)
+	cpu.regs.SetHalfCarry(false)
+	cpu.regs.SetCarry((value & 0x01) != 0)
+
+	return result
+}
+
+// SWAP - Swap upper and lower nibbles
+func (cpu *CPU) SWAP(value uint8) uint8 {
+	result := (value << 4) | (value >> 4)
+
+	cpu.regs.SetZero(result == 0)
+	cpu.regs.SetSubtract(false)
+	cpu.regs.SetHalfCarry(false)
+	cpu.regs.SetCarry(false)
+
+	return result
+}
+
+// SRL - Shift right into carry, bit 7 is reset
+func (cpu *CPU) SRL(value uint8) uint8 {
+	result := value >> 1
+
+	cpu.regs.SetZero(result == 0)
+	cpu.regs.SetSubtract(false)
+	cpu.regs.SetHalfCarry(false)
+	cpu.regs.SetCarry((value & 0x01) != 0)
+
+	return result
+}
+
+// BIT - Test bit, carry is unchanged
+func (cpu *CPU) BIT(bit uint8, value uint8) {
+	cpu.regs.SetZero((value & (1 << bit)) == 0)
+	cpu.regs.SetSubtract(false)
+	cpu.regs.SetHalfCarry(true)
+}
+
+// RES - Reset bit, no flags affected
+func (cpu *CPU) RES(bit uint8, value uint8) uint8 {
+	return value &^ (1 << bit)
+}
+
+// SET - Set bit, no flags affected
+func (cpu *CPU) SET(bit uint8, value uint8) uint8 {
+	return value | (1 << bit)
+}
+
+// <----------------------------- CB OPCODES -----------------------------> //
+
+// 0x00 - RLC B
+func (cpu *CPU) RLC_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.RLC(cpu.regs.b)
+}
+
+// 0x01 - RLC C
+func (cpu *CPU) RLC_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.RLC(cpu.regs.c)
+}
+
+// 0x02 - RLC D
+func (cpu *CPU) RLC_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.RLC(cpu.regs.d)
+}
+
+// 0x03 - RLC E
+func (cpu *CPU) RLC_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.RLC(cpu.regs.e)
+}
+
+// 0x04 - RLC H
+func (cpu *CPU) RLC_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.RLC(cpu.regs.h)
+}
+
+// 0x05 - RLC L
+func (cpu *CPU) RLC_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.RLC(cpu.regs.l)
+}
+
+// 0x06 - RLC (HL)
+func (cpu *CPU) RLC_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.RLC(cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0x07 - RLC A
+func (cpu *CPU) RLC_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.RLC(cpu.regs.a)
+}
+
+// 0x08 - RRC B
+func (cpu *CPU) RRC_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.RRC(cpu.regs.b)
+}
+
+// 0x09 - RRC C
+func (cpu *CPU) RRC_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.RRC(cpu.regs.c)
+}
+
+// 0x0A - RRC D
+func (cpu *CPU) RRC_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.RRC(cpu.regs.d)
+}
+
+// 0x0B - RRC E
+func (cpu *CPU) RRC_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.RRC(cpu.regs.e)
+}
+
+// 0x0C - RRC H
+func (cpu *CPU) RRC_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.RRC(cpu.regs.h)
+}
+
+// 0x0D - RRC L
+func (cpu *CPU) RRC_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.RRC(cpu.regs.l)
+}
+
+// 0x0E - RRC (HL)
+func (cpu *CPU) RRC_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.RRC(cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0x0F - RRC A
+func (cpu *CPU) RRC_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.RRC(cpu.regs.a)
+}
+
+// 0x10 - RL B
+func (cpu *CPU) RL_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.RL(cpu.regs.b)
+}
+
+// 0x11 - RL C
+func (cpu *CPU) RL_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.RL(cpu.regs.c)
+}
+
+// 0x12 - RL D
+func (cpu *CPU) RL_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.RL(cpu.regs.d)
+}
+
+// 0x13 - RL E
+func (cpu *CPU) RL_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.RL(cpu.regs.e)
+}
+
+// 0x14 - RL H
+func (cpu *CPU) RL_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.RL(cpu.regs.h)
+}
+
+// 0x15 - RL L
+func (cpu *CPU) RL_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.RL(cpu.regs.l)
+}
+
+// 0x16 - RL (HL)
+func (cpu *CPU) RL_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.RL(cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0x17 - RL A
+func (cpu *CPU) RL_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.RL(cpu.regs.a)
+}
+
+// 0x18 - RR B
+func (cpu *CPU) RR_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.RR(cpu.regs.b)
+}
+
+// 0x19 - RR C
+func (cpu *CPU) RR_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.RR(cpu.regs.c)
+}
+
+// 0x1A - RR D
+func (cpu *CPU) RR_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.RR(cpu.regs.d)
+}
+
+// 0x1B - RR E
+func (cpu *CPU) RR_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.RR(cpu.regs.e)
+}
+
+// 0x1C - RR H
+func (cpu *CPU) RR_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.RR(cpu.regs.h)
+}
+
+// 0x1D - RR L
+func (cpu *CPU) RR_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.RR(cpu.regs.l)
+}
+
+// 0x1E - RR (HL)
+func (cpu *CPU) RR_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.RR(cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0x1F - RR A
+func (cpu *CPU) RR_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.RR(cpu.regs.a)
+}
+
+// 0x20 - SLA B
+func (cpu *CPU) SLA_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.SLA(cpu.regs.b)
+}
+
+// 0x21 - SLA C
+func (cpu *CPU) SLA_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.SLA(cpu.regs.c)
+}
+
+// 0x22 - SLA D
+func (cpu *CPU) SLA_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.SLA(cpu.regs.d)
+}
+
+// 0x23 - SLA E
+func (cpu *CPU) SLA_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.SLA(cpu.regs.e)
+}
+
+// 0x24 - SLA H
+func (cpu *CPU) SLA_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.SLA(cpu.regs.h)
+}
+
+// 0x25 - SLA L
+func (cpu *CPU) SLA_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.SLA(cpu.regs.l)
+}
+
+// 0x26 - SLA (HL)
+func (cpu *CPU) SLA_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.SLA(cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0x27 - SLA A
+func (cpu *CPU) SLA_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.SLA(cpu.regs.a)
+}
+
+// 0x28 - SRA B
+func (cpu *CPU) SRA_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.SRA(cpu.regs.b)
+}
+
+// 0x29 - SRA C
+func (cpu *CPU) SRA_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.SRA(cpu.regs.c)
+}
+
+// 0x2A - SRA D
+func (cpu *CPU) SRA_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.SRA(cpu.regs.d)
+}
+
+// 0x2B - SRA E
+func (cpu *CPU) SRA_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.SRA(cpu.regs.e)
+}
+
+// 0x2C - SRA H
+func (cpu *CPU) SRA_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.SRA(cpu.regs.h)
+}
+
+// 0x2D - SRA L
+func (cpu *CPU) SRA_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.SRA(cpu.regs.l)
+}
+
+// 0x2E - SRA (HL)
+func (cpu *CPU) SRA_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.SRA(cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0x2F - SRA A
+func (cpu *CPU) SRA_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.SRA(cpu.regs.a)
+}
+
+// 0x30 - SWAP B
+func (cpu *CPU) SWAP_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.SWAP(cpu.regs.b)
+}
+
+// 0x31 - SWAP C
+func (cpu *CPU) SWAP_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.SWAP(cpu.regs.c)
+}
+
+// 0x32 - SWAP D
+func (cpu *CPU) SWAP_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.SWAP(cpu.regs.d)
+}
+
+// 0x33 - SWAP E
+func (cpu *CPU) SWAP_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.SWAP(cpu.regs.e)
+}
+
+// 0x34 - SWAP H
+func (cpu *CPU) SWAP_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.SWAP(cpu.regs.h)
+}
+
+// 0x35 - SWAP L
+func (cpu *CPU) SWAP_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.SWAP(cpu.regs.l)
+}
+
+// 0x36 - SWAP (HL)
+func (cpu *CPU) SWAP_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.SWAP(cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0x37 - SWAP A
+func (cpu *CPU) SWAP_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.SWAP(cpu.regs.a)
+}
+
+// 0x38 - SRL B
+func (cpu *CPU) SRL_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.SRL(cpu.regs.b)
+}
+
+// 0x39 - SRL C
+func (cpu *CPU) SRL_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.SRL(cpu.regs.c)
+}
+
+// 0x3A - SRL D
+func (cpu *CPU) SRL_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.SRL(cpu.regs.d)
+}
+
+// 0x3B - SRL E
+func (cpu *CPU) SRL_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.SRL(cpu.regs.e)
+}
+
+// 0x3C - SRL H
+func (cpu *CPU) SRL_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.SRL(cpu.regs.h)
+}
+
+// 0x3D - SRL L
+func (cpu *CPU) SRL_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.SRL(cpu.regs.l)
+}
+
+// 0x3E - SRL (HL)
+func (cpu *CPU) SRL_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.SRL(cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0x3F - SRL A
+func (cpu *CPU) SRL_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.SRL(cpu.regs.a)
+}
+
+// 0x40 - BIT 0, B
+func (cpu *CPU) BIT_0_B(stepInfo *OperandInfo) {
+	cpu.BIT(0, cpu.regs.b)
+}
+
+// 0x41 - BIT 0, C
+func (cpu *CPU) BIT_0_C(stepInfo *OperandInfo) {
+	cpu.BIT(0, cpu.regs.c)
+}
+
+// 0x42 - BIT 0, D
+func (cpu *CPU) BIT_0_D(stepInfo *OperandInfo) {
+	cpu.BIT(0, cpu.regs.d)
+}
+
+// 0x43 - BIT 0, E
+func (cpu *CPU) BIT_0_E(stepInfo *OperandInfo) {
+	cpu.BIT(0, cpu.regs.e)
+}
+
+// 0x44 - BIT 0, H
+func (cpu *CPU) BIT_0_H(stepInfo *OperandInfo) {
+	cpu.BIT(0, cpu.regs.h)
+}
+
+// 0x45 - BIT 0, L
+func (cpu *CPU) BIT_0_L(stepInfo *OperandInfo) {
+	cpu.BIT(0, cpu.regs.l)
+}
+
+// 0x46 - BIT 0, (HL)
+func (cpu *CPU) BIT_0_HL(stepInfo *OperandInfo) {
+	cpu.BIT(0, cpu.mem.Read8(cpu.regs.GetHL()))
+}
+
+// 0x47 - BIT 0, A
+func (cpu *CPU) BIT_0_A(stepInfo *OperandInfo) {
+	cpu.BIT(0, cpu.regs.a)
+}
+
+// 0x48 - BIT 1, B
+func (cpu *CPU) BIT_1_B(stepInfo *OperandInfo) {
+	cpu.BIT(1, cpu.regs.b)
+}
+
+// 0x49 - BIT 1, C
+func (cpu *CPU) BIT_1_C(stepInfo *OperandInfo) {
+	cpu.BIT(1, cpu.regs.c)
+}
+
+// 0x4A - BIT 1, D
+func (cpu *CPU) BIT_1_D(stepInfo *OperandInfo) {
+	cpu.BIT(1, cpu.regs.d)
+}
+
+// 0x4B - BIT 1, E
+func (cpu *CPU) BIT_1_E(stepInfo *OperandInfo) {
+	cpu.BIT(1, cpu.regs.e)
+}
+
+// 0x4C - BIT 1, H
+func (cpu *CPU) BIT_1_H(stepInfo *OperandInfo) {
+	cpu.BIT(1, cpu.regs.h)
+}
+
+// 0x4D - BIT 1, L
+func (cpu *CPU) BIT_1_L(stepInfo *OperandInfo) {
+	cpu.BIT(1, cpu.regs.l)
+}
+
+// 0x4E - BIT 1, (HL)
+func (cpu *CPU) BIT_1_HL(stepInfo *OperandInfo) {
+	cpu.BIT(1, cpu.mem.Read8(cpu.regs.GetHL()))
+}
+
+// 0x4F - BIT 1, A
+func (cpu *CPU) BIT_1_A(stepInfo *OperandInfo) {
+	cpu.BIT(1, cpu.regs.a)
+}
+
+// 0x50 - BIT 2, B
+func (cpu *CPU) BIT_2_B(stepInfo *OperandInfo) {
+	cpu.BIT(2, cpu.regs.b)
+}
+
+// 0x51 - BIT 2, C
+func (cpu *CPU) BIT_2_C(stepInfo *OperandInfo) {
+	cpu.BIT(2, cpu.regs.c)
+}
+
+// 0x52 - BIT 2, D
+func (cpu *CPU) BIT_2_D(stepInfo *OperandInfo) {
+	cpu.BIT(2, cpu.regs.d)
+}
+
+// 0x53 - BIT 2, E
+func (cpu *CPU) BIT_2_E(stepInfo *OperandInfo) {
+	cpu.BIT(2, cpu.regs.e)
+}
+
+// 0x54 - BIT 2, H
+func (cpu *CPU) BIT_2_H(stepInfo *OperandInfo) {
+	cpu.BIT(2, cpu.regs.h)
+}
+
+// 0x55 - BIT 2, L
+func (cpu *CPU) BIT_2_L(stepInfo *OperandInfo) {
+	cpu.BIT(2, cpu.regs.l)
+}
+
+// 0x56 - BIT 2, (HL)
+func (cpu *CPU) BIT_2_HL(stepInfo *OperandInfo) {
+	cpu.BIT(2, cpu.mem.Read8(cpu.regs.GetHL()))
+}
+
+// 0x57 - BIT 2, A
+func (cpu *CPU) BIT_2_A(stepInfo *OperandInfo) {
+	cpu.BIT(2, cpu.regs.a)
+}
+
+// 0x58 - BIT 3, B
+func (cpu *CPU) BIT_3_B(stepInfo *OperandInfo) {
+	cpu.BIT(3, cpu.regs.b)
+}
+
+// 0x59 - BIT 3, C
+func (cpu *CPU) BIT_3_C(stepInfo *OperandInfo) {
+	cpu.BIT(3, cpu.regs.c)
+}
+
+// 0x5A - BIT 3, D
+func (cpu *CPU) BIT_3_D(stepInfo *OperandInfo) {
+	cpu.BIT(3, cpu.regs.d)
+}
+
+// 0x5B - BIT 3, E
+func (cpu *CPU) BIT_3_E(stepInfo *OperandInfo) {
+	cpu.BIT(3, cpu.regs.e)
+}
+
+// 0x5C - BIT 3, H
+func (cpu *CPU) BIT_3_H(stepInfo *OperandInfo) {
+	cpu.BIT(3, cpu.regs.h)
+}
+
+// 0x5D - BIT 3, L
+func (cpu *CPU) BIT_3_L(stepInfo *OperandInfo) {
+	cpu.BIT(3, cpu.regs.l)
+}
+
+// 0x5E - BIT 3, (HL)
+func (cpu *CPU) BIT_3_HL(stepInfo *OperandInfo) {
+	cpu.BIT(3, cpu.mem.Read8(cpu.regs.GetHL()))
+}
+
+// 0x5F - BIT 3, A
+func (cpu *CPU) BIT_3_A(stepInfo *OperandInfo) {
+	cpu.BIT(3, cpu.regs.a)
+}
+
+// 0x60 - BIT 4, B
+func (cpu *CPU) BIT_4_B(stepInfo *OperandInfo) {
+	cpu.BIT(4, cpu.regs.b)
+}
+
+// 0x61 - BIT 4, C
+func (cpu *CPU) BIT_4_C(stepInfo *OperandInfo) {
+	cpu.BIT(4, cpu.regs.c)
+}
+
+// 0x62 - BIT 4, D
+func (cpu *CPU) BIT_4_D(stepInfo *OperandInfo) {
+	cpu.BIT(4, cpu.regs.d)
+}
+
+// 0x63 - BIT 4, E
+func (cpu *CPU) BIT_4_E(stepInfo *OperandInfo) {
+	cpu.BIT(4, cpu.regs.e)
+}
+
+// 0x64 - BIT 4, H
+func (cpu *CPU) BIT_4_H(stepInfo *OperandInfo) {
+	cpu.BIT(4, cpu.regs.h)
+}
+
+// 0x65 - BIT 4, L
+func (cpu *CPU) BIT_4_L(stepInfo *OperandInfo) {
+	cpu.BIT(4, cpu.regs.l)
+}
+
+// 0x66 - BIT 4, (HL)
+func (cpu *CPU) BIT_4_HL(stepInfo *OperandInfo) {
+	cpu.BIT(4, cpu.mem.Read8(cpu.regs.GetHL()))
+}
+
+// 0x67 - BIT 4, A
+func (cpu *CPU) BIT_4_A(stepInfo *OperandInfo) {
+	cpu.BIT(4, cpu.regs.a)
+}
+
+// 0x68 - BIT 5, B
+func (cpu *CPU) BIT_5_B(stepInfo *OperandInfo) {
+	cpu.BIT(5, cpu.regs.b)
+}
+
+// 0x69 - BIT 5, C
+func (cpu *CPU) BIT_5_C(stepInfo *OperandInfo) {
+	cpu.BIT(5, cpu.regs.c)
+}
+
+// 0x6A - BIT 5, D
+func (cpu *CPU) BIT_5_D(stepInfo *OperandInfo) {
+	cpu.BIT(5, cpu.regs.d)
+}
+
+// 0x6B - BIT 5, E
+func (cpu *CPU) BIT_5_E(stepInfo *OperandInfo) {
+	cpu.BIT(5, cpu.regs.e)
+}
+
+// 0x6C - BIT 5, H
+func (cpu *CPU) BIT_5_H(stepInfo *OperandInfo) {
+	cpu.BIT(5, cpu.regs.h)
+}
+
+// 0x6D - BIT 5, L
+func (cpu *CPU) BIT_5_L(stepInfo *OperandInfo) {
+	cpu.BIT(5, cpu.regs.l)
+}
+
+// 0x6E - BIT 5, (HL)
+func (cpu *CPU) BIT_5_HL(stepInfo *OperandInfo) {
+	cpu.BIT(5, cpu.mem.Read8(cpu.regs.GetHL()))
+}
+
+// 0x6F - BIT 5, A
+func (cpu *CPU) BIT_5_A(stepInfo *OperandInfo) {
+	cpu.BIT(5, cpu.regs.a)
+}
+
+// 0x70 - BIT 6, B
+func (cpu *CPU) BIT_6_B(stepInfo *OperandInfo) {
+	cpu.BIT(6, cpu.regs.b)
+}
+
+// 0x71 - BIT 6, C
+func (cpu *CPU) BIT_6_C(stepInfo *OperandInfo) {
+	cpu.BIT(6, cpu.regs.c)
+}
+
+// 0x72 - BIT 6, D
+func (cpu *CPU) BIT_6_D(stepInfo *OperandInfo) {
+	cpu.BIT(6, cpu.regs.d)
+}
+
+// 0x73 - BIT 6, E
+func (cpu *CPU) BIT_6_E(stepInfo *OperandInfo) {
+	cpu.BIT(6, cpu.regs.e)
+}
+
+// 0x74 - BIT 6, H
+func (cpu *CPU) BIT_6_H(stepInfo *OperandInfo) {
+	cpu.BIT(6, cpu.regs.h)
+}
+
+// 0x75 - BIT 6, L
+func (cpu *CPU) BIT_6_L(stepInfo *OperandInfo) {
+	cpu.BIT(6, cpu.regs.l)
+}
+
+// 0x76 - BIT 6, (HL)
+func (cpu *CPU) BIT_6_HL(stepInfo *OperandInfo) {
+	cpu.BIT(6, cpu.mem.Read8(cpu.regs.GetHL()))
+}
+
+// 0x77 - BIT 6, A
+func (cpu *CPU) BIT_6_A(stepInfo *OperandInfo) {
+	cpu.BIT(6, cpu.regs.a)
+}
+
+// 0x78 - BIT 7, B
+func (cpu *CPU) BIT_7_B(stepInfo *OperandInfo) {
+	cpu.BIT(7, cpu.regs.b)
+}
+
+// 0x79 - BIT 7, C
+func (cpu *CPU) BIT_7_C(stepInfo *OperandInfo) {
+	cpu.BIT(7, cpu.regs.c)
+}
+
+// 0x7A - BIT 7, D
+func (cpu *CPU) BIT_7_D(stepInfo *OperandInfo) {
+	cpu.BIT(7, cpu.regs.d)
+}
+
+// 0x7B - BIT 7, E
+func (cpu *CPU) BIT_7_E(stepInfo *OperandInfo) {
+	cpu.BIT(7, cpu.regs.e)
+}
+
+// 0x7C - BIT 7, H
+func (cpu *CPU) BIT_7_H(stepInfo *OperandInfo) {
+	cpu.BIT(7, cpu.regs.h)
+}
+
+// 0x7D - BIT 7, L
+func (cpu *CPU) BIT_7_L(stepInfo *OperandInfo) {
+	cpu.BIT(7, cpu.regs.l)
+}
+
+// 0x7E - BIT 7, (HL)
+func (cpu *CPU) BIT_7_HL(stepInfo *OperandInfo) {
+	cpu.BIT(7, cpu.mem.Read8(cpu.regs.GetHL()))
+}
+
+// 0x7F - BIT 7, A
+func (cpu *CPU) BIT_7_A(stepInfo *OperandInfo) {
+	cpu.BIT(7, cpu.regs.a)
+}
+
+// 0x80 - RES 0, B
+func (cpu *CPU) RES_0_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.RES(0, cpu.regs.b)
+}
+
+// 0x81 - RES 0, C
+func (cpu *CPU) RES_0_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.RES(0, cpu.regs.c)
+}
+
+// 0x82 - RES 0, D
+func (cpu *CPU) RES_0_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.RES(0, cpu.regs.d)
+}
+
+// 0x83 - RES 0, E
+func (cpu *CPU) RES_0_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.RES(0, cpu.regs.e)
+}
+
+// 0x84 - RES 0, H
+func (cpu *CPU) RES_0_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.RES(0, cpu.regs.h)
+}
+
+// 0x85 - RES 0, L
+func (cpu *CPU) RES_0_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.RES(0, cpu.regs.l)
+}
+
+// 0x86 - RES 0, (HL)
+func (cpu *CPU) RES_0_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.RES(0, cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0x87 - RES 0, A
+func (cpu *CPU) RES_0_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.RES(0, cpu.regs.a)
+}
+
+// 0x88 - RES 1, B
+func (cpu *CPU) RES_1_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.RES(1, cpu.regs.b)
+}
+
+// 0x89 - RES 1, C
+func (cpu *CPU) RES_1_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.RES(1, cpu.regs.c)
+}
+
+// 0x8A - RES 1, D
+func (cpu *CPU) RES_1_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.RES(1, cpu.regs.d)
+}
+
+// 0x8B - RES 1, E
+func (cpu *CPU) RES_1_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.RES(1, cpu.regs.e)
+}
+
+// 0x8C - RES 1, H
+func (cpu *CPU) RES_1_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.RES(1, cpu.regs.h)
+}
+
+// 0x8D - RES 1, L
+func (cpu *CPU) RES_1_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.RES(1, cpu.regs.l)
+}
+
+// 0x8E - RES 1, (HL)
+func (cpu *CPU) RES_1_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.RES(1, cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0x8F - RES 1, A
+func (cpu *CPU) RES_1_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.RES(1, cpu.regs.a)
+}
+
+// 0x90 - RES 2, B
+func (cpu *CPU) RES_2_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.RES(2, cpu.regs.b)
+}
+
+// 0x91 - RES 2, C
+func (cpu *CPU) RES_2_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.RES(2, cpu.regs.c)
+}
+
+// 0x92 - RES 2, D
+func (cpu *CPU) RES_2_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.RES(2, cpu.regs.d)
+}
+
+// 0x93 - RES 2, E
+func (cpu *CPU) RES_2_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.RES(2, cpu.regs.e)
+}
+
+// 0x94 - RES 2, H
+func (cpu *CPU) RES_2_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.RES(2, cpu.regs.h)
+}
+
+// 0x95 - RES 2, L
+func (cpu *CPU) RES_2_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.RES(2, cpu.regs.l)
+}
+
+// 0x96 - RES 2, (HL)
+func (cpu *CPU) RES_2_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.RES(2, cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0x97 - RES 2, A
+func (cpu *CPU) RES_2_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.RES(2, cpu.regs.a)
+}
+
+// 0x98 - RES 3, B
+func (cpu *CPU) RES_3_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.RES(3, cpu.regs.b)
+}
+
+// 0x99 - RES 3, C
+func (cpu *CPU) RES_3_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.RES(3, cpu.regs.c)
+}
+
+// 0x9A - RES 3, D
+func (cpu *CPU) RES_3_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.RES(3, cpu.regs.d)
+}
+
+// 0x9B - RES 3, E
+func (cpu *CPU) RES_3_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.RES(3, cpu.regs.e)
+}
+
+// 0x9C - RES 3, H
+func (cpu *CPU) RES_3_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.RES(3, cpu.regs.h)
+}
+
+// 0x9D - RES 3, L
+func (cpu *CPU) RES_3_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.RES(3, cpu.regs.l)
+}
+
+// 0x9E - RES 3, (HL)
+func (cpu *CPU) RES_3_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.RES(3, cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0x9F - RES 3, A
+func (cpu *CPU) RES_3_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.RES(3, cpu.regs.a)
+}
+
+// 0xA0 - RES 4, B
+func (cpu *CPU) RES_4_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.RES(4, cpu.regs.b)
+}
+
+// 0xA1 - RES 4, C
+func (cpu *CPU) RES_4_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.RES(4, cpu.regs.c)
+}
+
+// 0xA2 - RES 4, D
+func (cpu *CPU) RES_4_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.RES(4, cpu.regs.d)
+}
+
+// 0xA3 - RES 4, E
+func (cpu *CPU) RES_4_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.RES(4, cpu.regs.e)
+}
+
+// 0xA4 - RES 4, H
+func (cpu *CPU) RES_4_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.RES(4, cpu.regs.h)
+}
+
+// 0xA5 - RES 4, L
+func (cpu *CPU) RES_4_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.RES(4, cpu.regs.l)
+}
+
+// 0xA6 - RES 4, (HL)
+func (cpu *CPU) RES_4_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.RES(4, cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0xA7 - RES 4, A
+func (cpu *CPU) RES_4_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.RES(4, cpu.regs.a)
+}
+
+// 0xA8 - RES 5, B
+func (cpu *CPU) RES_5_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.RES(5, cpu.regs.b)
+}
+
+// 0xA9 - RES 5, C
+func (cpu *CPU) RES_5_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.RES(5, cpu.regs.c)
+}
+
+// 0xAA - RES 5, D
+func (cpu *CPU) RES_5_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.RES(5, cpu.regs.d)
+}
+
+// 0xAB - RES 5, E
+func (cpu *CPU) RES_5_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.RES(5, cpu.regs.e)
+}
+
+// 0xAC - RES 5, H
+func (cpu *CPU) RES_5_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.RES(5, cpu.regs.h)
+}
+
+// 0xAD - RES 5, L
+func (cpu *CPU) RES_5_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.RES(5, cpu.regs.l)
+}
+
+// 0xAE - RES 5, (HL)
+func (cpu *CPU) RES_5_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.RES(5, cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0xAF - RES 5, A
+func (cpu *CPU) RES_5_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.RES(5, cpu.regs.a)
+}
+
+// 0xB0 - RES 6, B
+func (cpu *CPU) RES_6_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.RES(6, cpu.regs.b)
+}
+
+// 0xB1 - RES 6, C
+func (cpu *CPU) RES_6_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.RES(6, cpu.regs.c)
+}
+
+// 0xB2 - RES 6, D
+func (cpu *CPU) RES_6_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.RES(6, cpu.regs.d)
+}
+
+// 0xB3 - RES 6, E
+func (cpu *CPU) RES_6_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.RES(6, cpu.regs.e)
+}
+
+// 0xB4 - RES 6, H
+func (cpu *CPU) RES_6_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.RES(6, cpu.regs.h)
+}
+
+// 0xB5 - RES 6, L
+func (cpu *CPU) RES_6_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.RES(6, cpu.regs.l)
+}
+
+// 0xB6 - RES 6, (HL)
+func (cpu *CPU) RES_6_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.RES(6, cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0xB7 - RES 6, A
+func (cpu *CPU) RES_6_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.RES(6, cpu.regs.a)
+}
+
+// 0xB8 - RES 7, B
+func (cpu *CPU) RES_7_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.RES(7, cpu.regs.b)
+}
+
+// 0xB9 - RES 7, C
+func (cpu *CPU) RES_7_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.RES(7, cpu.regs.c)
+}
+
+// 0xBA - RES 7, D
+func (cpu *CPU) RES_7_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.RES(7, cpu.regs.d)
+}
+
+// 0xBB - RES 7, E
+func (cpu *CPU) RES_7_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.RES(7, cpu.regs.e)
+}
+
+// 0xBC - RES 7, H
+func (cpu *CPU) RES_7_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.RES(7, cpu.regs.h)
+}
+
+// 0xBD - RES 7, L
+func (cpu *CPU) RES_7_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.RES(7, cpu.regs.l)
+}
+
+// 0xBE - RES 7, (HL)
+func (cpu *CPU) RES_7_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.RES(7, cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0xBF - RES 7, A
+func (cpu *CPU) RES_7_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.RES(7, cpu.regs.a)
+}
+
+// 0xC0 - SET 0, B
+func (cpu *CPU) SET_0_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.SET(0, cpu.regs.b)
+}
+
+// 0xC1 - SET 0, C
+func (cpu *CPU) SET_0_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.SET(0, cpu.regs.c)
+}
+
+// 0xC2 - SET 0, D
+func (cpu *CPU) SET_0_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.SET(0, cpu.regs.d)
+}
+
+// 0xC3 - SET 0, E
+func (cpu *CPU) SET_0_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.SET(0, cpu.regs.e)
+}
+
+// 0xC4 - SET 0, H
+func (cpu *CPU) SET_0_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.SET(0, cpu.regs.h)
+}
+
+// 0xC5 - SET 0, L
+func (cpu *CPU) SET_0_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.SET(0, cpu.regs.l)
+}
+
+// 0xC6 - SET 0, (HL)
+func (cpu *CPU) SET_0_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.SET(0, cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0xC7 - SET 0, A
+func (cpu *CPU) SET_0_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.SET(0, cpu.regs.a)
+}
+
+// 0xC8 - SET 1, B
+func (cpu *CPU) SET_1_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.SET(1, cpu.regs.b)
+}
+
+// 0xC9 - SET 1, C
+func (cpu *CPU) SET_1_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.SET(1, cpu.regs.c)
+}
+
+// 0xCA - SET 1, D
+func (cpu *CPU) SET_1_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.SET(1, cpu.regs.d)
+}
+
+// 0xCB - SET 1, E
+func (cpu *CPU) SET_1_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.SET(1, cpu.regs.e)
+}
+
+// 0xCC - SET 1, H
+func (cpu *CPU) SET_1_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.SET(1, cpu.regs.h)
+}
+
+// 0xCD - SET 1, L
+func (cpu *CPU) SET_1_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.SET(1, cpu.regs.l)
+}
+
+// 0xCE - SET 1, (HL)
+func (cpu *CPU) SET_1_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.SET(1, cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0xCF - SET 1, A
+func (cpu *CPU) SET_1_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.SET(1, cpu.regs.a)
+}
+
+// 0xD0 - SET 2, B
+func (cpu *CPU) SET_2_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.SET(2, cpu.regs.b)
+}
+
+// 0xD1 - SET 2, C
+func (cpu *CPU) SET_2_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.SET(2, cpu.regs.c)
+}
+
+// 0xD2 - SET 2, D
+func (cpu *CPU) SET_2_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.SET(2, cpu.regs.d)
+}
+
+// 0xD3 - SET 2, E
+func (cpu *CPU) SET_2_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.SET(2, cpu.regs.e)
+}
+
+// 0xD4 - SET 2, H
+func (cpu *CPU) SET_2_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.SET(2, cpu.regs.h)
+}
+
+// 0xD5 - SET 2, L
+func (cpu *CPU) SET_2_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.SET(2, cpu.regs.l)
+}
+
+// 0xD6 - SET 2, (HL)
+func (cpu *CPU) SET_2_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.SET(2, cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0xD7 - SET 2, A
+func (cpu *CPU) SET_2_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.SET(2, cpu.regs.a)
+}
+
+// 0xD8 - SET 3, B
+func (cpu *CPU) SET_3_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.SET(3, cpu.regs.b)
+}
+
+// 0xD9 - SET 3, C
+func (cpu *CPU) SET_3_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.SET(3, cpu.regs.c)
+}
+
+// 0xDA - SET 3, D
+func (cpu *CPU) SET_3_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.SET(3, cpu.regs.d)
+}
+
+// 0xDB - SET 3, E
+func (cpu *CPU) SET_3_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.SET(3, cpu.regs.e)
+}
+
+// 0xDC - SET 3, H
+func (cpu *CPU) SET_3_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.SET(3, cpu.regs.h)
+}
+
+// 0xDD - SET 3, L
+func (cpu *CPU) SET_3_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.SET(3, cpu.regs.l)
+}
+
+// 0xDE - SET 3, (HL)
+func (cpu *CPU) SET_3_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.SET(3, cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0xDF - SET 3, A
+func (cpu *CPU) SET_3_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.SET(3, cpu.regs.a)
+}
+
+// 0xE0 - SET 4, B
+func (cpu *CPU) SET_4_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.SET(4, cpu.regs.b)
+}
+
+// 0xE1 - SET 4, C
+func (cpu *CPU) SET_4_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.SET(4, cpu.regs.c)
+}
+
+// 0xE2 - SET 4, D
+func (cpu *CPU) SET_4_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.SET(4, cpu.regs.d)
+}
+
+// 0xE3 - SET 4, E
+func (cpu *CPU) SET_4_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.SET(4, cpu.regs.e)
+}
+
+// 0xE4 - SET 4, H
+func (cpu *CPU) SET_4_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.SET(4, cpu.regs.h)
+}
+
+// 0xE5 - SET 4, L
+func (cpu *CPU) SET_4_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.SET(4, cpu.regs.l)
+}
+
+// 0xE6 - SET 4, (HL)
+func (cpu *CPU) SET_4_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.SET(4, cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0xE7 - SET 4, A
+func (cpu *CPU) SET_4_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.SET(4, cpu.regs.a)
+}
+
+// 0xE8 - SET 5, B
+func (cpu *CPU) SET_5_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.SET(5, cpu.regs.b)
+}
+
+// 0xE9 - SET 5, C
+func (cpu *CPU) SET_5_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.SET(5, cpu.regs.c)
+}
+
+// 0xEA - SET 5, D
+func (cpu *CPU) SET_5_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.SET(5, cpu.regs.d)
+}
+
+// 0xEB - SET 5, E
+func (cpu *CPU) SET_5_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.SET(5, cpu.regs.e)
+}
+
+// 0xEC - SET 5, H
+func (cpu *CPU) SET_5_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.SET(5, cpu.regs.h)
+}
+
+// 0xED - SET 5, L
+func (cpu *CPU) SET_5_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.SET(5, cpu.regs.l)
+}
+
+// 0xEE - SET 5, (HL)
+func (cpu *CPU) SET_5_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.SET(5, cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0xEF - SET 5, A
+func (cpu *CPU) SET_5_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.SET(5, cpu.regs.a)
+}
+
+// 0xF0 - SET 6, B
+func (cpu *CPU) SET_6_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.SET(6, cpu.regs.b)
+}
+
+// 0xF1 - SET 6, C
+func (cpu *CPU) SET_6_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.SET(6, cpu.regs.c)
+}
+
+// 0xF2 - SET 6, D
+func (cpu *CPU) SET_6_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.SET(6, cpu.regs.d)
+}
+
+// 0xF3 - SET 6, E
+func (cpu *CPU) SET_6_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.SET(6, cpu.regs.e)
+}
+
+// 0xF4 - SET 6, H
+func (cpu *CPU) SET_6_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.SET(6, cpu.regs.h)
+}
+
+// 0xF5 - SET 6, L
+func (cpu *CPU) SET_6_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.SET(6, cpu.regs.l)
+}
+
+// 0xF6 - SET 6, (HL)
+func (cpu *CPU) SET_6_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.SET(6, cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0xF7 - SET 6, A
+func (cpu *CPU) SET_6_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.SET(6, cpu.regs.a)
+}
+
+// 0xF8 - SET 7, B
+func (cpu *CPU) SET_7_B(stepInfo *OperandInfo) {
+	cpu.regs.b = cpu.SET(7, cpu.regs.b)
+}
+
+// 0xF9 - SET 7, C
+func (cpu *CPU) SET_7_C(stepInfo *OperandInfo) {
+	cpu.regs.c = cpu.SET(7, cpu.regs.c)
+}
+
+// 0xFA - SET 7, D
+func (cpu *CPU) SET_7_D(stepInfo *OperandInfo) {
+	cpu.regs.d = cpu.SET(7, cpu.regs.d)
+}
+
+// 0xFB - SET 7, E
+func (cpu *CPU) SET_7_E(stepInfo *OperandInfo) {
+	cpu.regs.e = cpu.SET(7, cpu.regs.e)
+}
+
+// 0xFC - SET 7, H
+func (cpu *CPU) SET_7_H(stepInfo *OperandInfo) {
+	cpu.regs.h = cpu.SET(7, cpu.regs.h)
+}
+
+// 0xFD - SET 7, L
+func (cpu *CPU) SET_7_L(stepInfo *OperandInfo) {
+	cpu.regs.l = cpu.SET(7, cpu.regs.l)
+}
+
+// 0xFE - SET 7, (HL)
+func (cpu *CPU) SET_7_HL(stepInfo *OperandInfo) {
+	cpu.mem.Write8(cpu.regs.GetHL(), cpu.SET(7, cpu.mem.Read8(cpu.regs.GetHL())))
+}
+
+// 0xFF - SET 7, A
+func (cpu *CPU) SET_7_A(stepInfo *OperandInfo) {
+	cpu.regs.a = cpu.SET(7, cpu.regs.a)
+}
+
+// <----------------------------- CB EXECUTION -----------------------------> //
+
+func (cpu *CPU) CreateCBTable() {
+	cpu.cbTable = [256]Instruction{
+		{"RLC B", 2, cpu.RLC_B},          // 0x00
+		{"RLC C", 2, cpu.RLC_C},          // 0x01
+		{"RLC D", 2, cpu.RLC_D},          // 0x02
+		{"RLC E", 2, cpu.RLC_E},          // 0x03
+		{"RLC H", 2, cpu.RLC_H},          // 0x04
+		{"RLC L", 2, cpu.RLC_L},          // 0x05
+		{"RLC (HL)", 2, cpu.RLC_HL},      // 0x06
+		{"RLC A", 2, cpu.RLC_A},          // 0x07
+		{"RRC B", 2, cpu.RRC_B},          // 0x08
+		{"RRC C", 2, cpu.RRC_C},          // 0x09
+		{"RRC D", 2, cpu.RRC_D},          // 0x0A
+		{"RRC E", 2, cpu.RRC_E},          // 0x0B
+		{"RRC H", 2, cpu.RRC_H},          // 0x0C
+		{"RRC L", 2, cpu.RRC_L},          // 0x0D
+		{"RRC (HL)", 2, cpu.RRC_HL},      // 0x0E
+		{"RRC A", 2, cpu.RRC_A},          // 0x0F
+		{"RL B", 2, cpu.RL_B},            // 0x10
+		{"RL C", 2, cpu.RL_C},            // 0x11
+		{"RL D", 2, cpu.RL_D},            // 0x12
+		{"RL E", 2, cpu.RL_E},            // 0x13
+		{"RL H", 2, cpu.RL_H},            // 0x14
+		{"RL L", 2, cpu.RL_L},            // 0x15
+		{"RL (HL)", 2, cpu.RL_HL},        // 0x16
+		{"RL A", 2, cpu.RL_A},            // 0x17
+		{"RR B", 2, cpu.RR_B},            // 0x18
+		{"RR C", 2, cpu.RR_C},            // 0x19
+		{"RR D", 2, cpu.RR_D},            // 0x1A
+		{"RR E", 2, cpu.RR_E},            // 0x1B
+		{"RR H", 2, cpu.RR_H},            // 0x1C
+		{"RR L", 2, cpu.RR_L},            // 0x1D
+		{"RR (HL)", 2, cpu.RR_HL},        // 0x1E
+		{"RR A", 2, cpu.RR_A},            // 0x1F
+		{"SLA B", 2, cpu.SLA_B},          // 0x20
+		{"SLA C", 2, cpu.SLA_C},          // 0x21
+		{"SLA D", 2, cpu.SLA_D},          // 0x22
+		{"SLA E", 2, cpu.SLA_E},          // 0x23
+		{"SLA H", 2, cpu.SLA_H},          // 0x24
+		{"SLA L", 2, cpu.SLA_L},          // 0x25
+		{"SLA (HL)", 2, cpu.SLA_HL},      // 0x26
+		{"SLA A", 2, cpu.SLA_A},          // 0x27
+		{"SRA B", 2, cpu.SRA_B},          // 0x28
+		{"SRA C", 2, cpu.SRA_C},          // 0x29
+		{"SRA D", 2, cpu.SRA_D},          // 0x2A
+		{"SRA E", 2, cpu.SRA_E},          // 0x2B
+		{"SRA H", 2, cpu.SRA_H},          // 0x2C
+		{"SRA L", 2, cpu.SRA_L},          // 0x2D
+		{"SRA (HL)", 2, cpu.SRA_HL},      // 0x2E
+		{"SRA A", 2, cpu.SRA_A},          // 0x2F
+		{"SWAP B", 2, cpu.SWAP_B},        // 0x30
+		{"SWAP C", 2, cpu.SWAP_C},        // 0x31
+		{"SWAP D", 2, cpu.SWAP_D},        // 0x32
+		{"SWAP E", 2, cpu.SWAP_E},        // 0x33
+		{"SWAP H", 2, cpu.SWAP_H},        // 0x34
+		{"SWAP L", 2, cpu.SWAP_L},        // 0x35
+		{"SWAP (HL)", 2, cpu.SWAP_HL},    // 0x36
+		{"SWAP A", 2, cpu.SWAP_A},        // 0x37
+		{"SRL B", 2, cpu.SRL_B},          // 0x38
+		{"SRL C", 2, cpu.SRL_C},          // 0x39
+		{"SRL D", 2, cpu.SRL_D},          // 0x3A
+		{"SRL E", 2, cpu.SRL_E},          // 0x3B
+		{"SRL H", 2, cpu.SRL_H},          // 0x3C
+		{"SRL L", 2, cpu.SRL_L},          // 0x3D
+		{"SRL (HL)", 2, cpu.SRL_HL},      // 0x3E
+		{"SRL A", 2, cpu.SRL_A},          // 0x3F
+		{"BIT 0, B", 2, cpu.BIT_0_B},     // 0x40
+		{"BIT 0, C", 2, cpu.BIT_0_C},     // 0x41
+		{"BIT 0, D", 2, cpu.BIT_0_D},     // 0x42
+		{"BIT 0, E", 2, cpu.BIT_0_E},     // 0x43
+		{"BIT 0, H", 2, cpu.BIT_0_H},     // 0x44
+		{"BIT 0, L", 2, cpu.BIT_0_L},     // 0x45
+		{"BIT 0, (HL)", 2, cpu.BIT_0_HL}, // 0x46
+		{"BIT 0, A", 2, cpu.BIT_0_A},     // 0x47
+		{"BIT 1, B", 2, cpu.BIT_1_B},     // 0x48
+		{"BIT 1, C", 2, cpu.BIT_1_C},     // 0x49
+		{"BIT 1, D", 2, cpu.BIT_1_D},     // 0x4A
+		{"BIT 1, E", 2, cpu.BIT_1_E},     // 0x4B
+		{"BIT 1, H", 2, cpu.BIT_1_H},     // 0x4C
+		{"BIT 1, L", 2, cpu.BIT_1_L},     // 0x4D
+		{"BIT 1, (HL)", 2, cpu.BIT_1_HL}, // 0x4E
+		{"BIT 1, A", 2, cpu.BIT_1_A},     // 0x4F
+		{"BIT 2, B", 2, cpu.BIT_2_B},     // 0x50
+		{"BIT 2, C", 2, cpu.BIT_2_C},     // 0x51
+		{"BIT 2, D", 2, cpu.BIT_2_D},     // 0x52
+		{"BIT 2, E", 2, cpu.BIT_2_E},     // 0x53
+		{"BIT 2, H", 2, cpu.BIT_2_H},     // 0x54
+		{"BIT 2, L", 2, cpu.BIT_2_L},     // 0x55
+		{"BIT 2, (HL)", 2, cpu.BIT_2_HL}, // 0x56
+		{"BIT 2, A", 2, cpu.BIT_2_A},     // 0x57
+		{"BIT 3, B", 2, cpu.BIT_3_B},     // 0x58
+		{"BIT 3, C", 2, cpu.BIT_3_C},     // 0x59
+		{"BIT 3, D", 2, cpu.BIT_3_D},     // 0x5A
+		{"BIT 3, E", 2, cpu.BIT_3_E},     // 0x5B
+		{"BIT 3, H", 2, cpu.BIT_3_H},     // 0x5C
+		{"BIT 3, L", 2, cpu.BIT_3_L},     // 0x5D
+		{"BIT 3, (HL)", 2, cpu.BIT_3_HL}, // 0x5E
+		{"BIT 3, A", 2, cpu.BIT_3_A},     // 0x5F
+		{"BIT 4, B", 2, cpu.BIT_4_B},     // 0x60
+		{"BIT 4, C", 2, cpu.BIT_4_C},     // 0x61
+		{"BIT 4, D", 2, cpu.BIT_4_D},     // 0x62
+		{"BIT 4, E", 2, cpu.BIT_4_E},     // 0x63
+		{"BIT 4, H", 2, cpu.BIT_4_H},     // 0x64
+		{"BIT 4, L", 2, cpu.BIT_4_L},     // 0x65
+		{"BIT 4, (HL)", 2, cpu.BIT_4_HL}, // 0x66
+		{"BIT 4, A", 2, cpu.BIT_4_A},     // 0x67
+		{"BIT 5, B", 2, cpu.BIT_5_B},     // 0x68
+		{"BIT 5, C", 2, cpu.BIT_5_C},     // 0x69
+		{"BIT 5, D", 2, cpu.BIT_5_D},     // 0x6A
+		{"BIT 5, E", 2, cpu.BIT_5_E},     // 0x6B
+		{"BIT 5, H", 2, cpu.BIT_5_H},     // 0x6C
+		{"BIT 5, L", 2, cpu.BIT_5_L},     // 0x6D
+		{"BIT 5, (HL)", 2, cpu.BIT_5_HL}, // 0x6E
+		{"BIT 5, A", 2, cpu.BIT_5_A},     // 0x6F
+		{"BIT 6, B", 2, cpu.BIT_6_B},     // 0x70
+		{"BIT 6, C", 2, cpu.BIT_6_C},     // 0x71
+		{"BIT 6, D", 2, cpu.BIT_6_D},     // 0x72
+		{"BIT 6, E", 2, cpu.BIT_6_E},     // 0x73
+		{"BIT 6, H", 2, cpu.BIT_6_H},     // 0x74
+		{"BIT 6, L", 2, cpu.BIT_6_L},     // 0x75
+		{"BIT 6, (HL)", 2, cpu.BIT_6_HL}, // 0x76
+		{"BIT 6, A", 2, cpu.BIT_6_A},     // 0x77
+		{"BIT 7, B", 2, cpu.BIT_7_B},     // 0x78
+		{"BIT 7, C", 2, cpu.BIT_7_C},     // 0x79
+		{"BIT 7, D", 2, cpu.BIT_7_D},     // 0x7A
+		{"BIT 7, E", 2, cpu.BIT_7_E},     // 0x7B
+		{"BIT 7, H", 2, cpu.BIT_7_H},     // 0x7C
+		{"BIT 7, L", 2, cpu.BIT_7_L},     // 0x7D
+		{"BIT 7, (HL)", 2, cpu.BIT_7_HL}, // 0x7E
+		{"BIT 7, A", 2, cpu.BIT_7_A},     // 0x7F
+		{"RES 0, B", 2, cpu.RES_0_B},     // 0x80
+		{"RES 0, C", 2, cpu.RES_0_C},     // 0x81
+		{"RES 0, D", 2, cpu.RES_0_D},     // 0x82
+		{"RES 0, E", 2, cpu.RES_0_E},     // 0x83
+		{"RES 0, H", 2, cpu.RES_0_H},     // 0x84
+		{"RES 0, L", 2, cpu.RES_0_L},     // 0x85
+		{"RES 0, (HL)", 2, cpu.RES_0_HL}, // 0x86
+		{"RES 0, A", 2, cpu.RES_0_A},     // 0x87
+		{"RES 1, B", 2, cpu.RES_1_B},     // 0x88
+		{"RES 1, C", 2, cpu.RES_1_C},     // 0x89
+		{"RES 1, D", 2, cpu.RES_1_D},     // 0x8A
+		{"RES 1, E", 2, cpu.RES_1_E},     // 0x8B
+		{"RES 1, H", 2, cpu.RES_1_H},     // 0x8C
+		{"RES 1, L", 2, cpu.RES_1_L},     // 0x8D
+		{"RES 1, (HL)", 2, cpu.RES_1_HL}, // 0x8E
+		{"RES 1, A", 2, cpu.RES_1_A},     // 0x8F
+		{"RES 2, B", 2, cpu.RES_2_B},     // 0x90
+		{"RES 2, C", 2, cpu.RES_2_C},     // 0x91
+		{"RES 2, D", 2, cpu.RES_2_D},     // 0x92
+		{"RES 2, E", 2, cpu.RES_2_E},     // 0x93
+		{"RES 2, H", 2, cpu.RES_2_H},     // 0x94
+		{"RES 2, L", 2, cpu.RES_2_L},     // 0x95
+		{"RES 2, (HL)", 2, cpu.RES_2_HL}, // 0x96
+		{"RES 2, A", 2, cpu.RES_2_A},     // 0x97
+		{"RES 3, B", 2, cpu.RES_3_B},     // 0x98
+		{"RES 3, C", 2, cpu.RES_3_C},     // 0x99
+		{"RES 3, D", 2, cpu.RES_3_D},     // 0x9A
+		{"RES 3, E", 2, cpu.RES_3_E},     // 0x9B
+		{"RES 3, H", 2, cpu.RES_3_H},     // 0x9C
+		{"RES 3, L", 2, cpu.RES_3_L},     // 0x9D
+		{"RES 3, (HL)", 2, cpu.RES_3_HL}, // 0x9E
+		{"RES 3, A", 2, cpu.RES_3_A},     // 0x9F
+		{"RES 4, B", 2, cpu.RES_4_B},     // 0xA0
+		{"RES 4, C", 2, cpu.RES_4_C},     // 0xA1
+		{"RES 4, D", 2, cpu.RES_4_D},     // 0xA2
+		{"RES 4, E", 2, cpu.RES_4_E},     // 0xA3
+		{"RES 4, H", 2, cpu.RES_4_H},     // 0xA4
+		{"RES 4, L", 2, cpu.RES_4_L},     // 0xA5
+		{"RES 4, (HL)", 2, cpu.RES_4_HL}, // 0xA6
+		{"RES 4, A", 2, cpu.RES_4_A},     // 0xA7
+		{"RES 5, B", 2, cpu.RES_5_B},     // 0xA8
+		{"RES 5, C", 2, cpu.RES_5_C},     // 0xA9
+		{"RES 5, D", 2, cpu.RES_5_D},     // 0xAA
+		{"RES 5, E", 2, cpu.RES_5_E},     // 0xAB
+		{"RES 5, H", 2, cpu.RES_5_H},     // 0xAC
+		{"RES 5, L", 2, cpu.RES_5_L},     // 0xAD
+		{"RES 5, (HL)", 2, cpu.RES_5_HL}, // 0xAE
+		{"RES 5, A", 2, cpu.RES_5_A},     // 0xAF
+		{"RES 6, B", 2, cpu.RES_6_B},     // 0xB0
+		{"RES 6, C", 2, cpu.RES_6_C},     // 0xB1
+		{"RES 6, D", 2, cpu.RES_6_D},     // 0xB2
+		{"RES 6, E", 2, cpu.RES_6_E},     // 0xB3
+		{"RES 6, H", 2, cpu.RES_6_H},     // 0xB4
+		{"RES 6, L", 2, cpu.RES_6_L},     // 0xB5
+		{"RES 6, (HL)", 2, cpu.RES_6_HL}, // 0xB6
+		{"RES 6, A", 2, cpu.RES_6_A},     // 0xB7
+		{"RES 7, B", 2, cpu.RES_7_B},     // 0xB8
+		{"RES 7, C", 2, cpu.RES_7_C},     // 0xB9
+		{"RES 7, D", 2, cpu.RES_7_D},     // 0xBA
+		{"RES 7, E", 2, cpu.RES_7_E},     // 0xBB
+		{"RES 7, H", 2, cpu.RES_7_H},     // 0xBC
+		{"RES 7, L", 2, cpu.RES_7_L},     // 0xBD
+		{"RES 7, (HL)", 2, cpu.RES_7_HL}, // 0xBE
+		{"RES 7, A", 2, cpu.RES_7_A},     // 0xBF
+		{"SET 0, B", 2, cpu.SET_0_B},     // 0xC0
+		{"SET 0, C", 2, cpu.SET_0_C},     // 0xC1
+		{"SET 0, D", 2, cpu.SET_0_D},     // 0xC2
+		{"SET 0, E", 2, cpu.SET_0_E},     // 0xC3
+		{"SET 0, H", 2, cpu.SET_0_H},     // 0xC4
+		{"SET 0, L", 2, cpu.SET_0_L},     // 0xC5
+		{"SET 0, (HL)", 2, cpu.SET_0_HL}, // 0xC6
+		{"SET 0, A", 2, cpu.SET_0_A},     // 0xC7
+		{"SET 1, B", 2, cpu.SET_1_B},     // 0xC8
+		{"SET 1, C", 2, cpu.SET_1_C},     // 0xC9
+		{"SET 1, D", 2, cpu.SET_1_D},     // 0xCA
+		{"SET 1, E", 2, cpu.SET_1_E},     // 0xCB
+		{"SET 1, H", 2, cpu.SET_1_H},     // 0xCC
+		{"SET 1, L", 2, cpu.SET_1_L},     // 0xCD
+		{"SET 1, (HL)", 2, cpu.SET_1_HL}, // 0xCE
+		{"SET 1, A", 2, cpu.SET_1_A},     // 0xCF
+		{"SET 2, B", 2, cpu.SET_2_B},     // 0xD0
+		{"SET 2, C", 2, cpu.SET_2_C},     // 0xD1
+		{"SET 2, D", 2, cpu.SET_2_D},     // 0xD2
+		{"SET 2, E", 2, cpu.SET_2_E},     // 0xD3
+		{"SET 2, H", 2, cpu.SET_2_H},     // 0xD4
+		{"SET 2, L", 2, cpu.SET_2_L},     // 0xD5
+		{"SET 2, (HL)", 2, cpu.SET_2_HL}, // 0xD6
+		{"SET 2, A", 2, cpu.SET_2_A},     // 0xD7
+		{"SET 3, B", 2, cpu.SET_3_B},     // 0xD8
+		{"SET 3, C", 2, cpu.SET_3_C},     // 0xD9
+		{"SET 3, D", 2, cpu.SET_3_D},     // 0xDA
+		{"SET 3, E", 2, cpu.SET_3_E},     // 0xDB
+		{"SET 3, H", 2, cpu.SET_3_H},     // 0xDC
+		{"SET 3, L", 2, cpu.SET_3_L},     // 0xDD
+		{"SET 3, (HL)", 2, cpu.SET_3_HL}, // 0xDE
+		{"SET 3, A", 2, cpu.SET_3_A},     // 0xDF
+		{"SET 4, B", 2, cpu.SET_4_B},     // 0xE0
+		{"SET 4, C", 2, cpu.SET_4_C},     // 0xE1
+		{"SET 4, D", 2, cpu.SET_4_D},     // 0xE2
+		{"SET 4, E", 2, cpu.SET_4_E},     // 0xE3
+		{"SET 4, H", 2, cpu.SET_4_H},     // 0xE4
+		{"SET 4, L", 2, cpu.SET_4_L},     // 0xE5
+		{"SET 4, (HL)", 2, cpu.SET_4_HL}, // 0xE6
+		{"SET 4, A", 2, cpu.SET_4_A},     // 0xE7
+		{"SET 5, B", 2, cpu.SET_5_B},     // 0xE8
+		{"SET 5, C", 2, cpu.SET_5_C},     // 0xE9
+		{"SET 5, D", 2, cpu.SET_5_D},     // 0xEA
+		{"SET 5, E", 2, cpu.SET_5_E},     // 0xEB
+		{"SET 5, H", 2, cpu.SET_5_H},     // 0xEC
+		{"SET 5, L", 2, cpu.SET_5_L},     // 0xED
+		{"SET 5, (HL)", 2, cpu.SET_5_HL}, // 0xEE
+		{"SET 5, A", 2, cpu.SET_5_A},     // 0xEF
+		{"SET 6, B", 2, cpu.SET_6_B},     // 0xF0
+		{"SET 6, C", 2, cpu.SET_6_C},     // 0xF1
+		{"SET 6, D", 2, cpu.SET_6_D},     // 0xF2
+		{"SET 6, E", 2, cpu.SET_6_E},     // 0xF3
+		{"SET 6, H", 2, cpu.SET_6_H},     // 0xF4
+		{"SET 6, L", 2, cpu.SET_6_L},     // 0xF5
+		{"SET 6, (HL)", 2, cpu.SET_6_HL}, // 0xF6
+		{"SET 6, A", 2, cpu.SET_6_A},     // 0xF7
+		{"SET 7, B", 2, cpu.SET_7_B},     // 0xF8
+		{"SET 7, C", 2, cpu.SET_7_C},     // 0xF9
+		{"SET 7, D", 2, cpu.SET_7_D},     // 0xFA
+		{"SET 7, E", 2, cpu.SET_7_E},     // 0xFB
+		{"SET 7, H", 2, cpu.SET_7_H},     // 0xFC
+		{"SET 7, L", 2, cpu.SET_7_L},     // 0xFD
+		{"SET 7, (HL)", 2, cpu.SET_7_HL}, // 0xFE
+		{"SET 7, A", 2, cpu.SET_7_A},     // 0xFF
+	}
+}
+
+func (cpu *CPU) CreateCBTicks() {
+	// ticks are in clock cycles and include the fetch of the 0xCB prefix
+	cpu.cbTicksTable = [256]uint8{
+		8, 8, 8, 8, 8, 8, 16, 8, 8, 8, 8, 8, 8, 8, 16, 8, // 0x0_
+		8, 8, 8, 8, 8, 8, 16, 8, 8, 8, 8, 8, 8, 8, 16, 8, // 0x1_
+		8, 8, 8, 8, 8, 8, 16, 8, 8, 8, 8, 8, 8, 8, 16, 8, // 0x2_
+		8, 8, 8, 8, 8, 8, 16, 8, 8, 8, 8, 8, 8, 8, 16, 8, // 0x3_
+		8, 8, 8, 8, 8, 8, 12, 8, 8, 8, 8, 8, 8, 8, 12, 8, // 0x4_
+		8, 8, 8, 8, 8, 8, 12, 8, 8, 8, 8, 8, 8, 8, 12, 8, // 0x5_
+		8, 8, 8, 8, 8, 8, 12, 8, 8, 8, 8, 8, 8, 8, 12, 8, // 0x6_
+		8, 8, 8, 8, 8, 8, 12, 8, 8, 8, 8, 8, 8, 8, 12, 8, // 0x7_
+		8, 8, 8, 8, 8, 8, 16, 8, 8, 8, 8, 8, 8, 8, 16, 8, // 0x8_
+		8, 8, 8, 8, 8, 8, 16, 8, 8, 8, 8, 8, 8, 8, 16, 8, // 0x9_
+		8, 8, 8, 8, 8, 8, 16, 8, 8, 8, 8, 8, 8, 8, 16, 8, // 0xa_
+		8, 8, 8, 8, 8, 8, 16, 8, 8, 8, 8, 8, 8, 8, 16, 8, // 0xb_
+		8, 8, 8, 8, 8, 8, 16, 8, 8, 8, 8, 8, 8, 8, 16, 8, // 0xc_
+		8, 8, 8, 8, 8, 8, 16, 8, 8, 8, 8, 8, 8, 8, 16, 8, // 0xd_
+		8, 8, 8, 8, 8, 8, 16, 8, 8, 8, 8, 8, 8, 8, 16, 8, // 0xe_
+		8, 8, 8, 8, 8, 8, 16, 8, 8, 8, 8, 8, 8, 8, 16, 8, // 0xf_
+	}
+}
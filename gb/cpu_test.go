@@ -0,0 +1,787 @@
+package gb
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// testROM returns a 32KB ROM titled TEST with the cartridge type and RAM size code in its header
+func testROM(cartType uint8, ramSize uint8) []uint8 {
+	rom := make([]uint8, 0x8000)
+	copy(rom[0x134:], "TEST")
+	rom[0x147] = cartType
+	rom[0x149] = ramSize
+	return rom
+}
+
+// newROMConsole creates a console of the model running rom, without a boot ROM.
+// The header checksum is filled in, so the header can be changed before
+func newROMConsole(t *testing.T, rom []uint8, model Model) *Console {
+	t.Helper()
+	return newConsole(loadTestCart(t, rom), nil, model, RENDERER_SCANLINE)
+}
+
+// loadTestCart fills in the header checksum and loads the ROM
+func loadTestCart(t *testing.T, rom []uint8) *Cartridge {
+	t.Helper()
+
+	rom[0x14D] = headerChecksum(rom)
+	cart, err := LoadCartridge(bytes.NewReader(rom))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cart
+}
+
+// newTestConsole creates a DMG with a 32KB ROM only cartridge, without a boot ROM
+func newTestConsole(t *testing.T) *Console {
+	t.Helper()
+	return newROMConsole(t, testROM(0x00, 0x00), MODEL_DMG)
+}
+
+const testPC = 0xC100 // code runs from WRAM so it can be written
+
+// runAt writes code at testPC and runs one instruction from there
+func runAt(c *Console, code ...uint8) {
+	for i, value := range code {
+		c.mem.Write8(testPC+uint16(i), value)
+	}
+	c.cpu.regs.pc = testPC
+	c.cpu.Step()
+}
+
+// opcodes that set PC themselves, checked by TestControlFlow
+var controlOpcodes = map[uint8]bool{
+	0x18: true, 0x20: true, 0x28: true, 0x30: true, 0x38: true, // JR
+	0xC2: true, 0xC3: true, 0xCA: true, 0xD2: true, 0xDA: true, 0xE9: true, // JP
+	0xC4: true, 0xCC: true, 0xCD: true, 0xD4: true, 0xDC: true, // CALL
+	0xC0: true, 0xC8: true, 0xC9: true, 0xD0: true, 0xD8: true, 0xD9: true, // RET
+	0xC7: true, 0xCF: true, 0xD7: true, 0xDF: true, 0xE7: true, 0xEF: true, 0xF7: true, 0xFF: true, // RST
+}
+
+var undefinedOpcodes = []uint8{0xD3, 0xDB, 0xDD, 0xE3, 0xE4, 0xEB, 0xEC, 0xED, 0xF4, 0xFC, 0xFD}
+
+func TestInstructionLength(t *testing.T) {
+	undefined := map[uint8]bool{}
+	for _, opcode := range undefinedOpcodes {
+		undefined[opcode] = true
+	}
+
+	for op := 0; op < 0x100; op++ {
+		opcode := uint8(op)
+		if controlOpcodes[opcode] || undefined[opcode] {
+			continue
+		}
+
+		c := newTestConsole(t)
+		r := &c.cpu.regs
+		r.sp = 0xDFF0
+		r.SetBC(0xC810)
+		r.SetDE(0xC820)
+		r.SetHL(0xC800)
+
+		// operands 0x80 0xD2 keep memory writes in HRAM (LDH) and WRAM (a16)
+		runAt(c, opcode, 0x80, 0xD2)
+
+		length := c.cpu.table[opcode].instuctionLength
+		if want := testPC + uint16(length); r.pc != want {
+			t.Errorf("%02X %s: PC = 0x%04X, want 0x%04X", opcode, c.cpu.table[opcode].name, r.pc, want)
+		}
+	}
+}
+
+func TestCBInstructionLength(t *testing.T) {
+	for op := 0; op < 0x100; op++ {
+		c := newTestConsole(t)
+		c.cpu.regs.SetHL(0xC800)
+
+		runAt(c, 0xCB, uint8(op))
+
+		if want := uint16(testPC + 2); c.cpu.regs.pc != want {
+			t.Errorf("CB %02X %s: PC = 0x%04X, want 0x%04X", op, c.cpu.cbTable[op].name, c.cpu.regs.pc, want)
+		}
+	}
+}
+
+func TestUndefinedOpcodes(t *testing.T) {
+	for _, opcode := range undefinedOpcodes {
+		c := newTestConsole(t)
+		runAt(c, opcode)
+		c.cpu.Step()
+
+		err, ok := c.Locked().(*LockupError)
+		if !ok || err.Address != testPC || err.Opcode != opcode {
+			t.Errorf("%02X: Locked() = %v, want a lockup at 0x%04X", opcode, c.Locked(), testPC)
+		}
+	}
+}
+
+// flags making each condition true and false
+var conditions = []struct {
+	name            string
+	taken, notTaken uint8
+}{
+	{"NZ", 0x00, 0x80},
+	{"Z", 0x80, 0x00},
+	{"NC", 0x00, 0x10},
+	{"C", 0x10, 0x00},
+}
+
+func TestControlFlow(t *testing.T) {
+	type test struct {
+		name   string
+		code   []uint8
+		flags  uint8
+		pc     uint16 // expected PC
+		pushed bool   // return address 0xC1xx pushed
+	}
+
+	const target = 0xD234
+	const returnAddress = 0xD456
+
+	tests := []test{
+		{"JP nn", []uint8{0xC3, 0x34, 0xD2}, 0, target, false},
+		{"JP (HL)", []uint8{0xE9}, 0, 0xC800, false},
+		{"JR +5", []uint8{0x18, 0x05}, 0, testPC + 2 + 5, false},
+		{"JR -2", []uint8{0x18, 0xFE}, 0, testPC, false},
+		{"JR -128", []uint8{0x18, 0x80}, 0, testPC + 2 - 128, false},
+		{"CALL nn", []uint8{0xCD, 0x34, 0xD2}, 0, target, true},
+		{"RET", []uint8{0xC9}, 0, returnAddress, false},
+		{"RETI", []uint8{0xD9}, 0, returnAddress, false},
+	}
+
+	for i, cond := range conditions {
+		jp := []uint8{0xC2, 0xCA, 0xD2, 0xDA}[i]
+		jr := []uint8{0x20, 0x28, 0x30, 0x38}[i]
+		call := []uint8{0xC4, 0xCC, 0xD4, 0xDC}[i]
+		ret := []uint8{0xC0, 0xC8, 0xD0, 0xD8}[i]
+
+		tests = append(tests,
+			test{"JP " + cond.name + " taken", []uint8{jp, 0x34, 0xD2}, cond.taken, target, false},
+			test{"JP " + cond.name + " not taken", []uint8{jp, 0x34, 0xD2}, cond.notTaken, testPC + 3, false},
+			test{"JR " + cond.name + " taken", []uint8{jr, 0x10}, cond.taken, testPC + 2 + 0x10, false},
+			test{"JR " + cond.name + " not taken", []uint8{jr, 0x10}, cond.notTaken, testPC + 2, false},
+			test{"CALL " + cond.name + " taken", []uint8{call, 0x34, 0xD2}, cond.taken, target, true},
+			test{"CALL " + cond.name + " not taken", []uint8{call, 0x34, 0xD2}, cond.notTaken, testPC + 3, false},
+			test{"RET " + cond.name + " taken", []uint8{ret}, cond.taken, returnAddress, false},
+			test{"RET " + cond.name + " not taken", []uint8{ret}, cond.notTaken, testPC + 1, false},
+		)
+	}
+
+	for vector := uint16(0); vector <= 0x38; vector += 8 {
+		tests = append(tests, test{"RST", []uint8{0xC7 | uint8(vector)}, 0, vector, true})
+	}
+
+	for _, tt := range tests {
+		c := newTestConsole(t)
+		r := &c.cpu.regs
+		r.f = tt.flags
+		r.SetHL(0xC800)
+
+		// a return address on the stack for RET
+		r.sp = 0xDFF0
+		c.mem.PushStack16(returnAddress, &r.sp)
+
+		runAt(c, tt.code...)
+
+		if r.pc != tt.pc {
+			t.Errorf("%s: PC = 0x%04X, want 0x%04X", tt.name, r.pc, tt.pc)
+		}
+
+		if tt.pushed {
+			want := testPC + uint16(c.cpu.table[tt.code[0]].instuctionLength)
+			if r.sp != 0xDFEC || c.mem.Read16(r.sp) != want {
+				t.Errorf("%s: pushed 0x%04X at SP 0x%04X, want 0x%04X at 0xDFEC", tt.name, c.mem.Read16(r.sp), r.sp, want)
+			}
+		}
+	}
+}
+
+// <----------------------------- ALU FLAGS -----------------------------> //
+
+const (
+	flagZ = 0x80
+	flagN = 0x40
+	flagH = 0x20
+	flagC = 0x10
+)
+
+func flags(z, n, h, c bool) uint8 {
+	var f uint8
+	if z {
+		f |= flagZ
+	}
+	if n {
+		f |= flagN
+	}
+	if h {
+		f |= flagH
+	}
+	if c {
+		f |= flagC
+	}
+	return f
+}
+
+// reference results for the 8-bit ALU, half carry and carry come from the carries into bits 4 and 8
+func refAdd(a, b, carry int) (uint8, uint8) {
+	r := a + b + carry
+	return uint8(r), flags(r&0xFF == 0, false, (a^b^r)&0x10 != 0, r&0x100 != 0)
+}
+
+func refSub(a, b, carry int) (uint8, uint8) {
+	r := a - b - carry
+	return uint8(r), flags(r&0xFF == 0, true, (a^b^r)&0x10 != 0, r < 0)
+}
+
+func TestALU8(t *testing.T) {
+	c := newTestConsole(t)
+	cpu := c.cpu
+	r := &cpu.regs
+
+	type op struct {
+		name string
+		run  func(value uint8)
+		ref  func(a, b, carry int) (uint8, uint8)
+	}
+
+	ops := []op{
+		{"ADD", func(v uint8) { cpu.ADD(&r.a, v) }, func(a, b, _ int) (uint8, uint8) { return refAdd(a, b, 0) }},
+		{"ADC", cpu.ADC, refAdd},
+		{"SUB", cpu.SUB, func(a, b, _ int) (uint8, uint8) { return refSub(a, b, 0) }},
+		{"SBC", cpu.SBC, refSub},
+		{"CP", cpu.CP, func(a, b, _ int) (uint8, uint8) {
+			_, f := refSub(a, b, 0)
+			return uint8(a), f
+		}},
+		{"AND", cpu.AND, func(a, b, _ int) (uint8, uint8) { return uint8(a & b), flags(a&b == 0, false, true, false) }},
+		{"OR", cpu.OR, func(a, b, _ int) (uint8, uint8) { return uint8(a | b), flags(a|b == 0, false, false, false) }},
+		{"XOR", cpu.XOR, func(a, b, _ int) (uint8, uint8) { return uint8(a ^ b), flags(a^b == 0, false, false, false) }},
+	}
+
+	for _, o := range ops {
+		for a := 0; a < 0x100; a++ {
+			for b := 0; b < 0x100; b++ {
+				for carry := 0; carry < 2; carry++ {
+					r.a = uint8(a)
+					r.f = flags(false, false, false, carry == 1)
+					o.run(uint8(b))
+
+					result, f := o.ref(a, b, carry)
+					if r.a != result || r.f != f {
+						t.Fatalf("%s 0x%02X, 0x%02X carry %d: A = 0x%02X F = 0x%02X, want A = 0x%02X F = 0x%02X",
+							o.name, a, b, carry, r.a, r.f, result, f)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestIncDec(t *testing.T) {
+	c := newTestConsole(t)
+	cpu := c.cpu
+	r := &cpu.regs
+
+	// INC and DEC leave the carry alone
+	for value := 0; value < 0x100; value++ {
+		for carry := 0; carry < 2; carry++ {
+			r.f = flags(false, false, false, carry == 1)
+			result := cpu.INC(uint8(value))
+			want, f := refAdd(value, 1, 0)
+			f = f&^flagC | flags(false, false, false, carry == 1)
+			if result != want || r.f != f {
+				t.Fatalf("INC 0x%02X carry %d: 0x%02X F = 0x%02X, want 0x%02X F = 0x%02X", value, carry, result, r.f, want, f)
+			}
+
+			r.f = flags(false, false, false, carry == 1)
+			result = cpu.DEC(uint8(value))
+			want, f = refSub(value, 1, 0)
+			f = f&^flagC | flags(false, false, false, carry == 1)
+			if result != want || r.f != f {
+				t.Fatalf("DEC 0x%02X carry %d: 0x%02X F = 0x%02X, want 0x%02X F = 0x%02X", value, carry, result, r.f, want, f)
+			}
+		}
+	}
+}
+
+// refDAA adjusts the low nibble first, then checks the adjusted value for the high nibble
+func refDAA(a uint8, n, h, c bool) (uint8, uint8) {
+	result := int(a)
+	if n {
+		if h {
+			result = (result - 0x06) & 0xFF
+		}
+		if c {
+			result -= 0x60
+		}
+	} else {
+		if h || result&0x0F > 0x09 {
+			result += 0x06
+		}
+		if c || result > 0x9F {
+			result += 0x60
+		}
+	}
+
+	carry := c || (!n && result&0x100 != 0)
+	return uint8(result), flags(uint8(result) == 0, n, false, carry)
+}
+
+func TestDAA(t *testing.T) {
+	c := newTestConsole(t)
+	r := &c.cpu.regs
+
+	for a := 0; a < 0x100; a++ {
+		for f := 0; f < 0x100; f += 0x10 {
+			r.a = uint8(a)
+			r.f = uint8(f)
+			c.cpu.DAA(nil)
+
+			want, wantF := refDAA(uint8(a), f&flagN != 0, f&flagH != 0, f&flagC != 0)
+			if r.a != want || r.f != wantF {
+				t.Fatalf("DAA A = 0x%02X F = 0x%02X: A = 0x%02X F = 0x%02X, want A = 0x%02X F = 0x%02X", a, f, r.a, r.f, want, wantF)
+			}
+		}
+	}
+}
+
+func TestDAAArithmetic(t *testing.T) {
+	c := newTestConsole(t)
+	cpu := c.cpu
+	r := &cpu.regs
+	bcd := func(n int) uint8 { return uint8(n/10<<4 | n%10) }
+
+	for x := 0; x < 100; x++ {
+		for y := 0; y < 100; y++ {
+			r.a = bcd(x)
+			cpu.ADD(&r.a, bcd(y))
+			cpu.DAA(nil)
+			if r.a != bcd((x+y)%100) || (r.GetCarry() == 1) != (x+y >= 100) {
+				t.Fatalf("%d + %d: A = 0x%02X carry %d", x, y, r.a, r.GetCarry())
+			}
+
+			r.a = bcd(x)
+			cpu.SUB(bcd(y))
+			cpu.DAA(nil)
+			if r.a != bcd((x-y+100)%100) || (r.GetCarry() == 1) != (x < y) {
+				t.Fatalf("%d - %d: A = 0x%02X carry %d", x, y, r.a, r.GetCarry())
+			}
+		}
+	}
+}
+
+var boundaries16 = []uint16{0x0000, 0x0001, 0x000F, 0x0010, 0x00FF, 0x0100, 0x0FFF, 0x1000, 0x7FFF, 0x8000, 0xF000, 0xFF00, 0xFFFF}
+
+// pairs16 returns every pair of boundary values plus random pairs
+func pairs16() [][2]uint16 {
+	var pairs [][2]uint16
+	for _, x := range boundaries16 {
+		for _, y := range boundaries16 {
+			pairs = append(pairs, [2]uint16{x, y})
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		pairs = append(pairs, [2]uint16{uint16(rng.Intn(0x10000)), uint16(rng.Intn(0x10000))})
+	}
+	return pairs
+}
+
+func TestAddHL(t *testing.T) {
+	c := newTestConsole(t)
+	r := &c.cpu.regs
+
+	for _, pair := range pairs16() {
+		hl, value := pair[0], pair[1]
+
+		for _, z := range []uint8{0, flagZ} {
+			r.SetHL(hl)
+			r.SetBC(value)
+			r.f = z | flagN
+
+			runAt(c, 0x09) // ADD HL, BC
+
+			sum := int(hl) + int(value)
+			want := uint16(sum)
+			f := z | flags(false, false, (int(hl)^int(value)^sum)&0x1000 != 0, sum > 0xFFFF)
+			if r.GetHL() != want || r.f != f {
+				t.Fatalf("ADD HL 0x%04X, 0x%04X: HL = 0x%04X F = 0x%02X, want HL = 0x%04X F = 0x%02X", hl, value, r.GetHL(), r.f, want, f)
+			}
+		}
+	}
+}
+
+func TestAddSP(t *testing.T) {
+	c := newTestConsole(t)
+	r := &c.cpu.regs
+
+	for _, pair := range pairs16() {
+		sp, offset := pair[0], uint8(pair[1])
+
+		// H and C come from the unsigned add of the low bytes, Z and N are cleared
+		low := int(sp&0xFF) + int(offset)
+		want := uint16(int(sp) + int(int8(offset)))
+		f := flags(false, false, (int(sp)^int(offset)^low)&0x10 != 0, low > 0xFF)
+
+		for _, opcode := range []uint8{0xE8, 0xF8} {
+			r.sp = sp
+			r.f = flagZ | flagN
+
+			runAt(c, opcode, offset)
+
+			got := r.sp
+			if opcode == 0xF8 {
+				got = r.GetHL()
+				if r.sp != sp {
+					t.Fatalf("LD HL, SP+e8 changed SP")
+				}
+			}
+			if got != want || r.f != f {
+				t.Fatalf("%s SP = 0x%04X e8 = 0x%02X: 0x%04X F = 0x%02X, want 0x%04X F = 0x%02X",
+					c.cpu.table[opcode].name, sp, offset, got, r.f, want, f)
+			}
+		}
+	}
+}
+
+// <----------------------------- INTERRUPTS -----------------------------> //
+
+// stepCPU runs one instruction and then the interrupt check, like Console.Step without the rest of the console
+func stepCPU(c *Console) {
+	c.cpu.Step()
+	c.cpu.HandleInterrupts()
+}
+
+// loadAt writes code at testPC and points PC at it
+func loadAt(c *Console, code ...uint8) {
+	for i, value := range code {
+		c.mem.Write8(testPC+uint16(i), value)
+	}
+	c.cpu.regs.pc = testPC
+}
+
+// pending sets IE and IF
+func pending(c *Console, ie uint8, iflag uint8) {
+	c.mem.Write8(IE_ADDRESS, ie)
+	c.mem.Write8(IF_ADDRESS, iflag)
+}
+
+func TestHandleInterrupts(t *testing.T) {
+	tests := []struct {
+		name      string
+		ie, iflag uint8
+		ime       bool
+		vector    uint16 // 0 for no dispatch
+		after     uint8  // IF after the check
+	}{
+		{"VBlank", 0x1F, 0x01, true, 0x40, 0x00},
+		{"STAT", 0x1F, 0x02, true, 0x48, 0x00},
+		{"timer", 0x1F, 0x04, true, 0x50, 0x00},
+		{"serial", 0x1F, 0x08, true, 0x58, 0x00},
+		{"joypad", 0x1F, 0x10, true, 0x60, 0x00},
+		{"VBlank first", 0x1F, 0x1F, true, 0x40, 0x1E},
+		{"timer before serial", 0x1F, 0x0C, true, 0x50, 0x08},
+		{"STAT before joypad", 0x1F, 0x12, true, 0x48, 0x10},
+		{"IE masks priority", 0x04, 0x05, true, 0x50, 0x01},
+		{"not in IE", 0x1E, 0x01, true, 0, 0x01},
+		{"IME off", 0x1F, 0x01, false, 0, 0x01},
+		{"nothing pending", 0x1F, 0x00, true, 0, 0x00},
+	}
+
+	const pc = 0xC123
+
+	for _, tt := range tests {
+		c := newTestConsole(t)
+		r := &c.cpu.regs
+		r.pc = pc
+		r.sp = 0xDFF0
+		c.cpu.ime = tt.ime
+		pending(c, tt.ie, tt.iflag)
+		start := c.cpu.ticks
+
+		c.cpu.HandleInterrupts()
+
+		if iflag := c.mem.Read8(IF_ADDRESS) & 0x1F; iflag != tt.after {
+			t.Errorf("%s: IF = 0x%02X, want 0x%02X", tt.name, iflag, tt.after)
+		}
+
+		if tt.vector == 0 {
+			if r.pc != pc || r.sp != 0xDFF0 || c.cpu.ticks != start || c.cpu.ime != tt.ime {
+				t.Errorf("%s: dispatched, PC = 0x%04X SP = 0x%04X", tt.name, r.pc, r.sp)
+			}
+			continue
+		}
+
+		if r.pc != tt.vector {
+			t.Errorf("%s: PC = 0x%04X, want 0x%04X", tt.name, r.pc, tt.vector)
+		}
+		if r.sp != 0xDFEE || c.mem.Read16(r.sp) != pc {
+			t.Errorf("%s: pushed 0x%04X at SP 0x%04X, want 0x%04X at 0xDFEE", tt.name, c.mem.Read16(r.sp), r.sp, pc)
+		}
+		if c.cpu.ime {
+			t.Errorf("%s: IME still set", tt.name)
+		}
+		if ticks := c.cpu.ticks - start; ticks != 20 {
+			t.Errorf("%s: dispatch took %d ticks, want 20", tt.name, ticks)
+		}
+	}
+}
+
+func TestEIDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     []uint8
+		steps    int
+		returnTo uint16 // address pushed by the dispatch, 0 for none
+	}{
+		{"EI", []uint8{0xFB, 0x00, 0x00}, 1, 0},
+		{"EI NOP", []uint8{0xFB, 0x00, 0x00}, 2, testPC + 2},
+		{"EI EI", []uint8{0xFB, 0xFB, 0x00}, 2, testPC + 2},
+		{"EI DI", []uint8{0xFB, 0xF3, 0x00}, 3, 0},
+		{"DI", []uint8{0xF3, 0x00, 0x00}, 3, 0},
+	}
+
+	for _, tt := range tests {
+		c := newTestConsole(t)
+		r := &c.cpu.regs
+		r.sp = 0xDFF0
+		pending(c, 0x01, 0x01)
+		loadAt(c, tt.code...)
+
+		for i := 0; i < tt.steps; i++ {
+			stepCPU(c)
+		}
+
+		if tt.returnTo == 0 {
+			if r.pc != testPC+uint16(tt.steps) {
+				t.Errorf("%s: PC = 0x%04X, want 0x%04X", tt.name, r.pc, testPC+uint16(tt.steps))
+			}
+			continue
+		}
+		if r.pc != 0x40 || c.mem.Read16(r.sp) != tt.returnTo {
+			t.Errorf("%s: PC = 0x%04X returning to 0x%04X, want 0x0040 returning to 0x%04X", tt.name, r.pc, c.mem.Read16(r.sp), tt.returnTo)
+		}
+	}
+}
+
+func TestRETI(t *testing.T) {
+	tests := []struct {
+		name   string
+		opcode uint8
+		ime    bool // IME right after the return
+	}{
+		{"RETI", 0xD9, true},
+		{"RET", 0xC9, false},
+	}
+
+	const returnAddress = 0xD456
+
+	for _, tt := range tests {
+		c := newTestConsole(t)
+		r := &c.cpu.regs
+		r.sp = 0xDFF0
+		c.mem.PushStack16(returnAddress, &r.sp)
+		pending(c, 0x04, 0x04)
+		loadAt(c, tt.opcode)
+
+		c.cpu.Step()
+		if r.pc != returnAddress || c.cpu.ime != tt.ime {
+			t.Errorf("%s: PC = 0x%04X IME = %v, want 0x%04X IME = %v", tt.name, r.pc, c.cpu.ime, returnAddress, tt.ime)
+		}
+
+		// RETI enables interrupts without EI's delay, the pending one is taken straight away
+		c.cpu.HandleInterrupts()
+		if tt.ime && (r.pc != 0x50 || c.mem.Read16(r.sp) != returnAddress) {
+			t.Errorf("%s: PC = 0x%04X returning to 0x%04X, want 0x0050 returning to 0x%04X", tt.name, r.pc, c.mem.Read16(r.sp), returnAddress)
+		}
+	}
+}
+
+func TestHaltWake(t *testing.T) {
+	tests := []struct {
+		name string
+		ime  bool
+		ie   uint8
+		pc   uint16 // PC after the wake up
+		wake bool
+	}{
+		{"IME on", true, 0x01, 0x40, true},
+		{"IME off", false, 0x01, testPC + 1, true},
+		{"not in IE", true, 0x00, testPC + 1, false},
+	}
+
+	for _, tt := range tests {
+		c := newTestConsole(t)
+		r := &c.cpu.regs
+		r.sp = 0xDFF0
+		c.cpu.ime = tt.ime
+		pending(c, tt.ie, 0x00)
+		loadAt(c, 0x76, 0x00)
+
+		// halted, the clock keeps running without executing
+		stepCPU(c)
+		for i := 0; i < 3; i++ {
+			start := c.cpu.ticks
+			stepCPU(c)
+			if !c.cpu.halted || r.pc != testPC+1 || c.cpu.ticks-start != 4 {
+				t.Fatalf("%s: halted = %v PC = 0x%04X after %d ticks, want halted at 0x%04X after 4", tt.name, c.cpu.halted, r.pc, c.cpu.ticks-start, testPC+1)
+			}
+		}
+
+		c.mem.RequestInterrupt(INT_VBLANK)
+		stepCPU(c)
+
+		if c.cpu.halted == tt.wake || r.pc != tt.pc {
+			t.Errorf("%s: halted = %v PC = 0x%04X, want halted = %v PC = 0x%04X", tt.name, c.cpu.halted, r.pc, !tt.wake, tt.pc)
+		}
+		if tt.ime && tt.wake && c.mem.Read16(r.sp) != testPC+1 {
+			t.Errorf("%s: returning to 0x%04X, want 0x%04X", tt.name, c.mem.Read16(r.sp), testPC+1)
+		}
+	}
+}
+
+// <----------------------------- HALT, STOP AND SPEED SWITCH -----------------------------> //
+
+func TestHaltBug(t *testing.T) {
+	tests := []struct {
+		name   string
+		ie     uint8 // IF is 0x01, with IME off
+		code   []uint8
+		steps  int
+		a      uint8
+		pc     uint16
+		halted bool
+	}{
+		{"INC A read twice", 0x01, []uint8{0x76, 0x3C, 0x00}, 3, 2, testPC + 2, false},
+		{"opcode read as its own operand", 0x01, []uint8{0x76, 0x3E, 0x14}, 2, 0x3E, testPC + 2, false},
+		{"no interrupt pending", 0x00, []uint8{0x76, 0x3C, 0x00}, 3, 0, testPC + 1, true},
+	}
+
+	for _, tt := range tests {
+		c := newTestConsole(t)
+		c.cpu.regs.a = 0
+		pending(c, tt.ie, 0x01)
+		loadAt(c, tt.code...)
+
+		for i := 0; i < tt.steps; i++ {
+			c.cpu.Step()
+		}
+
+		r := &c.cpu.regs
+		if r.a != tt.a || r.pc != tt.pc || c.cpu.halted != tt.halted {
+			t.Errorf("%s: A = 0x%02X PC = 0x%04X halted = %v, want A = 0x%02X PC = 0x%04X halted = %v",
+				tt.name, r.a, r.pc, c.cpu.halted, tt.a, tt.pc, tt.halted)
+		}
+	}
+}
+
+func TestSTOPWake(t *testing.T) {
+	tests := []struct {
+		name        string
+		selected    uint8    // JOYP before STOP
+		held        []Button // pressed before STOP
+		press       Button
+		selectAfter uint8 // JOYP written after the press, 0 for none
+		wake        bool
+	}{
+		{"selected button", 0x10, nil, BUTTON_A, 0, true},
+		{"selected direction", 0x20, nil, BUTTON_DOWN, 0, true},
+		{"unselected group", 0x20, nil, BUTTON_A, 0, false},
+		{"nothing selected", 0x30, nil, BUTTON_START, 0, false},
+		{"pressed group selected", 0x30, nil, BUTTON_A, 0x10, true},
+		{"held button", 0x10, []Button{BUTTON_A}, BUTTON_A, 0, false},
+		{"other button on a held line", 0x00, []Button{BUTTON_A}, BUTTON_RIGHT, 0, false},
+		{"other line", 0x10, []Button{BUTTON_A}, BUTTON_B, 0, true},
+	}
+
+	for _, tt := range tests {
+		c := newTestConsole(t)
+		c.mem.Write8(JOYP_ADDRESS, tt.selected)
+		for _, button := range tt.held {
+			c.Press(button)
+		}
+		c.mem.ClearInterrupt(INT_JOYPAD)
+
+		runAt(c, 0x10, 0x00)
+		if !c.cpu.stopped {
+			t.Fatalf("%s: STOP didn't stop the CPU", tt.name)
+		}
+
+		// stopped, nothing runs
+		start := c.cpu.ticks
+		if ticks := c.Step(); ticks != 4 || c.cpu.ticks != start || c.cpu.regs.pc != testPC+2 {
+			t.Errorf("%s: stopped CPU ran, PC = 0x%04X", tt.name, c.cpu.regs.pc)
+		}
+
+		c.Press(tt.press)
+		if tt.selectAfter != 0 {
+			c.mem.Write8(JOYP_ADDRESS, tt.selectAfter)
+		}
+
+		if c.cpu.stopped == tt.wake {
+			t.Errorf("%s: stopped = %v, want %v", tt.name, c.cpu.stopped, !tt.wake)
+		}
+		if requested := c.mem.Read8(IF_ADDRESS)&(1<<INT_JOYPAD) != 0; requested != tt.wake {
+			t.Errorf("%s: joypad interrupt = %v, want %v", tt.name, requested, tt.wake)
+		}
+	}
+}
+
+func TestSpeedSwitch(t *testing.T) {
+	tests := []struct {
+		name    string
+		model   Model
+		cgb     bool    // CGB game, for CGB mode
+		key1    []uint8 // KEY1 writes, each followed by STOP
+		double  bool
+		stopped bool
+		read    uint8 // KEY1 at the end
+	}{
+		{"switch", MODEL_CGB, true, []uint8{0x01}, true, false, 0xFE},
+		{"switch back", MODEL_CGB, true, []uint8{0x01, 0x01}, false, false, 0x7E},
+		{"not armed", MODEL_CGB, true, []uint8{0x00}, false, true, 0x7E},
+		{"only bit 0 writable", MODEL_CGB, true, []uint8{0x80}, false, true, 0x7E},
+		{"DMG mode", MODEL_CGB, false, []uint8{0x01}, false, true, 0xFF},
+		{"DMG", MODEL_DMG, false, []uint8{0x01}, false, true, 0xFF},
+	}
+
+	for _, tt := range tests {
+		rom := testROM(0x00, 0x00)
+		if tt.cgb {
+			rom[0x143] = 0x80
+		}
+		c := newROMConsole(t, rom, tt.model)
+
+		for _, value := range tt.key1 {
+			c.mem.Write8(KEY1_ADDRESS, value)
+			runAt(c, 0x10, 0x00)
+		}
+
+		if c.cpu.doubleSpeed != tt.double || c.cpu.stopped != tt.stopped {
+			t.Errorf("%s: double speed = %v stopped = %v, want %v %v", tt.name, c.cpu.doubleSpeed, c.cpu.stopped, tt.double, tt.stopped)
+		}
+		if key1 := c.mem.Read8(KEY1_ADDRESS); key1 != tt.read {
+			t.Errorf("%s: KEY1 = 0x%02X, want 0x%02X", tt.name, key1, tt.read)
+		}
+	}
+}
+
+func TestDoubleSpeedTiming(t *testing.T) {
+	rom := testROM(0x00, 0x00)
+	rom[0x143] = 0x80
+	c := newROMConsole(t, rom, MODEL_CGB)
+	c.mem.Write8(KEY1_ADDRESS, 0x01)
+	runAt(c, 0x10, 0x00, 0x00)
+
+	// a NOP takes 4 CPU cycles, which is only 2 dots for the PPU in double speed
+	dots := c.ppu.dots
+	if ticks := c.Step(); ticks != 4 {
+		t.Errorf("NOP took %d ticks, want 4", ticks)
+	}
+	if c.ppu.dots-dots != 2 {
+		t.Errorf("PPU ran %d dots, want 2", c.ppu.dots-dots)
+	}
+}
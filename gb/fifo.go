@@ -0,0 +1,279 @@
+package gb
+
+/*
+
+Pixel FIFO renderer
+
+Draws mode 3 dot by dot the way the hardware does, so writes to SCX, the palettes and LCDC in the middle of a line
+show up where they happen and mode 3 takes as long as it does on hardware.
+
+	Fetcher   Fetches a row of 8 background (or window) pixels, 2 dots each for the tile number, the low byte
+	          and the high byte, then pushes them into the background FIFO once it's empty
+	BG FIFO   Shifts out one pixel per dot, mixed with the sprite FIFO and drawn
+	OBJ FIFO  Holds the sprite pixels lined up with the background pixels, filled when a sprite is reached
+
+Mode 3 takes at least 172 dots: the first fetch of the line is thrown away (6 dots), the second arrives
+6 dots later and 160 pixels are shifted out. It gets longer by
+
+	SCX % 8   Pixels shifted out and thrown away at the start of the line, for the fine scroll
+	Window    6 dots, the FIFO is cleared and the fetcher starts over on the window
+	Sprites   6 dots for each sprite fetch, plus waiting for the background fetch in progress to finish
+
+*/
+
+const FETCHER_PUSH = 6 // fetcher step waiting for the FIFO to be empty
+
+type pixelFIFO struct {
+	pixels [8]pixel
+	head   uint8
+	length uint8
+}
+
+func (f *pixelFIFO) push(p pixel) {
+	f.pixels[(f.head+f.length)%8] = p
+	f.length++
+}
+
+func (f *pixelFIFO) pop() pixel {
+	p := f.pixels[f.head]
+	f.head = (f.head + 1) % 8
+	f.length--
+	return p
+}
+
+// at returns the pixel i places from the front
+func (f *pixelFIFO) at(i uint8) *pixel {
+	return &f.pixels[(f.head+i)%8]
+}
+
+func (f *pixelFIFO) clear() {
+	f.head = 0
+	f.length = 0
+}
+
+type fetcher struct {
+	step   uint8 // 0 - 1 tile number, 2 - 3 low byte, 4 - 5 high byte, 6 push
+	x      uint8 // tile column, counted from the start of the line or the window
+	window bool  // fetching the window instead of the background
+	tile   uint8
+	attrs  uint8 // CGB tile attributes
+	row    uint8 // row of the tile
+	low    uint8
+	high   uint8
+}
+
+type fifoRenderer struct {
+	ppu         *PPU
+	bg          pixelFIFO
+	obj         pixelFIFO
+	fetch       fetcher
+	x           uint8  // next pixel to draw
+	discard     uint8  // pixels to shift out without drawing
+	startup     uint8  // dots left of the first, thrown away fetch
+	sprite      int8   // sprite being fetched, -1 for none
+	stall       uint8  // dots left of the sprite fetch
+	fetched     uint16 // sprites fetched this line, one bit for each
+	windowDrawn bool   // the window was started on this line
+}
+
+func (r *fifoRenderer) startLine() {
+	r.bg.clear()
+	r.obj.clear()
+	r.fetch = fetcher{}
+	r.x = 0
+	r.discard = r.ppu.register(SCX_ADDRESS) % 8
+	r.startup = 6
+	r.sprite = -1
+	r.stall = 0
+	r.fetched = 0
+	r.windowDrawn = false
+}
+
+func (r *fifoRenderer) dot() bool {
+	ppu := r.ppu
+	lcdc := ppu.register(LCDC_ADDRESS)
+
+	if r.startup > 0 {
+		r.startup--
+		return false
+	}
+
+	// sprite fetch, waits for the background fetch in progress then takes 6 dots
+	if r.sprite < 0 && lcdc&LCDC_SPRITE_ENABLE != 0 {
+		r.sprite = r.nextSprite()
+		r.stall = 6
+	}
+	if r.sprite >= 0 {
+		if r.fetch.step < FETCHER_PUSH {
+			r.stepFetcher()
+			return false
+		}
+
+		r.stall--
+		if r.stall == 0 {
+			r.loadSprite(ppu.sprites[r.sprite])
+			r.sprite = -1
+		}
+		return false
+	}
+
+	// the window starts at WX - 7
+	if !r.fetch.window && r.windowStarts() {
+		r.bg.clear()
+		r.fetch = fetcher{window: true}
+		r.windowDrawn = true
+
+		r.discard = 0
+		if wx := ppu.register(WX_ADDRESS); wx < 7 {
+			r.discard = 7 - wx
+		}
+	}
+
+	r.stepFetcher()
+
+	if r.bg.length == 0 {
+		return false
+	}
+
+	bg := r.bg.pop()
+	if r.discard > 0 {
+		r.discard--
+		return false
+	}
+
+	var obj pixel
+	if r.obj.length > 0 {
+		obj = r.obj.pop()
+	}
+
+	ppu.drawPixel(r.x, bg, obj)
+	r.x++
+
+	if r.x < SCREEN_WIDTH {
+		return false
+	}
+
+	if r.windowDrawn {
+		ppu.windowLine++
+	}
+	return true
+}
+
+func (r *fifoRenderer) windowStarts() bool {
+	ppu := r.ppu
+	return ppu.windowEnabled() && int(r.x)+7 >= int(ppu.register(WX_ADDRESS))
+}
+
+// nextSprite returns the sprite not yet fetched that starts at the next pixel, or -1. Sprites partially off
+// the left of the screen all start at the first pixel, the one further left is fetched first
+func (r *fifoRenderer) nextSprite() int8 {
+	ppu := r.ppu
+	next := int8(-1)
+	for i := uint8(0); i < ppu.spriteCount; i++ {
+		if r.fetched&(1<<i) != 0 || int(ppu.sprites[i].x)-8 > int(r.x) {
+			continue
+		}
+		if next < 0 || ppu.sprites[i].x < ppu.sprites[next].x {
+			next = int8(i)
+		}
+	}
+
+	if next >= 0 {
+		r.fetched |= 1 << uint8(next)
+	}
+	return next
+}
+
+// loadSprite mixes a sprite's pixels into the sprite FIFO, pixels already there from earlier sprites win,
+// except in CGB mode, where the sprite earlier in OAM wins
+func (r *fifoRenderer) loadSprite(s sprite) {
+	ppu := r.ppu
+	low, high := ppu.spriteRow(s)
+	cgb := ppu.console.cgbMode()
+
+	for r.obj.length < 8 {
+		r.obj.push(pixel{})
+	}
+
+	// sprites partially off the left of the screen lose their first pixels
+	skip := uint8(0)
+	if s.x < 8 {
+		skip = 8 - s.x
+	}
+
+	for i := skip; i < 8; i++ {
+		slot := r.obj.at(i - skip)
+		p := ppu.spritePixel(s, low, high, i)
+		if p.color != 0 && (slot.color == 0 || cgb && p.index < slot.index) {
+			*slot = p
+		}
+	}
+}
+
+// stepFetcher advances the fetcher by a dot, each step reads VRAM on its second dot
+func (r *fifoRenderer) stepFetcher() {
+	ppu := r.ppu
+	f := &r.fetch
+
+	switch f.step {
+	case 1:
+		if f.window {
+			f.tile, f.attrs = ppu.tileMapEntry(ppu.tileMapAddress(true, f.x, ppu.windowLine/8))
+			f.row = ppu.windowLine % 8
+		} else {
+			y := ppu.line + ppu.register(SCY_ADDRESS)
+			column := ppu.register(SCX_ADDRESS)/8 + f.x
+			f.tile, f.attrs = ppu.tileMapEntry(ppu.tileMapAddress(false, column, y/8))
+			f.row = y % 8
+		}
+	case 3:
+		f.low = ppu.mem.vram[ppu.tileRowIndex(f.tile, f.attrs, f.row)]
+	case 5:
+		f.high = ppu.mem.vram[ppu.tileRowIndex(f.tile, f.attrs, f.row)+1]
+	case FETCHER_PUSH:
+		if r.bg.length > 0 {
+			return
+		}
+		for column := uint8(0); column < 8; column++ {
+			r.bg.push(tilePixel(f.low, f.high, f.attrs, column))
+		}
+		f.x++
+		f.step = 0
+		return
+	}
+
+	f.step++
+}
+
+// <----------------------------- SAVE STATES -----------------------------> //
+
+func (f *pixelFIFO) saveState(s *stateWriter) {
+	for _, p := range f.pixels {
+		s.write(p.color, p.palette, p.priority, p.index)
+	}
+	s.write(f.head, f.length)
+}
+
+func (f *pixelFIFO) loadState(s *stateReader) {
+	for i := range f.pixels {
+		p := &f.pixels[i]
+		s.read(&p.color, &p.palette, &p.priority, &p.index)
+	}
+	s.read(&f.head, &f.length)
+}
+
+func (r *fifoRenderer) saveState(s *stateWriter) {
+	r.bg.saveState(s)
+	r.obj.saveState(s)
+	f := &r.fetch
+	s.write(f.step, f.x, f.window, f.tile, f.attrs, f.row, f.low, f.high)
+	s.write(r.x, r.discard, r.startup, r.sprite, r.stall, r.fetched, r.windowDrawn)
+}
+
+func (r *fifoRenderer) loadState(s *stateReader) {
+	r.bg.loadState(s)
+	r.obj.loadState(s)
+	f := &r.fetch
+	s.read(&f.step, &f.x, &f.window, &f.tile, &f.attrs, &f.row, &f.low, &f.high)
+	s.read(&r.x, &r.discard, &r.startup, &r.sprite, &r.stall, &r.fetched, &r.windowDrawn)
+}
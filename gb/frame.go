@@ -0,0 +1,78 @@
+package gb
+
+import (
+	"image"
+	"image/color"
+)
+
+/*
+
+Frames
+
+The PPU draws into one buffer while the last finished frame sits in another, the two are swapped when a frame
+finishes. Frame and Shades copy the finished frame out, so a frontend can call them from its own goroutine
+while the console runs the next frame.
+
+DMG family models only have the 4 shades, drawn with a palette the frontend picks. CGB models have their own
+colors, in DMG mode too, where they come from the palettes the boot ROM picked.
+
+*/
+
+// Palette holds the colors of the 4 DMG shades, from 0 (lightest) to 3 (darkest)
+type Palette [4]color.RGBA
+
+var PALETTE_GRAY = Palette{
+	{0xFF, 0xFF, 0xFF, 0xFF},
+	{0xAA, 0xAA, 0xAA, 0xFF},
+	{0x55, 0x55, 0x55, 0xFF},
+	{0x00, 0x00, 0x00, 0xFF},
+}
+
+// the green of the original DMG screen
+var PALETTE_GREEN = Palette{
+	{0x9B, 0xBC, 0x0F, 0xFF},
+	{0x8B, 0xAC, 0x0F, 0xFF},
+	{0x30, 0x62, 0x30, 0xFF},
+	{0x0F, 0x38, 0x0F, 0xFF},
+}
+
+// SetPalette sets the colors Frame draws the 4 DMG shades with on DMG family models, it's safe to call from any goroutine
+func (c *Console) SetPalette(palette Palette) {
+	c.palette.Store(palette)
+}
+
+// Palette returns the colors Frame draws the 4 DMG shades with
+func (c *Console) Palette() Palette {
+	return c.palette.Load().(Palette)
+}
+
+// Frame returns the last finished frame as a new 160x144 image, colored with the palette on DMG family models
+func (c *Console) Frame() *image.RGBA {
+	palette := c.Palette()
+	cgb := c.model.IsCGB()
+
+	img := image.NewRGBA(image.Rect(0, 0, SCREEN_WIDTH, SCREEN_HEIGHT))
+	c.ppu.withFrame(func(frame *screen) {
+		for y := 0; y < SCREEN_HEIGHT; y++ {
+			row := img.Pix[y*img.Stride:]
+			for x := 0; x < SCREEN_WIDTH; x++ {
+				color := palette[frame.shades[y][x]&0x03]
+				if cgb {
+					color = rgba(frame.colors[y][x])
+				}
+				row[x*4], row[x*4+1], row[x*4+2], row[x*4+3] = color.R, color.G, color.B, color.A
+			}
+		}
+	})
+	return img
+}
+
+// Shades returns the last finished frame as shades, 0 (white) - 3 (black), for frontends doing their own coloring.
+// In CGB mode they're the color numbers (0 - 3) before the CGB palettes
+func (c *Console) Shades() [SCREEN_HEIGHT][SCREEN_WIDTH]uint8 {
+	var shades [SCREEN_HEIGHT][SCREEN_WIDTH]uint8
+	c.ppu.withFrame(func(frame *screen) {
+		shades = frame.shades
+	})
+	return shades
+}
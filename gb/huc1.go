@@ -0,0 +1,131 @@
+package gb
+
+/*
+
+HuC1 (Hudson)
+
+	0000 - 1FFF IR select, 0x0E maps the infrared port at A000 - BFFF, anything else maps RAM
+	2000 - 3FFF ROM bank number, 6 bits
+	4000 - 5FFF RAM bank number, 2 bits
+	6000 - 7FFF No effect
+
+Infrared port (A000 - BFFF in IR mode)
+
+	Read  - 0xC1 when the receiver sees light, 0xC0 when it doesn't
+	Write - Bit 0 turns the IR LED on
+
+RAM is always writable in RAM mode, there's no separate RAM enable.
+
+*/
+
+type HuC1 struct {
+	cart    *Cartridge
+	irMode  bool
+	romBank uint8
+	ramBank uint8
+	ir      Infrared
+}
+
+func NewHuC1(cart *Cartridge) *HuC1 {
+	return &HuC1{
+		cart:    cart,
+		romBank: 1,
+	}
+}
+
+// IR returns the cartridge's infrared port
+func (m *HuC1) IR() *Infrared {
+	return &m.ir
+}
+
+func (m *HuC1) ReadROM(address uint16) uint8 {
+	if address < ROM_BANK_SIZE {
+		return m.cart.rom[address]
+	}
+
+	bank := int(m.romBank) % (len(m.cart.rom) / ROM_BANK_SIZE)
+	return m.cart.rom[bank*ROM_BANK_SIZE+int(address-ROM_BANK_SIZE)]
+}
+
+func (m *HuC1) WriteROM(address uint16, value uint8) {
+	switch {
+	case address < 0x2000:
+		m.irMode = value == 0x0E
+	case address < 0x4000:
+		m.romBank = value & 0x3F
+	case address < 0x6000:
+		m.ramBank = value & 0x03
+	}
+}
+
+func (m *HuC1) ReadRAM(address uint16) uint8 {
+	if m.irMode {
+		return m.ir.Read()
+	}
+
+	if len(m.cart.ram) == 0 {
+		return 0xFF
+	}
+	return m.cart.ram[(int(m.ramBank)*RAM_BANK_SIZE+int(address))%len(m.cart.ram)]
+}
+
+func (m *HuC1) WriteRAM(address uint16, value uint8) {
+	if m.irMode {
+		m.ir.Write(value)
+		return
+	}
+
+	if len(m.cart.ram) == 0 {
+		return
+	}
+	m.cart.ram[(int(m.ramBank)*RAM_BANK_SIZE+int(address))%len(m.cart.ram)] = value
+	m.cart.dirty = true
+}
+
+// <----------------------------- INFRARED -----------------------------> //
+
+// Infrared is the IR LED and receiver on HuC1 and HuC3 cartridges, the other end of the link is up to the frontend
+type Infrared struct {
+	led      bool          // LED is on
+	light    bool          // receiver sees light
+	onChange func(on bool) // called when the LED turns on or off
+}
+
+// Read returns 0xC1 when the receiver sees light, 0xC0 otherwise
+func (ir *Infrared) Read() uint8 {
+	if ir.light {
+		return 0xC1
+	}
+	return 0xC0
+}
+
+// Write turns the LED on or off with bit 0
+func (ir *Infrared) Write(value uint8) {
+	on := value&0x01 != 0
+	if on == ir.led {
+		return
+	}
+
+	ir.led = on
+	if ir.onChange != nil {
+		ir.onChange(on)
+	}
+}
+
+// SetCallback sets the function called when the LED turns on or off
+func (ir *Infrared) SetCallback(callback func(on bool)) {
+	ir.onChange = callback
+}
+
+// SetLight sets whether the receiver sees light, e.g. from another cartridge's LED
+func (ir *Infrared) SetLight(on bool) {
+	ir.light = on
+}
+
+func (m *HuC1) saveState(s *stateWriter) {
+	s.write(m.irMode, m.romBank, m.ramBank, m.ir.led)
+}
+
+func (m *HuC1) loadState(s *stateReader) {
+	s.read(&m.irMode, &m.romBank, &m.ramBank, &m.ir.led)
+}
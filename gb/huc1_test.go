@@ -0,0 +1,53 @@
+package gb
+
+import "testing"
+
+func TestHuC1(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []mapperWrite
+		light  bool
+		reads  map[uint16]uint8 // address to the value read, ROM reads give the bank
+	}{
+		{"power on", nil, false, map[uint16]uint8{0x0000: 0, 0x4000: 1}},
+		{"ROM bank", []mapperWrite{{0x2000, 0x05}}, false, map[uint16]uint8{0x4000: 5}},
+		{"ROM bank 6 bits", []mapperWrite{{0x2000, 0xC5}}, false, map[uint16]uint8{0x4000: 5}},
+		{"ROM bank 0", []mapperWrite{{0x2000, 0x00}}, false, map[uint16]uint8{0x4000: 0}},
+		{"RAM always writable", []mapperWrite{{0xA000, 0x42}}, false, map[uint16]uint8{0xA000: 0x42}},
+		{"RAM bank", []mapperWrite{{0x4000, 0x02}, {0xA000, 0x42}, {0x4000, 0x01}}, false, map[uint16]uint8{0xA000: 0x00}},
+		{"RAM bank back", []mapperWrite{{0x4000, 0x02}, {0xA000, 0x42}, {0x4000, 0x01}, {0x4000, 0x02}}, false, map[uint16]uint8{0xA000: 0x42}},
+		{"IR dark", []mapperWrite{{0x0000, 0x0E}}, false, map[uint16]uint8{0xA000: 0xC0}},
+		{"IR light", []mapperWrite{{0x0000, 0x0E}}, true, map[uint16]uint8{0xA000: 0xC1}},
+		{"IR writes miss RAM", []mapperWrite{{0x0000, 0x0E}, {0xA000, 0x01}, {0x0000, 0x00}}, false, map[uint16]uint8{0xA000: 0x00}},
+	}
+
+	for _, test := range tests {
+		m := NewHuC1(bankedCart(64, 4*RAM_BANK_SIZE))
+		m.IR().SetLight(test.light)
+		writeMapper(m, test.writes)
+
+		for address, want := range test.reads {
+			var got uint8
+			if address < ROM_END {
+				got = m.ReadROM(address)
+			} else {
+				got = m.ReadRAM(address - VRAM_END)
+			}
+			if got != want {
+				t.Errorf("%s: %04X = %02X, want %02X", test.name, address, got, want)
+			}
+		}
+	}
+}
+
+func TestHuC1Infrared(t *testing.T) {
+	m := NewHuC1(bankedCart(2, RAM_BANK_SIZE))
+	var changes []bool
+	m.IR().SetCallback(func(on bool) { changes = append(changes, on) })
+
+	writeMapper(m, []mapperWrite{{0x0000, 0x0E}, {0xA000, 0x01}, {0xA000, 0x01}, {0xA000, 0x00}})
+
+	if len(changes) != 2 || !changes[0] || changes[1] {
+		t.Errorf("LED changes = %v, want [true false]", changes)
+	}
+}
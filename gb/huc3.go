@@ -0,0 +1,295 @@
+package gb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+/*
+
+HuC3 (Hudson)
+
+	0000 - 1FFF Mode select for A000 - BFFF
+		0x00 RAM, read only
+		0x0A RAM, read and write
+		0x0B RTC command write
+		0x0C RTC response read
+		0x0D RTC semaphore, reads 1 when the RTC is ready
+		0x0E Infrared port, same as HuC1
+	2000 - 3FFF ROM bank number, 7 bits
+	4000 - 5FFF RAM bank number, 2 bits
+	6000 - 7FFF No effect
+
+RTC commands are written in mode 0x0B, bits 4-6 select the command and bits 0-3 are its argument.
+Reading in mode 0x0C returns the last command in bits 4-6 and its result in bits 0-3.
+
+	1 Read the RTC memory nibble at the address, then increment the address
+	3 Write the argument to the RTC memory nibble at the address, then increment the address
+	4 Set the address low nibble
+	5 Set the address high nibble
+	6 Extended command
+		0 Copy the current time into RTC memory 0x00 - 0x05
+		1 Copy RTC memory 0x00 - 0x05 into the current time
+		2 Status, returns 1
+
+The time is kept as minutes since midnight (0x00 - 0x02) and a 12-bit day counter (0x03 - 0x05), low nibble first.
+Like the MBC3 RTC it runs off emulated cycles unless a wall clock is set.
+
+The clock is battery backed, it's saved after the RAM in 272 bytes, little endian:
+
+	000 - 0FF RTC memory, one nibble per byte
+	100       Seconds
+	101 - 102 Minutes since midnight
+	103 - 104 Days
+	108 - 10F UNIX timestamp of the save
+
+*/
+
+const HUC3_SAVE_SIZE = 0x110
+
+type HuC3 struct {
+	cart    *Cartridge
+	mode    uint8
+	romBank uint8
+	ramBank uint8
+	ir      Infrared
+
+	// RTC
+	memory   [0x100]uint8 // 4-bit RTC memory
+	address  uint8        // RTC memory address
+	response uint8        // result of the last command, read in mode 0x0C
+	seconds  uint8
+	minutes  uint16    // minutes since midnight
+	days     uint16    // 12-bit day counter
+	cycles   uint32    // cycles since the last second, when running off emulated cycles
+	clock    Clock     // wall clock, nil when running off emulated cycles
+	synced   time.Time // wall clock time the clock is up to date with
+	saved    time.Time // timestamp of a save loaded without a clock, for a clock set later to catch up from
+}
+
+func NewHuC3(cart *Cartridge) *HuC3 {
+	return &HuC3{
+		cart:    cart,
+		romBank: 1,
+	}
+}
+
+// IR returns the cartridge's infrared port
+func (m *HuC3) IR() *Infrared {
+	return &m.ir
+}
+
+// SetClock makes the RTC follow the given wall clock, nil goes back to emulated cycles.
+// After loading a save without a clock, the first clock set catches up on the time since the save
+func (m *HuC3) SetClock(clock Clock) {
+	m.clock = clock
+	if clock == nil {
+		return
+	}
+
+	if m.saved.IsZero() {
+		m.synced = clock.Now()
+		return
+	}
+
+	m.synced = m.saved
+	m.saved = time.Time{}
+	m.sync()
+}
+
+// Step runs the RTC off emulated cycles
+func (m *HuC3) Step(ticks int) {
+	if m.clock != nil {
+		return
+	}
+
+	m.cycles += uint32(ticks)
+	for m.cycles >= CLOCK_SPEED {
+		m.cycles -= CLOCK_SPEED
+		m.advance(1)
+	}
+}
+
+func (m *HuC3) ReadROM(address uint16) uint8 {
+	if address < ROM_BANK_SIZE {
+		return m.cart.rom[address]
+	}
+
+	bank := int(m.romBank) % (len(m.cart.rom) / ROM_BANK_SIZE)
+	return m.cart.rom[bank*ROM_BANK_SIZE+int(address-ROM_BANK_SIZE)]
+}
+
+func (m *HuC3) WriteROM(address uint16, value uint8) {
+	switch {
+	case address < 0x2000:
+		m.mode = value & 0x0F
+	case address < 0x4000:
+		m.romBank = value & 0x7F
+	case address < 0x6000:
+		m.ramBank = value & 0x03
+	}
+}
+
+func (m *HuC3) ReadRAM(address uint16) uint8 {
+	switch m.mode {
+	case 0x00, 0x0A:
+		if len(m.cart.ram) == 0 {
+			return 0xFF
+		}
+		return m.cart.ram[(int(m.ramBank)*RAM_BANK_SIZE+int(address))%len(m.cart.ram)]
+	case 0x0C:
+		return m.response
+	case 0x0D:
+		return 0x01
+	case 0x0E:
+		return m.ir.Read()
+	default:
+		return 0xFF
+	}
+}
+
+func (m *HuC3) WriteRAM(address uint16, value uint8) {
+	switch m.mode {
+	case 0x0A:
+		if len(m.cart.ram) == 0 {
+			return
+		}
+		m.cart.ram[(int(m.ramBank)*RAM_BANK_SIZE+int(address))%len(m.cart.ram)] = value
+		m.cart.dirty = true
+	case 0x0B:
+		m.command(value>>4&0x07, value&0x0F)
+	case 0x0E:
+		m.ir.Write(value)
+	}
+}
+
+// command runs an RTC command and stores its response
+func (m *HuC3) command(command uint8, argument uint8) {
+	var result uint8
+
+	switch command {
+	case 0x1:
+		result = m.memory[m.address]
+		m.address++
+	case 0x3:
+		m.memory[m.address] = argument
+		m.address++
+	case 0x4:
+		m.address = m.address&0xF0 | argument
+	case 0x5:
+		m.address = m.address&0x0F | argument<<4
+	case 0x6:
+		switch argument {
+		case 0x0:
+			m.sync()
+			for i := 0; i < 3; i++ {
+				m.memory[i] = uint8(m.minutes>>(i*4)) & 0x0F
+				m.memory[3+i] = uint8(m.days>>(i*4)) & 0x0F
+			}
+		case 0x1:
+			m.sync()
+			m.minutes, m.days = 0, 0
+			for i := 0; i < 3; i++ {
+				m.minutes |= uint16(m.memory[i]) << (i * 4)
+				m.days |= uint16(m.memory[3+i]) << (i * 4)
+			}
+			m.minutes %= 1440
+			m.seconds = 0
+			m.cycles = 0
+		case 0x2:
+			result = 0x1
+		}
+	}
+
+	m.response = command<<4 | result
+}
+
+// sync catches the time up with the wall clock
+func (m *HuC3) sync() {
+	if m.clock == nil {
+		return
+	}
+
+	elapsed := m.clock.Now().Sub(m.synced)
+	if elapsed < time.Second {
+		return
+	}
+
+	seconds := uint64(elapsed / time.Second)
+	m.synced = m.synced.Add(time.Duration(seconds) * time.Second)
+	m.advance(seconds)
+}
+
+// advance moves the time forward by a number of seconds
+func (m *HuC3) advance(seconds uint64) {
+	total := uint64(m.seconds) + uint64(m.minutes)*60 + seconds
+
+	m.seconds = uint8(total % 60)
+	m.minutes = uint16(total / 60 % 1440)
+	m.days = uint16((uint64(m.days) + total/86400) & 0xFFF)
+}
+
+func (m *HuC3) batterySize() int {
+	return HUC3_SAVE_SIZE
+}
+
+func (m *HuC3) saveBattery() []uint8 {
+	m.sync()
+
+	data := make([]uint8, HUC3_SAVE_SIZE)
+	copy(data, m.memory[:])
+	data[0x100] = m.seconds
+	binary.LittleEndian.PutUint16(data[0x101:], m.minutes)
+	binary.LittleEndian.PutUint16(data[0x103:], m.days)
+
+	now := time.Now()
+	if m.clock != nil {
+		now = m.clock.Now()
+	}
+	binary.LittleEndian.PutUint64(data[0x108:], uint64(now.Unix()))
+
+	return data
+}
+
+// loadBattery restores the clock, following the wall clock it also catches up on the time passed since the save
+func (m *HuC3) loadBattery(data []uint8) error {
+	if len(data) != HUC3_SAVE_SIZE {
+		return fmt.Errorf("huc3 save is %d bytes, expected %d", len(data), HUC3_SAVE_SIZE)
+	}
+
+	for i := range m.memory {
+		m.memory[i] = data[i] & 0x0F
+	}
+	m.seconds = data[0x100] % 60
+	m.minutes = binary.LittleEndian.Uint16(data[0x101:]) % 1440
+	m.days = binary.LittleEndian.Uint16(data[0x103:]) & 0xFFF
+	m.cycles = 0
+
+	saved := time.Unix(int64(binary.LittleEndian.Uint64(data[0x108:])), 0)
+	if m.clock == nil {
+		m.saved = saved
+		return nil
+	}
+
+	m.synced = saved
+	m.saved = time.Time{}
+	m.sync()
+	return nil
+}
+
+func (m *HuC3) saveState(s *stateWriter) {
+	m.sync()
+	s.write(m.mode, m.romBank, m.ramBank, m.ir.led)
+	s.write(&m.memory, m.address, m.response, m.seconds, m.minutes, m.days, m.cycles)
+}
+
+func (m *HuC3) loadState(s *stateReader) {
+	s.read(&m.mode, &m.romBank, &m.ramBank, &m.ir.led)
+	s.read(&m.memory, &m.address, &m.response, &m.seconds, &m.minutes, &m.days, &m.cycles)
+
+	// following the wall clock, the time keeps going from now
+	if m.clock != nil {
+		m.synced = m.clock.Now()
+	}
+}
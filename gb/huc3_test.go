@@ -0,0 +1,90 @@
+package gb
+
+import "testing"
+
+// huc3Command runs an RTC command and returns the result nibble of its response
+func huc3Command(m *HuC3, command uint8, argument uint8) uint8 {
+	m.WriteROM(0x0000, 0x0B)
+	m.WriteRAM(0x0000, command<<4|argument)
+	m.WriteROM(0x0000, 0x0C)
+	return m.ReadRAM(0x0000) & 0x0F
+}
+
+// huc3SetAddress sets the RTC memory address
+func huc3SetAddress(m *HuC3, address uint8) {
+	huc3Command(m, 0x4, address&0x0F)
+	huc3Command(m, 0x5, address>>4)
+}
+
+// huc3WriteTime writes minutes and days to RTC memory and copies them into the time
+func huc3WriteTime(m *HuC3, minutes uint16, days uint16) {
+	huc3SetAddress(m, 0x00)
+	for i := 0; i < 3; i++ {
+		huc3Command(m, 0x3, uint8(minutes>>(i*4))&0x0F)
+	}
+	for i := 0; i < 3; i++ {
+		huc3Command(m, 0x3, uint8(days>>(i*4))&0x0F)
+	}
+	huc3Command(m, 0x6, 0x1)
+}
+
+// huc3ReadTime copies the time into RTC memory and reads minutes and days back
+func huc3ReadTime(m *HuC3) (minutes uint16, days uint16) {
+	huc3Command(m, 0x6, 0x0)
+	huc3SetAddress(m, 0x00)
+	for i := 0; i < 3; i++ {
+		minutes |= uint16(huc3Command(m, 0x1, 0)) << (i * 4)
+	}
+	for i := 0; i < 3; i++ {
+		days |= uint16(huc3Command(m, 0x1, 0)) << (i * 4)
+	}
+	return minutes, days
+}
+
+func TestHuC3Time(t *testing.T) {
+	tests := []struct {
+		minutes, days         uint16
+		seconds               int // emulated seconds between writing and reading the time
+		wantMinutes, wantDays uint16
+	}{
+		{0, 0, 0, 0, 0},
+		{754, 0x123, 0, 754, 0x123},
+		{754, 0x123, 59, 754, 0x123},
+		{754, 0x123, 60, 755, 0x123},
+		{1439, 5, 60, 0, 6},
+		{2000, 0, 0, 560, 0}, // minutes past a day wrap
+		{0, 0xFFF, 86400, 0, 0},
+	}
+
+	for _, test := range tests {
+		m := NewHuC3(bankedCart(2, RAM_BANK_SIZE))
+		huc3WriteTime(m, test.minutes, test.days)
+		for i := 0; i < test.seconds; i++ {
+			m.Step(int(CLOCK_SPEED))
+		}
+
+		minutes, days := huc3ReadTime(m)
+		if minutes != test.wantMinutes || days != test.wantDays {
+			t.Errorf("%d:%03X + %ds: read %d:%03X, want %d:%03X",
+				test.minutes, test.days, test.seconds, minutes, days, test.wantMinutes, test.wantDays)
+		}
+	}
+}
+
+func TestHuC3Memory(t *testing.T) {
+	m := NewHuC3(bankedCart(2, RAM_BANK_SIZE))
+	huc3SetAddress(m, 0xFE)
+	huc3Command(m, 0x3, 0x7)
+	huc3Command(m, 0x3, 0x9)
+
+	huc3SetAddress(m, 0xFE)
+	if got := huc3Command(m, 0x1, 0); got != 0x7 {
+		t.Errorf("RTC memory FE = %X, want 7", got)
+	}
+	if got := huc3Command(m, 0x1, 0); got != 0x9 {
+		t.Errorf("RTC memory FF = %X, want 9", got)
+	}
+	if got := huc3Command(m, 0x6, 0x2); got != 0x1 {
+		t.Errorf("status = %X, want 1", got)
+	}
+}
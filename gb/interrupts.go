@@ -0,0 +1,56 @@
+package gb
+
+/*
+
+Interrupts
+
+	- IE (0xFFFF) enables each interrupt source
+	- IF (0xFF0F) holds the requested interrupts, bits 5-7 always read as 1
+	- IME (in the CPU) is the master switch, set by EI (after one instruction) or RETI and cleared by DI
+
+When an interrupt is both requested and enabled and IME is set, the CPU clears IME and the IF bit,
+pushes PC and jumps to the interrupt's vector. Lower bits have priority.
+
+	Bit 0: VBlank  -> 0x40
+	Bit 1: STAT    -> 0x48
+	Bit 2: Timer   -> 0x50
+	Bit 3: Serial  -> 0x58
+	Bit 4: Joypad  -> 0x60
+
+*/
+
+type Interrupt uint8
+
+const (
+	INT_VBLANK Interrupt = iota
+	INT_STAT
+	INT_TIMER
+	INT_SERIAL
+	INT_JOYPAD
+)
+
+const IF_ADDRESS = 0xFF0F
+const IE_ADDRESS = 0xFFFF
+
+// interrupt dispatch takes 5 M-cycles
+const INTERRUPT_TICKS = 20
+
+// Vector returns the address the CPU jumps to when servicing the interrupt
+func (i Interrupt) Vector() uint16 {
+	return 0x0040 + uint16(i)*8
+}
+
+// RequestInterrupt sets the interrupt's bit in IF, used by the PPU, timer, serial and joypad
+func (mem *MemoryMap) RequestInterrupt(interrupt Interrupt) {
+	mem.io[IF_ADDRESS-UNUSED_END] |= 1 << interrupt
+}
+
+// ClearInterrupt resets the interrupt's bit in IF
+func (mem *MemoryMap) ClearInterrupt(interrupt Interrupt) {
+	mem.io[IF_ADDRESS-UNUSED_END] &^= 1 << interrupt
+}
+
+// PendingInterrupts returns the interrupts that are both requested and enabled
+func (mem *MemoryMap) PendingInterrupts() uint8 {
+	return mem.ie & mem.io[IF_ADDRESS-UNUSED_END] & 0x1F
+}
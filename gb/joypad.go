@@ -0,0 +1,112 @@
+package gb
+
+/*
+
+Joypad register P1/JOYP (0xFF00)
+
+	Bit 5 - P15 Select action buttons    (0 = select)
+	Bit 4 - P14 Select direction buttons (0 = select)
+	Bit 3 - P13 Down  or Start  (0 = pressed)
+	Bit 2 - P12 Up    or Select (0 = pressed)
+	Bit 1 - P11 Left  or B      (0 = pressed)
+	Bit 0 - P10 Right or A      (0 = pressed)
+
+When one of P10 - P13 goes low, because a selected button is pressed or a pressed button's group is selected,
+the joypad interrupt is requested and the CPU wakes from STOP.
+
+The joypad also holds the tilt input, read by cartridges with an accelerometer (MBC7).
+
+*/
+
+type Button uint8
+
+const (
+	BUTTON_RIGHT Button = iota
+	BUTTON_LEFT
+	BUTTON_UP
+	BUTTON_DOWN
+	BUTTON_A
+	BUTTON_B
+	BUTTON_SELECT
+	BUTTON_START
+)
+
+const JOYP_ADDRESS = 0xFF00
+
+type Joypad struct {
+	mem      *MemoryMap // memory map, for requesting interrupts
+	pressed  uint8      // one bit per Button, 1 = pressed
+	selected uint8      // bits 4-5 of P1
+	tiltX    float64    // tilt in g, positive is right
+	tiltY    float64    // tilt in g, positive is towards the player
+}
+
+func (j *Joypad) Read() uint8 {
+	result := 0xC0 | j.selected | 0x0F
+
+	if j.selected&0x10 == 0 {
+		result &^= j.pressed & 0x0F
+	}
+	if j.selected&0x20 == 0 {
+		result &^= j.pressed >> 4
+	}
+
+	return result
+}
+
+func (j *Joypad) Write(value uint8) {
+	lines := j.lines()
+	j.selected = value & 0x30
+	j.checkLines(lines)
+}
+
+func (j *Joypad) Press(button Button) {
+	lines := j.lines()
+	j.pressed |= 1 << button
+	j.checkLines(lines)
+}
+
+func (j *Joypad) Release(button Button) {
+	j.pressed &^= 1 << button
+}
+
+// lines returns P10 - P13, a bit is 0 while a selected button on it is pressed
+func (j *Joypad) lines() uint8 {
+	return j.Read() & 0x0F
+}
+
+// checkLines requests the joypad interrupt and wakes the CPU from STOP when a line went low since before
+func (j *Joypad) checkLines(before uint8) {
+	if before&^j.lines() == 0 {
+		return
+	}
+
+	j.mem.RequestInterrupt(INT_JOYPAD)
+	j.mem.console.cpu.stopped = false
+}
+
+// SetTilt sets the tilt in g
+func (j *Joypad) SetTilt(x, y float64) {
+	j.tiltX = x
+	j.tiltY = y
+}
+
+// Tilt returns the tilt in g
+func (j *Joypad) Tilt() (x, y float64) {
+	return j.tiltX, j.tiltY
+}
+
+func (j *Joypad) Reset() {
+	j.pressed = 0
+	j.selected = 0x30
+}
+
+// the buttons and tilt are input from the frontend, not console state, so only the selected group is saved
+
+func (j *Joypad) saveState(s *stateWriter) {
+	s.write(j.selected)
+}
+
+func (j *Joypad) loadState(s *stateReader) {
+	s.read(&j.selected)
+}
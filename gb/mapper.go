@@ -0,0 +1,123 @@
+package gb
+
+/*
+
+Mappers (memory bank controllers) sit between the CPU and the cartridge ROM/RAM.
+
+Writes to the ROM area (0x0000 - 0x7FFF) don't change ROM, they set the mapper's registers,
+which select the ROM bank seen at 0x4000 - 0x7FFF and the RAM bank seen at 0xA000 - 0xBFFF.
+
+*/
+
+const ROM_BANK_SIZE = 0x4000
+const RAM_BANK_SIZE = 0x2000
+
+// Mapper is implemented by every memory bank controller, RAM addresses are relative to 0xA000.
+// WriteRAM sets the cartridge's dirty flag when it stores into the cartridge RAM
+type Mapper interface {
+	ReadROM(address uint16) uint8
+	WriteROM(address uint16, value uint8)
+	ReadRAM(address uint16) uint8
+	WriteRAM(address uint16, value uint8)
+}
+
+// clockedMapper is implemented by mappers with hardware that runs off the system clock, like an RTC
+type clockedMapper interface {
+	Step(ticks int)
+}
+
+// batteryMapper is implemented by mappers with battery backed state besides the RAM,
+// it's stored after the RAM in the battery save
+type batteryMapper interface {
+	batterySize() int // bytes of saved state, 0 when there is none
+	saveBattery() []uint8
+	loadBattery(data []uint8) error
+}
+
+// wallClockMapper is implemented by mappers with a real time clock that can follow a wall clock
+type wallClockMapper interface {
+	SetClock(clock Clock)
+}
+
+// irMapper is implemented by mappers with an infrared port
+type irMapper interface {
+	IR() *Infrared
+}
+
+// cameraMapper is implemented by mappers with an image sensor
+type cameraMapper interface {
+	SetCameraSource(source CameraSource)
+}
+
+// tiltMapper is implemented by mappers with an accelerometer
+type tiltMapper interface {
+	SetTiltSource(tilt func() (x, y float64))
+}
+
+// rumbleMapper is implemented by mappers that may drive a rumble motor
+type rumbleMapper interface {
+	SetRumbleCallback(callback func(on bool))
+}
+
+// newMapper creates the memory bank controller for the cartridge type in the header
+func newMapper(cart *Cartridge) (Mapper, error) {
+	switch cart.Header.Type {
+	case 0x00, 0x08, 0x09:
+		return &ROMOnly{cart: cart}, nil
+	case 0x01, 0x02, 0x03:
+		return NewMBC1(cart), nil
+	case 0x05, 0x06:
+		return NewMBC2(cart), nil
+	case 0x0B, 0x0C, 0x0D:
+		return NewMMM01(cart), nil
+	case 0x0F, 0x10, 0x11, 0x12, 0x13:
+		return NewMBC3(cart), nil
+	case 0x19, 0x1A, 0x1B, 0x1C, 0x1D, 0x1E:
+		return NewMBC5(cart), nil
+	case 0x20:
+		return NewMBC6(cart), nil
+	case 0x22:
+		return NewMBC7(cart), nil
+	case 0xFC:
+		return NewPocketCamera(cart), nil
+	case 0xFD:
+		return NewTAMA5(cart), nil
+	case 0xFE:
+		return NewHuC3(cart), nil
+	case 0xFF:
+		return NewHuC1(cart), nil
+	default:
+		return nil, &UnsupportedMapperError{Type: cart.Header.Type}
+	}
+}
+
+// <----------------------------- ROM ONLY -----------------------------> //
+
+// ROMOnly is a cartridge without a memory bank controller, 32KB ROM and up to 8KB RAM
+type ROMOnly struct {
+	cart *Cartridge
+}
+
+func (m *ROMOnly) ReadROM(address uint16) uint8 {
+	if int(address) < len(m.cart.rom) {
+		return m.cart.rom[address]
+	}
+	return 0xFF
+}
+
+// writes to the ROM area have no effect without a memory bank controller
+func (m *ROMOnly) WriteROM(address uint16, value uint8) {}
+
+func (m *ROMOnly) ReadRAM(address uint16) uint8 {
+	if int(address) < len(m.cart.ram) {
+		return m.cart.ram[address]
+	}
+	return 0xFF
+}
+
+func (m *ROMOnly) WriteRAM(address uint16, value uint8) {
+	if int(address) < len(m.cart.ram) {
+		m.cart.ram[address] = value
+		m.cart.dirty = true
+	}
+}
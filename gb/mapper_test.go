@@ -0,0 +1,26 @@
+package gb
+
+// bankedCart creates a cartridge with ROM banks that start with their own bank number, and RAM of ramSize bytes
+func bankedCart(romBanks int, ramSize int) *Cartridge {
+	rom := make([]uint8, romBanks*ROM_BANK_SIZE)
+	for bank := 0; bank < romBanks; bank++ {
+		rom[bank*ROM_BANK_SIZE] = uint8(bank)
+	}
+	return &Cartridge{rom: rom, ram: make([]uint8, ramSize)}
+}
+
+// mapperWrite is a write to the mapper, ROM addresses go to WriteROM and A000 - BFFF to WriteRAM
+type mapperWrite struct {
+	address uint16
+	value   uint8
+}
+
+func writeMapper(m Mapper, writes []mapperWrite) {
+	for _, w := range writes {
+		if w.address < ROM_END {
+			m.WriteROM(w.address, w.value)
+		} else {
+			m.WriteRAM(w.address-VRAM_END, w.value)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package gb
+
+import (
+	"bytes"
+)
+
+/*
+
+MBC1
+
+	0000 - 1FFF RAM enable, 0x0A in the lower nibble enables RAM
+	2000 - 3FFF ROM bank number, lower 5 bits, 0 is treated as 1
+	4000 - 5FFF RAM bank number or upper 2 bits of the ROM bank number
+	6000 - 7FFF Banking mode select
+		0 = 0000 - 3FFF is always bank 0, RAM is always bank 0
+		1 = the 2-bit register also applies to 0000 - 3FFF and RAM
+
+MBC1M multicarts are 1MB and only connect 4 bits of the ROM bank register,
+so the 2-bit register selects one of four 256KB games.
+
+*/
+
+type MBC1 struct {
+	cart      *Cartridge
+	ramEnable bool
+	bank1     uint8 // 5-bit ROM bank register
+	bank2     uint8 // 2-bit RAM bank / upper ROM bank register
+	mode      uint8 // banking mode
+	multicart bool  // MBC1M wiring
+}
+
+func NewMBC1(cart *Cartridge) *MBC1 {
+	return &MBC1{
+		cart:      cart,
+		bank1:     1,
+		multicart: isMBC1Multicart(cart.rom),
+	}
+}
+
+// isMBC1Multicart detects MBC1M carts, which are 1MB with a second game (and logo) at bank 0x10
+func isMBC1Multicart(rom []uint8) bool {
+	if len(rom) != 0x100000 {
+		return false
+	}
+
+	logo := rom[0x10*ROM_BANK_SIZE+0x104 : 0x10*ROM_BANK_SIZE+0x134]
+	return bytes.Equal(logo, nintendoLogo[:])
+}
+
+// romBank returns the bank mapped at 0x0000 (low) or 0x4000 (high)
+func (m *MBC1) romBank(high bool) int {
+	shift := uint(5)
+	bank1 := m.bank1
+	if m.multicart {
+		shift = 4
+		bank1 &= 0x0F
+	}
+
+	bank := 0
+	if high || m.mode == 1 {
+		bank = int(m.bank2) << shift
+	}
+	if high {
+		bank |= int(bank1)
+	}
+
+	// unconnected upper bits wrap around the ROM
+	return bank % (len(m.cart.rom) / ROM_BANK_SIZE)
+}
+
+func (m *MBC1) ReadROM(address uint16) uint8 {
+	if address < ROM_BANK_SIZE {
+		return m.cart.rom[m.romBank(false)*ROM_BANK_SIZE+int(address)]
+	}
+	return m.cart.rom[m.romBank(true)*ROM_BANK_SIZE+int(address-ROM_BANK_SIZE)]
+}
+
+func (m *MBC1) WriteROM(address uint16, value uint8) {
+	switch {
+	case address < 0x2000:
+		m.ramEnable = value&0x0F == 0x0A
+	case address < 0x4000:
+		// bank 0 can't be selected here, the check only looks at the 5 bits
+		m.bank1 = value & 0x1F
+		if m.bank1 == 0 {
+			m.bank1 = 1
+		}
+	case address < 0x6000:
+		m.bank2 = value & 0x03
+	default:
+		m.mode = value & 0x01
+	}
+}
+
+// ramAddress returns the offset into cartridge RAM, or -1 when RAM is disabled or missing
+func (m *MBC1) ramAddress(address uint16) int {
+	if !m.ramEnable || len(m.cart.ram) == 0 {
+		return -1
+	}
+
+	bank := 0
+	if m.mode == 1 {
+		bank = int(m.bank2)
+	}
+
+	return (bank*RAM_BANK_SIZE + int(address)) % len(m.cart.ram)
+}
+
+func (m *MBC1) ReadRAM(address uint16) uint8 {
+	if offset := m.ramAddress(address); offset >= 0 {
+		return m.cart.ram[offset]
+	}
+	return 0xFF
+}
+
+func (m *MBC1) WriteRAM(address uint16, value uint8) {
+	if offset := m.ramAddress(address); offset >= 0 {
+		m.cart.ram[offset] = value
+		m.cart.dirty = true
+	}
+}
+
+func (m *MBC1) saveState(s *stateWriter) {
+	s.write(m.ramEnable, m.bank1, m.bank2, m.mode)
+}
+
+func (m *MBC1) loadState(s *stateReader) {
+	s.read(&m.ramEnable, &m.bank1, &m.bank2, &m.mode)
+}
@@ -0,0 +1,84 @@
+package gb
+
+/*
+
+MBC2
+
+	0000 - 3FFF RAM enable or ROM bank number, selected by bit 8 of the address
+		bit 8 clear: RAM enable, 0x0A in the lower nibble enables RAM
+		bit 8 set:   ROM bank number, 4 bits, 0 is treated as 1
+	A000 - A1FF Built-in 512x4-bit RAM, only the lower nibble is stored
+	A200 - BFFF Mirrors of A000 - A1FF
+
+*/
+
+const MBC2_RAM_SIZE = 0x200
+
+type MBC2 struct {
+	cart      *Cartridge
+	ramEnable bool
+	romBank   uint8
+}
+
+func NewMBC2(cart *Cartridge) *MBC2 {
+	// the RAM is inside the MBC2 chip, so the header lists no RAM
+	if len(cart.ram) != MBC2_RAM_SIZE {
+		cart.ram = make([]uint8, MBC2_RAM_SIZE)
+	}
+
+	return &MBC2{
+		cart:    cart,
+		romBank: 1,
+	}
+}
+
+func (m *MBC2) ReadROM(address uint16) uint8 {
+	if address < ROM_BANK_SIZE {
+		return m.cart.rom[address]
+	}
+
+	bank := int(m.romBank) % (len(m.cart.rom) / ROM_BANK_SIZE)
+	return m.cart.rom[bank*ROM_BANK_SIZE+int(address-ROM_BANK_SIZE)]
+}
+
+func (m *MBC2) WriteROM(address uint16, value uint8) {
+	// only the lower half of the ROM area has registers
+	if address >= ROM_BANK_SIZE {
+		return
+	}
+
+	if address&0x0100 == 0 {
+		m.ramEnable = value&0x0F == 0x0A
+	} else {
+		m.romBank = value & 0x0F
+		if m.romBank == 0 {
+			m.romBank = 1
+		}
+	}
+}
+
+func (m *MBC2) ReadRAM(address uint16) uint8 {
+	if !m.ramEnable {
+		return 0xFF
+	}
+
+	// the upper nibble isn't connected and reads as 1s
+	return m.cart.ram[address%MBC2_RAM_SIZE] | 0xF0
+}
+
+func (m *MBC2) WriteRAM(address uint16, value uint8) {
+	if !m.ramEnable {
+		return
+	}
+
+	m.cart.ram[address%MBC2_RAM_SIZE] = value & 0x0F
+	m.cart.dirty = true
+}
+
+func (m *MBC2) saveState(s *stateWriter) {
+	s.write(m.ramEnable, m.romBank)
+}
+
+func (m *MBC2) loadState(s *stateReader) {
+	s.read(&m.ramEnable, &m.romBank)
+}
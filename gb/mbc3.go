@@ -0,0 +1,153 @@
+package gb
+
+/*
+
+MBC3
+
+	0000 - 1FFF RAM and RTC enable, 0x0A in the lower nibble enables them
+	2000 - 3FFF ROM bank number, 7 bits (8 bits on MBC30), 0 is treated as 1
+	4000 - 5FFF RAM bank number (0x00 - 0x03, up to 0x07 on MBC30) or RTC register select (0x08 - 0x0C)
+	6000 - 7FFF Latch clock data, writing 0x00 then 0x01 latches the RTC
+
+*/
+
+type MBC3 struct {
+	cart      *Cartridge
+	ramEnable bool
+	romBank   uint8
+	ramBank   uint8 // RAM bank, or RTC register when 0x08 - 0x0C
+	latch     uint8 // last value written to the latch register
+	rtc       *RTC  // nil when the cartridge has no timer
+}
+
+func NewMBC3(cart *Cartridge) *MBC3 {
+	m := &MBC3{
+		cart:    cart,
+		romBank: 1,
+	}
+
+	if cartridgeTypes[cart.Header.Type].rtc {
+		m.rtc = &RTC{}
+	}
+
+	return m
+}
+
+// RTC returns the cartridge's real time clock, or nil if it has none
+func (m *MBC3) RTC() *RTC {
+	return m.rtc
+}
+
+func (m *MBC3) batterySize() int {
+	if m.rtc == nil {
+		return 0
+	}
+	return RTC_SAVE_SIZE
+}
+
+func (m *MBC3) saveBattery() []uint8 {
+	return m.rtc.Save()
+}
+
+func (m *MBC3) loadBattery(data []uint8) error {
+	return m.rtc.Load(data)
+}
+
+// SetClock makes the RTC follow the given wall clock, nil goes back to emulated cycles
+func (m *MBC3) SetClock(clock Clock) {
+	if m.rtc != nil {
+		m.rtc.SetClock(clock)
+	}
+}
+
+// Step runs the RTC off emulated cycles
+func (m *MBC3) Step(ticks int) {
+	if m.rtc != nil {
+		m.rtc.Step(ticks)
+	}
+}
+
+func (m *MBC3) ReadROM(address uint16) uint8 {
+	if address < ROM_BANK_SIZE {
+		return m.cart.rom[address]
+	}
+
+	bank := int(m.romBank) % (len(m.cart.rom) / ROM_BANK_SIZE)
+	return m.cart.rom[bank*ROM_BANK_SIZE+int(address-ROM_BANK_SIZE)]
+}
+
+func (m *MBC3) WriteROM(address uint16, value uint8) {
+	switch {
+	case address < 0x2000:
+		m.ramEnable = value&0x0F == 0x0A
+	case address < 0x4000:
+		// MBC30 carts larger than 2MB use all 8 bits
+		if len(m.cart.rom) <= 0x200000 {
+			value &= 0x7F
+		}
+		m.romBank = value
+		if m.romBank == 0 {
+			m.romBank = 1
+		}
+	case address < 0x6000:
+		m.ramBank = value & 0x0F
+	default:
+		if m.latch == 0x00 && value == 0x01 && m.rtc != nil {
+			m.rtc.Latch()
+		}
+		m.latch = value
+	}
+}
+
+// isRTCSelected reports whether the RTC registers are mapped at 0xA000 instead of RAM
+func (m *MBC3) isRTCSelected() bool {
+	return m.rtc != nil && m.ramBank >= 0x08 && m.ramBank <= 0x0C
+}
+
+func (m *MBC3) ReadRAM(address uint16) uint8 {
+	if !m.ramEnable {
+		return 0xFF
+	}
+
+	if m.isRTCSelected() {
+		return m.rtc.Read(m.ramBank)
+	}
+
+	if m.ramBank > 0x07 || len(m.cart.ram) == 0 {
+		return 0xFF
+	}
+
+	return m.cart.ram[(int(m.ramBank)*RAM_BANK_SIZE+int(address))%len(m.cart.ram)]
+}
+
+func (m *MBC3) WriteRAM(address uint16, value uint8) {
+	if !m.ramEnable {
+		return
+	}
+
+	if m.isRTCSelected() {
+		m.rtc.Write(m.ramBank, value)
+		return
+	}
+
+	if m.ramBank > 0x07 || len(m.cart.ram) == 0 {
+		return
+	}
+
+	m.cart.ram[(int(m.ramBank)*RAM_BANK_SIZE+int(address))%len(m.cart.ram)] = value
+	m.cart.dirty = true
+}
+
+func (m *MBC3) saveState(s *stateWriter) {
+	s.write(m.ramEnable, m.romBank, m.ramBank, m.latch)
+	if m.rtc != nil {
+		m.rtc.saveState(s)
+	}
+}
+
+func (m *MBC3) loadState(s *stateReader) {
+	s.read(&m.ramEnable, &m.romBank, &m.ramBank, &m.latch)
+	if m.rtc != nil {
+		m.rtc.loadState(s)
+	}
+}
@@ -0,0 +1,98 @@
+package gb
+
+/*
+
+MBC5
+
+	0000 - 1FFF RAM enable, 0x0A enables RAM, anything else disables it
+	2000 - 2FFF ROM bank number, lower 8 bits (bank 0 can be selected)
+	3000 - 3FFF ROM bank number, bit 8
+	4000 - 5FFF RAM bank number (0x00 - 0x0F)
+		on rumble carts bit 3 drives the rumble motor instead, leaving 8 RAM banks
+
+*/
+
+type MBC5 struct {
+	cart      *Cartridge
+	ramEnable bool
+	romBank   uint16 // 9-bit ROM bank number
+	ramBank   uint8
+	hasRumble bool
+	rumbling  bool
+	onRumble  func(on bool) // called when the motor turns on or off
+}
+
+func NewMBC5(cart *Cartridge) *MBC5 {
+	return &MBC5{
+		cart:      cart,
+		romBank:   1,
+		hasRumble: cartridgeTypes[cart.Header.Type].rumble,
+	}
+}
+
+// SetRumbleCallback sets the function called when the rumble motor turns on or off
+func (m *MBC5) SetRumbleCallback(callback func(on bool)) {
+	m.onRumble = callback
+}
+
+func (m *MBC5) ReadROM(address uint16) uint8 {
+	if address < ROM_BANK_SIZE {
+		return m.cart.rom[address]
+	}
+
+	bank := int(m.romBank) % (len(m.cart.rom) / ROM_BANK_SIZE)
+	return m.cart.rom[bank*ROM_BANK_SIZE+int(address-ROM_BANK_SIZE)]
+}
+
+func (m *MBC5) WriteROM(address uint16, value uint8) {
+	switch {
+	case address < 0x2000:
+		m.ramEnable = value == 0x0A
+	case address < 0x3000:
+		m.romBank = m.romBank&0x100 | uint16(value)
+	case address < 0x4000:
+		m.romBank = m.romBank&0xFF | uint16(value&0x01)<<8
+	case address < 0x6000:
+		if m.hasRumble {
+			m.setRumble(value&0x08 != 0)
+			value &= 0x07
+		}
+		m.ramBank = value & 0x0F
+	}
+}
+
+func (m *MBC5) setRumble(on bool) {
+	if on == m.rumbling {
+		return
+	}
+
+	m.rumbling = on
+	if m.onRumble != nil {
+		m.onRumble(on)
+	}
+}
+
+func (m *MBC5) ReadRAM(address uint16) uint8 {
+	if !m.ramEnable || len(m.cart.ram) == 0 {
+		return 0xFF
+	}
+	return m.cart.ram[(int(m.ramBank)*RAM_BANK_SIZE+int(address))%len(m.cart.ram)]
+}
+
+func (m *MBC5) WriteRAM(address uint16, value uint8) {
+	if !m.ramEnable || len(m.cart.ram) == 0 {
+		return
+	}
+	m.cart.ram[(int(m.ramBank)*RAM_BANK_SIZE+int(address))%len(m.cart.ram)] = value
+	m.cart.dirty = true
+}
+
+func (m *MBC5) saveState(s *stateWriter) {
+	s.write(m.ramEnable, m.romBank, m.ramBank, m.rumbling)
+}
+
+func (m *MBC5) loadState(s *stateReader) {
+	var rumbling bool
+	s.read(&m.ramEnable, &m.romBank, &m.ramBank, &rumbling)
+	m.setRumble(rumbling)
+}
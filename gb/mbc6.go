@@ -0,0 +1,238 @@
+package gb
+
+import (
+	"fmt"
+)
+
+/*
+
+MBC6 (Net de Get)
+
+ROM and RAM are split into two independently banked halves each:
+
+	4000 - 5FFF ROM/Flash bank A (8KB banks)
+	6000 - 7FFF ROM/Flash bank B (8KB banks)
+	A000 - AFFF RAM bank A (4KB banks)
+	B000 - BFFF RAM bank B (4KB banks)
+
+Registers
+
+	0000 - 03FF RAM enable, 0x0A enables RAM
+	0400 - 07FF RAM bank A number
+	0800 - 0BFF RAM bank B number
+	0C00 - 0FFF Flash enable (bit 0)
+	1000        Flash write enable (bit 0)
+	2000 - 27FF ROM/Flash bank A number
+	2800 - 2FFF ROM/Flash select A, 0x08 selects flash
+	3000 - 37FF ROM/Flash bank B number
+	3800 - 3FFF ROM/Flash select B, 0x08 selects flash
+
+Flash
+
+The 1MB flash chip takes JEDEC style commands, written through a flash selected bank while flash
+and flash writes are enabled. Addresses are offsets into the flash, commands start with
+0xAA to 5555 and 0x55 to 2AAA, followed by the command byte to 5555:
+
+	A0 Program the next byte written, bits can only be cleared
+	80 Erase, followed by 0xAA to 5555, 0x55 to 2AAA, then 0x10 to 5555 erases the chip,
+	   or 0x30 to an address erases its 128KB sector
+	90 ID mode, reads return the manufacturer (0xC2) and device (0x81) IDs
+	F0 Reset back to reading the flash, written on its own as well
+
+Programming and erasing finish instantly. The flash is battery backed, it's saved after the RAM.
+
+*/
+
+const MBC6_ROM_BANK_SIZE = 0x2000
+const MBC6_RAM_BANK_SIZE = 0x1000
+const MBC6_RAM_SIZE = 0x8000
+const MBC6_FLASH_SIZE = 0x100000
+const MBC6_FLASH_SECTOR_SIZE = 0x20000
+
+// flash command states, the number of command bytes accepted so far
+const (
+	FLASH_READ = iota
+	FLASH_UNLOCK1
+	FLASH_UNLOCK2
+	FLASH_PROGRAM
+	FLASH_ERASE
+	FLASH_ERASE_UNLOCK1
+	FLASH_ERASE_UNLOCK2
+	FLASH_ID
+)
+
+type MBC6 struct {
+	cart        *Cartridge
+	ramEnable   bool
+	ramBanks    [2]uint8
+	romBanks    [2]uint8
+	flashSelect [2]bool
+	flashEnable bool
+	flashWrite  bool
+	flash       []uint8
+	flashState  uint8 // FLASH_* command state
+}
+
+func NewMBC6(cart *Cartridge) *MBC6 {
+	if len(cart.ram) < MBC6_RAM_SIZE {
+		cart.ram = make([]uint8, MBC6_RAM_SIZE)
+	}
+
+	m := &MBC6{
+		cart:  cart,
+		flash: make([]uint8, MBC6_FLASH_SIZE),
+	}
+	for i := range m.flash {
+		m.flash[i] = 0xFF
+	}
+
+	return m
+}
+
+func (m *MBC6) ReadROM(address uint16) uint8 {
+	if address < ROM_BANK_SIZE {
+		return m.cart.rom[address]
+	}
+
+	// bank A or bank B
+	half := int(address-ROM_BANK_SIZE) / MBC6_ROM_BANK_SIZE
+	offset := int(m.romBanks[half])*MBC6_ROM_BANK_SIZE + int(address)%MBC6_ROM_BANK_SIZE
+
+	if m.flashSelect[half] {
+		if !m.flashEnable {
+			return 0xFF
+		}
+		if m.flashState == FLASH_ID {
+			return []uint8{0xC2, 0x81}[offset&0x01]
+		}
+		return m.flash[offset%len(m.flash)]
+	}
+
+	return m.cart.rom[offset%len(m.cart.rom)]
+}
+
+func (m *MBC6) WriteROM(address uint16, value uint8) {
+	switch {
+	case address < 0x0400:
+		m.ramEnable = value == 0x0A
+	case address < 0x0800:
+		m.ramBanks[0] = value & 0x07
+	case address < 0x0C00:
+		m.ramBanks[1] = value & 0x07
+	case address < 0x1000:
+		m.flashEnable = value&0x01 != 0
+	case address == 0x1000:
+		m.flashWrite = value&0x01 != 0
+	case address < 0x2000:
+		// unused
+	case address >= ROM_BANK_SIZE:
+		half := int(address-ROM_BANK_SIZE) / MBC6_ROM_BANK_SIZE
+		if m.flashSelect[half] && m.flashEnable && m.flashWrite {
+			offset := int(m.romBanks[half])*MBC6_ROM_BANK_SIZE + int(address)%MBC6_ROM_BANK_SIZE
+			m.flashCommand(offset%len(m.flash), value)
+		}
+	case address < 0x2800:
+		m.romBanks[0] = value
+	case address < 0x3000:
+		m.flashSelect[0] = value == 0x08
+	case address < 0x3800:
+		m.romBanks[1] = value
+	default:
+		m.flashSelect[1] = value == 0x08
+	}
+}
+
+func (m *MBC6) ramAddress(address uint16) int {
+	half := int(address) / MBC6_RAM_BANK_SIZE
+	return (int(m.ramBanks[half])*MBC6_RAM_BANK_SIZE + int(address)%MBC6_RAM_BANK_SIZE) % len(m.cart.ram)
+}
+
+func (m *MBC6) ReadRAM(address uint16) uint8 {
+	if !m.ramEnable {
+		return 0xFF
+	}
+	return m.cart.ram[m.ramAddress(address)]
+}
+
+func (m *MBC6) WriteRAM(address uint16, value uint8) {
+	if !m.ramEnable {
+		return
+	}
+	m.cart.ram[m.ramAddress(address)] = value
+	m.cart.dirty = true
+}
+
+// flashCommand takes a write to the flash at offset, stepping through the command sequences
+func (m *MBC6) flashCommand(offset int, value uint8) {
+	command := offset & 0x7FFF
+
+	// reset from any state, except as the byte to program
+	if value == 0xF0 && m.flashState != FLASH_PROGRAM {
+		m.flashState = FLASH_READ
+		return
+	}
+
+	switch {
+	case m.flashState == FLASH_PROGRAM:
+		m.flash[offset] &= value
+		m.cart.dirty = true
+		m.flashState = FLASH_READ
+	case (m.flashState == FLASH_READ || m.flashState == FLASH_ID) && command == 0x5555 && value == 0xAA:
+		m.flashState = FLASH_UNLOCK1
+	case m.flashState == FLASH_UNLOCK1 && command == 0x2AAA && value == 0x55:
+		m.flashState = FLASH_UNLOCK2
+	case m.flashState == FLASH_UNLOCK2 && command == 0x5555 && value == 0xA0:
+		m.flashState = FLASH_PROGRAM
+	case m.flashState == FLASH_UNLOCK2 && command == 0x5555 && value == 0x80:
+		m.flashState = FLASH_ERASE
+	case m.flashState == FLASH_UNLOCK2 && command == 0x5555 && value == 0x90:
+		m.flashState = FLASH_ID
+	case m.flashState == FLASH_ERASE && command == 0x5555 && value == 0xAA:
+		m.flashState = FLASH_ERASE_UNLOCK1
+	case m.flashState == FLASH_ERASE_UNLOCK1 && command == 0x2AAA && value == 0x55:
+		m.flashState = FLASH_ERASE_UNLOCK2
+	case m.flashState == FLASH_ERASE_UNLOCK2 && command == 0x5555 && value == 0x10:
+		m.erase(0, len(m.flash))
+	case m.flashState == FLASH_ERASE_UNLOCK2 && value == 0x30:
+		sector := offset / MBC6_FLASH_SECTOR_SIZE * MBC6_FLASH_SECTOR_SIZE
+		m.erase(sector, sector+MBC6_FLASH_SECTOR_SIZE)
+	case m.flashState != FLASH_ID:
+		// a broken sequence goes back to reading
+		m.flashState = FLASH_READ
+	}
+}
+
+// erase sets the flash from start to end back to 0xFF
+func (m *MBC6) erase(start int, end int) {
+	for i := start; i < end; i++ {
+		m.flash[i] = 0xFF
+	}
+	m.cart.dirty = true
+	m.flashState = FLASH_READ
+}
+
+func (m *MBC6) batterySize() int {
+	return MBC6_FLASH_SIZE
+}
+
+func (m *MBC6) saveBattery() []uint8 {
+	return append([]uint8{}, m.flash...)
+}
+
+func (m *MBC6) loadBattery(data []uint8) error {
+	if len(data) != MBC6_FLASH_SIZE {
+		return fmt.Errorf("mbc6 flash save is %d bytes, expected %d", len(data), MBC6_FLASH_SIZE)
+	}
+	copy(m.flash, data)
+	return nil
+}
+
+func (m *MBC6) saveState(s *stateWriter) {
+	s.write(m.ramEnable, m.ramBanks, m.romBanks, m.flashSelect, m.flashEnable, m.flashWrite)
+	s.write(m.flash, m.flashState)
+}
+
+func (m *MBC6) loadState(s *stateReader) {
+	s.read(&m.ramEnable, &m.ramBanks, &m.romBanks, &m.flashSelect, &m.flashEnable, &m.flashWrite)
+	s.read(m.flash, &m.flashState)
+}
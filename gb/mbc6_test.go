@@ -0,0 +1,92 @@
+package gb
+
+import "testing"
+
+// flashWrite is a write to an offset into the MBC6 flash
+type flashWrite struct {
+	offset int
+	value  uint8
+}
+
+// newFlashMBC6 creates an MBC6 with flash and flash writes enabled, mapped through bank A
+func newFlashMBC6() *MBC6 {
+	m := NewMBC6(bankedCart(8, 0))
+	writeMapper(m, []mapperWrite{{0x0C00, 0x01}, {0x1000, 0x01}, {0x2800, 0x08}})
+	return m
+}
+
+// flashOffset maps an offset into the flash at 4000 - 5FFF, returning the address
+func flashOffset(m *MBC6, offset int) uint16 {
+	m.WriteROM(0x2000, uint8(offset/MBC6_ROM_BANK_SIZE))
+	return ROM_BANK_SIZE + uint16(offset%MBC6_ROM_BANK_SIZE)
+}
+
+func flashCommand(command uint8) []flashWrite {
+	return []flashWrite{{0x5555, 0xAA}, {0x2AAA, 0x55}, {0x5555, command}}
+}
+
+func flashProgram(offset int, value uint8) []flashWrite {
+	return append(flashCommand(0xA0), flashWrite{offset, value})
+}
+
+func flashErase(last flashWrite) []flashWrite {
+	return append(flashCommand(0x80), flashWrite{0x5555, 0xAA}, flashWrite{0x2AAA, 0x55}, last)
+}
+
+func flashSequence(sequences ...[]flashWrite) []flashWrite {
+	var writes []flashWrite
+	for _, sequence := range sequences {
+		writes = append(writes, sequence...)
+	}
+	return writes
+}
+
+func TestFlash(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []flashWrite
+		reads  map[int]uint8 // offset to the byte read
+	}{
+		{"erased", nil, map[int]uint8{0x00000: 0xFF, 0xFFFFF: 0xFF}},
+		{"program", flashProgram(0x12345, 0x3C), map[int]uint8{0x12344: 0xFF, 0x12345: 0x3C, 0x12346: 0xFF}},
+		{"program clears bits only", flashSequence(flashProgram(0x12345, 0x3C), flashProgram(0x12345, 0xF0)), map[int]uint8{0x12345: 0x30}},
+		{"write without command", []flashWrite{{0x12345, 0x3C}}, map[int]uint8{0x12345: 0xFF}},
+		{"broken sequence", []flashWrite{{0x5555, 0xAA}, {0x2AAA, 0x12}, {0x5555, 0xA0}, {0x12345, 0x3C}}, map[int]uint8{0x12345: 0xFF}},
+		{"reset", flashSequence(flashCommand(0xA0)[:2], []flashWrite{{0x0000, 0xF0}}, flashProgram(0x12345, 0x3C)), map[int]uint8{0x12345: 0x3C}},
+		{"sector erase", flashSequence(flashProgram(0x12345, 0x00), flashProgram(0x40000, 0x00), flashErase(flashWrite{0x10000, 0x30})),
+			map[int]uint8{0x12345: 0xFF, 0x40000: 0x00}},
+		{"chip erase", flashSequence(flashProgram(0x12345, 0x00), flashProgram(0x40000, 0x00), flashErase(flashWrite{0x5555, 0x10})),
+			map[int]uint8{0x12345: 0xFF, 0x40000: 0xFF}},
+		{"ID", flashCommand(0x90), map[int]uint8{0x00000: 0xC2, 0x00001: 0x81, 0x12345: 0x81}},
+		{"ID reset", flashSequence(flashCommand(0x90), []flashWrite{{0x0000, 0xF0}}), map[int]uint8{0x00000: 0xFF, 0x00001: 0xFF}},
+	}
+
+	for _, test := range tests {
+		m := newFlashMBC6()
+		for _, w := range test.writes {
+			m.WriteROM(flashOffset(m, w.offset), w.value)
+		}
+
+		for offset, want := range test.reads {
+			if got := m.ReadROM(flashOffset(m, offset)); got != want {
+				t.Errorf("%s: flash %05X = %02X, want %02X", test.name, offset, got, want)
+			}
+		}
+	}
+}
+
+func TestFlashWriteProtect(t *testing.T) {
+	m := newFlashMBC6()
+	m.WriteROM(0x1000, 0x00)
+	for _, w := range flashProgram(0x12345, 0x3C) {
+		m.WriteROM(flashOffset(m, w.offset), w.value)
+	}
+
+	m.WriteROM(0x1000, 0x01)
+	if got := m.ReadROM(flashOffset(m, 0x12345)); got != 0xFF {
+		t.Errorf("flash programmed with writes disabled, %02X", got)
+	}
+	if m.cart.dirty {
+		t.Error("RAM dirty without a flash write")
+	}
+}
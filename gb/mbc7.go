@@ -0,0 +1,354 @@
+package gb
+
+/*
+
+MBC7 (Kirby Tilt 'n' Tumble, Command Master)
+
+	0000 - 1FFF RAM enable 1, 0x0A enables
+	2000 - 3FFF ROM bank number
+	4000 - 5FFF RAM enable 2, 0x40 enables
+	A000 - AFFF Registers, only mapped when both enables are set, selected by bits 4-7 of the address
+		Ax0x Write 0x55 to erase the latched accelerometer values
+		Ax1x Write 0xAA to latch the accelerometer, only after an erase
+		Ax2x Accelerometer X, low byte
+		Ax3x Accelerometer X, high byte
+		Ax4x Accelerometer Y, low byte
+		Ax5x Accelerometer Y, high byte
+		Ax6x Unused, reads 0x00
+		Ax7x Unused, reads 0xFF
+		Ax8x EEPROM pins
+			Bit 7 - CS  (chip select)
+			Bit 6 - CLK (clock)
+			Bit 1 - DI  (data in)
+			Bit 0 - DO  (data out, read only)
+	B000 - BFFF Unmapped, reads 0xFF
+
+The accelerometer reads 0x81D0 when level, moving by about 0x70 per g of tilt.
+Tilt comes from the joypad, see Joypad.SetTilt.
+
+*/
+
+const MBC7_EEPROM_SIZE = 0x100
+
+const MBC7_ACCEL_CENTER = 0x81D0
+const MBC7_ACCEL_G = 0x70
+
+type MBC7 struct {
+	cart       *Cartridge
+	ramEnable1 bool
+	ramEnable2 bool
+	romBank    uint8
+	latched    bool   // accelerometer latched since the last erase
+	x, y       uint16 // latched accelerometer values
+	tilt       func() (x, y float64)
+	eeprom     EEPROM
+}
+
+func NewMBC7(cart *Cartridge) *MBC7 {
+	// the EEPROM holds the save, the header lists no RAM
+	if len(cart.ram) != MBC7_EEPROM_SIZE {
+		cart.ram = make([]uint8, MBC7_EEPROM_SIZE)
+	}
+
+	return &MBC7{
+		cart:    cart,
+		romBank: 1,
+		x:       0x8000,
+		y:       0x8000,
+		eeprom:  EEPROM{data: cart.ram, dirty: &cart.dirty},
+	}
+}
+
+// SetTiltSource sets the function the accelerometer reads the tilt from when latched
+func (m *MBC7) SetTiltSource(tilt func() (x, y float64)) {
+	m.tilt = tilt
+}
+
+func (m *MBC7) ReadROM(address uint16) uint8 {
+	if address < ROM_BANK_SIZE {
+		return m.cart.rom[address]
+	}
+
+	bank := int(m.romBank) % (len(m.cart.rom) / ROM_BANK_SIZE)
+	return m.cart.rom[bank*ROM_BANK_SIZE+int(address-ROM_BANK_SIZE)]
+}
+
+func (m *MBC7) WriteROM(address uint16, value uint8) {
+	switch {
+	case address < 0x2000:
+		m.ramEnable1 = value == 0x0A
+		if !m.ramEnable1 {
+			m.ramEnable2 = false
+		}
+	case address < 0x4000:
+		m.romBank = value
+	case address < 0x6000:
+		m.ramEnable2 = m.ramEnable1 && value == 0x40
+	}
+}
+
+func (m *MBC7) ReadRAM(address uint16) uint8 {
+	if !m.ramEnable1 || !m.ramEnable2 || address >= 0x1000 {
+		return 0xFF
+	}
+
+	switch address >> 4 & 0x0F {
+	case 0x2:
+		return uint8(m.x)
+	case 0x3:
+		return uint8(m.x >> 8)
+	case 0x4:
+		return uint8(m.y)
+	case 0x5:
+		return uint8(m.y >> 8)
+	case 0x6:
+		return 0x00
+	case 0x8:
+		return m.eeprom.Read()
+	default:
+		return 0xFF
+	}
+}
+
+func (m *MBC7) WriteRAM(address uint16, value uint8) {
+	if !m.ramEnable1 || !m.ramEnable2 || address >= 0x1000 {
+		return
+	}
+
+	switch address >> 4 & 0x0F {
+	case 0x0:
+		if value == 0x55 {
+			m.latched = false
+			m.x = 0x8000
+			m.y = 0x8000
+		}
+	case 0x1:
+		if value == 0xAA && !m.latched {
+			m.latchAccelerometer()
+		}
+	case 0x8:
+		m.eeprom.Write(value)
+	}
+}
+
+func (m *MBC7) latchAccelerometer() {
+	m.latched = true
+
+	var x, y float64
+	if m.tilt != nil {
+		x, y = m.tilt()
+	}
+
+	m.x = uint16(MBC7_ACCEL_CENTER + int(x*MBC7_ACCEL_G))
+	m.y = uint16(MBC7_ACCEL_CENTER + int(y*MBC7_ACCEL_G))
+}
+
+func (m *MBC7) saveState(s *stateWriter) {
+	s.write(m.ramEnable1, m.ramEnable2, m.romBank, m.latched, m.x, m.y)
+	m.eeprom.saveState(s)
+}
+
+func (m *MBC7) loadState(s *stateReader) {
+	s.read(&m.ramEnable1, &m.ramEnable2, &m.romBank, &m.latched, &m.x, &m.y)
+	m.eeprom.loadState(s)
+}
+
+// <----------------------------- EEPROM -----------------------------> //
+
+/*
+
+93LC56 serial EEPROM, 128 16-bit words, used by MBC7 for saves
+
+Commands are clocked in on the rising edge of CLK while CS is high, a 1 start bit,
+a 2-bit opcode and an 8-bit address (the top address bit is ignored), most significant bit first.
+
+	READ   10 AAAAAAAA            Shifts out a dummy 0 then the word, continuing with the next word
+	WRITE  01 AAAAAAAA + 16 bits  Writes the word
+	ERASE  11 AAAAAAAA            Sets the word to 0xFFFF
+	EWEN   00 11xxxxxx            Enables writes and erases
+	EWDS   00 00xxxxxx            Disables writes and erases
+	WRAL   00 01xxxxxx + 16 bits  Writes every word
+	ERAL   00 10xxxxxx            Sets every word to 0xFFFF
+
+Writes and erases complete instantly, DO reads 1 (ready) afterwards. Words are stored big endian.
+
+*/
+
+const (
+	EEPROM_IDLE    = iota // waiting for a start bit
+	EEPROM_COMMAND        // shifting in opcode and address
+	EEPROM_DATA           // shifting in 16 data bits for WRITE or WRAL
+	EEPROM_READ           // shifting out words
+)
+
+type EEPROM struct {
+	data         []uint8 // 256 bytes, the cartridge RAM
+	dirty        *bool   // the cartridge's flag, set when data changes
+	state        uint8
+	cs, clk, di  bool
+	do           bool
+	shift        uint16 // bits being shifted in or out
+	bits         uint8  // bits shifted so far
+	command      uint16 // opcode and address of the current command
+	writeEnabled bool
+}
+
+// Read returns the pins, only DO is driven by the EEPROM
+func (e *EEPROM) Read() uint8 {
+	var value uint8
+	if e.cs {
+		value |= 0x80
+	}
+	if e.clk {
+		value |= 0x40
+	}
+	if e.di {
+		value |= 0x02
+	}
+	if e.do {
+		value |= 0x01
+	}
+	return value
+}
+
+// Write sets CS, CLK and DI, clocking a bit on the rising edge of CLK
+func (e *EEPROM) Write(value uint8) {
+	cs := value&0x80 != 0
+	clk := value&0x40 != 0
+	e.di = value&0x02 != 0
+
+	// deselecting the chip aborts the current command
+	if !cs {
+		e.state = EEPROM_IDLE
+	}
+
+	if cs && clk && !e.clk {
+		e.clock()
+	}
+
+	e.cs = cs
+	e.clk = clk
+}
+
+func (e *EEPROM) clock() {
+	switch e.state {
+	case EEPROM_IDLE:
+		if e.di {
+			e.state = EEPROM_COMMAND
+			e.shift = 0
+			e.bits = 0
+		}
+	case EEPROM_COMMAND:
+		e.shiftIn()
+		if e.bits == 10 {
+			e.command = e.shift
+			e.execute()
+		}
+	case EEPROM_DATA:
+		e.shiftIn()
+		if e.bits == 16 {
+			e.writeData(e.shift)
+		}
+	case EEPROM_READ:
+		e.do = e.shift&0x8000 != 0
+		e.shift <<= 1
+		e.bits++
+		// sequential read, move on to the next word
+		if e.bits == 16 {
+			e.command = e.command&0x300 | (e.command+1)&0x7F
+			e.loadWord()
+		}
+	}
+}
+
+func (e *EEPROM) shiftIn() {
+	e.shift <<= 1
+	if e.di {
+		e.shift |= 1
+	}
+	e.bits++
+}
+
+func (e *EEPROM) address() int {
+	return int(e.command & 0x7F)
+}
+
+func (e *EEPROM) loadWord() {
+	address := e.address()
+	e.shift = uint16(e.data[address*2])<<8 | uint16(e.data[address*2+1])
+	e.bits = 0
+}
+
+// execute runs the command once its opcode and address have been shifted in
+func (e *EEPROM) execute() {
+	switch e.command >> 8 {
+	case 0x2: // READ
+		e.state = EEPROM_READ
+		e.do = false
+		e.loadWord()
+	case 0x1: // WRITE
+		e.state = EEPROM_DATA
+		e.shift = 0
+		e.bits = 0
+	case 0x3: // ERASE
+		if e.writeEnabled {
+			e.setWord(e.address(), 0xFFFF)
+		}
+		e.finish()
+	case 0x0:
+		switch e.command >> 6 & 0x03 {
+		case 0x3: // EWEN
+			e.writeEnabled = true
+			e.finish()
+		case 0x0: // EWDS
+			e.writeEnabled = false
+			e.finish()
+		case 0x1: // WRAL
+			e.state = EEPROM_DATA
+			e.shift = 0
+			e.bits = 0
+		case 0x2: // ERAL
+			if e.writeEnabled {
+				for address := 0; address < MBC7_EEPROM_SIZE/2; address++ {
+					e.setWord(address, 0xFFFF)
+				}
+			}
+			e.finish()
+		}
+	}
+}
+
+func (e *EEPROM) writeData(value uint16) {
+	if e.writeEnabled {
+		if e.command>>8 == 0x1 {
+			e.setWord(e.address(), value)
+		} else {
+			for address := 0; address < MBC7_EEPROM_SIZE/2; address++ {
+				e.setWord(address, value)
+			}
+		}
+	}
+	e.finish()
+}
+
+func (e *EEPROM) setWord(address int, value uint16) {
+	e.data[address*2] = uint8(value >> 8)
+	e.data[address*2+1] = uint8(value)
+	*e.dirty = true
+}
+
+// finish ends a command, DO reports ready
+func (e *EEPROM) finish() {
+	e.state = EEPROM_IDLE
+	e.do = true
+}
+
+// the contents are the cartridge RAM, saved with the cartridge
+
+func (e *EEPROM) saveState(s *stateWriter) {
+	s.write(e.state, e.cs, e.clk, e.di, e.do, e.shift, e.bits, e.command, e.writeEnabled)
+}
+
+func (e *EEPROM) loadState(s *stateReader) {
+	s.read(&e.state, &e.cs, &e.clk, &e.di, &e.do, &e.shift, &e.bits, &e.command, &e.writeEnabled)
+}
@@ -0,0 +1,136 @@
+package gb
+
+import "testing"
+
+// eepromCommand selects the EEPROM and clocks in a start bit followed by the bits of a command, most significant first
+func eepromCommand(e *EEPROM, command uint32, bits int) {
+	e.Write(0x80)
+	for i := bits - 1; i >= 0; i-- {
+		di := uint8(command>>uint(i)&0x01) << 1
+		e.Write(0x80 | di)
+		e.Write(0xC0 | di)
+	}
+}
+
+// eepromRun runs a command and deselects the EEPROM, command holds the start bit, opcode and address
+func eepromRun(e *EEPROM, command uint32) {
+	eepromCommand(e, command, 11)
+	e.Write(0x00)
+}
+
+// eepromRunData runs a command followed by a data word
+func eepromRunData(e *EEPROM, command uint32, value uint16) {
+	eepromCommand(e, command<<16|uint32(value), 27)
+	e.Write(0x00)
+}
+
+// eepromRead reads the word at the address with READ
+func eepromRead(e *EEPROM, address uint8) uint16 {
+	eepromCommand(e, 0x600|uint32(address), 11)
+
+	var value uint16
+	for i := 0; i < 16; i++ {
+		e.Write(0x80)
+		e.Write(0xC0)
+		value = value<<1 | uint16(e.Read()&0x01)
+	}
+	e.Write(0x00)
+	return value
+}
+
+const (
+	eepromEWEN  = 0x4C0
+	eepromEWDS  = 0x400
+	eepromWRAL  = 0x440
+	eepromERAL  = 0x480
+	eepromWRITE = 0x500 // | address
+	eepromERASE = 0x700 // | address
+)
+
+func TestEEPROM(t *testing.T) {
+	tests := []struct {
+		name  string
+		run   func(e *EEPROM)
+		words map[uint8]uint16 // address to the word read back
+	}{
+		{"WRITE disabled", func(e *EEPROM) {
+			eepromRunData(e, eepromWRITE|0x05, 0x1234)
+		}, map[uint8]uint16{0x05: 0x0000}},
+		{"EWEN WRITE", func(e *EEPROM) {
+			eepromRun(e, eepromEWEN)
+			eepromRunData(e, eepromWRITE|0x05, 0x1234)
+		}, map[uint8]uint16{0x04: 0x0000, 0x05: 0x1234, 0x06: 0x0000}},
+		{"address bit 7 ignored", func(e *EEPROM) {
+			eepromRun(e, eepromEWEN)
+			eepromRunData(e, eepromWRITE|0x85, 0x1234)
+		}, map[uint8]uint16{0x05: 0x1234}},
+		{"EWDS", func(e *EEPROM) {
+			eepromRun(e, eepromEWEN)
+			eepromRun(e, eepromEWDS)
+			eepromRunData(e, eepromWRITE|0x05, 0x1234)
+		}, map[uint8]uint16{0x05: 0x0000}},
+		{"ERASE", func(e *EEPROM) {
+			eepromRun(e, eepromEWEN)
+			eepromRunData(e, eepromWRITE|0x05, 0x1234)
+			eepromRunData(e, eepromWRITE|0x06, 0x5678)
+			eepromRun(e, eepromERASE|0x05)
+		}, map[uint8]uint16{0x05: 0xFFFF, 0x06: 0x5678}},
+		{"ERAL", func(e *EEPROM) {
+			eepromRun(e, eepromEWEN)
+			eepromRunData(e, eepromWRITE|0x05, 0x1234)
+			eepromRun(e, eepromERAL)
+		}, map[uint8]uint16{0x00: 0xFFFF, 0x05: 0xFFFF, 0x7F: 0xFFFF}},
+		{"ERAL disabled", func(e *EEPROM) {
+			eepromRun(e, eepromERAL)
+		}, map[uint8]uint16{0x00: 0x0000, 0x7F: 0x0000}},
+		{"WRAL", func(e *EEPROM) {
+			eepromRun(e, eepromEWEN)
+			eepromRunData(e, eepromWRAL, 0xABCD)
+		}, map[uint8]uint16{0x00: 0xABCD, 0x40: 0xABCD, 0x7F: 0xABCD}},
+		{"aborted WRITE", func(e *EEPROM) {
+			eepromRun(e, eepromEWEN)
+			eepromCommand(e, eepromWRITE|0x05, 11)
+			e.Write(0x00)
+			eepromRunData(e, eepromWRITE|0x06, 0x5678)
+		}, map[uint8]uint16{0x05: 0x0000, 0x06: 0x5678}},
+	}
+
+	for _, test := range tests {
+		var dirty bool
+		e := &EEPROM{data: make([]uint8, MBC7_EEPROM_SIZE), dirty: &dirty}
+		test.run(e)
+
+		for address, want := range test.words {
+			if got := eepromRead(e, address); got != want {
+				t.Errorf("%s: word %02X = %04X, want %04X", test.name, address, got, want)
+			}
+		}
+	}
+}
+
+func TestEEPROMSequentialRead(t *testing.T) {
+	var dirty bool
+	e := &EEPROM{data: make([]uint8, MBC7_EEPROM_SIZE), dirty: &dirty}
+	eepromRun(e, eepromEWEN)
+	eepromRunData(e, eepromWRITE|0x7F, 0x1234)
+	eepromRunData(e, eepromWRITE|0x00, 0x5678)
+
+	if !dirty {
+		t.Error("WRITE didn't mark the RAM dirty")
+	}
+	if e.Read()&0x01 == 0 {
+		t.Error("DO not ready after WRITE")
+	}
+
+	// READ keeps going with the next word, wrapping around
+	eepromCommand(e, 0x600|0x7F, 11)
+	var value uint32
+	for i := 0; i < 32; i++ {
+		e.Write(0x80)
+		e.Write(0xC0)
+		value = value<<1 | uint32(e.Read()&0x01)
+	}
+	if value != 0x12345678 {
+		t.Errorf("sequential read = %08X, want 12345678", value)
+	}
+}
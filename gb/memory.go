@@ -1,5 +1,9 @@
 package gb
 
+import (
+	"errors"
+)
+
 /*
 
 GameBoy Memory Areas
@@ -7,16 +11,16 @@ GameBoy Memory Areas
 0000 – 3FFF ROM0 Non-switchable ROM Bank.
 4000 – 7FFF ROMX Switchable ROM bank.
 
-8000 – 9FFF VRAM Video RAM, switchable (0-1) in GBC mode.
+8000 – 9FFF VRAM Video RAM, switchable (0-1) in GBC mode through VBK (FF4F).
 
 A000 – BFFF SRAM External RAM in cartridge, often battery buffered.
 C000 – CFFF WRAM0 Work RAM.
-D000 – DFFF WRAMX Work RAM, switchable (1-7) in GBC mode
+D000 – DFFF WRAMX Work RAM, switchable (1-7) in GBC mode through SVBK (FF70).
 
-E000 – FDFF ECHO Description of the behaviour below.
+E000 – FDFF ECHO Mirror of C000 – DDFF, reads and writes go to WRAM (in the bank SVBK selects).
 
 FE00 – FE9F OAM (Object Attribute Table) Sprite information table.
-FEA0 – FEFF UNUSED Description of the behaviour below.
+FEA0 – FEFF UNUSED Writes are ignored, reads return 0.
 FF00 – FF7F I/O Registers I/O registers are mapped here.
 FF80 – FFFE HRAM Internal CPU RAM
 
@@ -28,51 +32,74 @@ FFFF - IE Register Interrupt enable flags.
 // 64kb memory map
 
 type MemoryMap struct {
-	console *Console      // not sure if we need this? for access to other parts of console
-	rom     [0x8000]uint8 // diff between this and cartidge.go? may need to replace this with cartridge.go
-	vram    [0x2000]uint8
-	sram    [0x2000]uint8
-	wram    [0x2000]uint8
-	oam     [0x100]uint8
-	hram    [0x80]uint8
-	io      [0x100]uint8
+	console *Console                           // for access to other parts of console
+	cart    *Cartridge                         // inserted cartridge, provides rom and external ram (sram)
+	vram    [2 * VRAM_BANK_SIZE]uint8          // bank 1 only in CGB mode
+	wram    [WRAM_BANKS * WRAM_BANK_SIZE]uint8 // banks 2-7 only in CGB mode
+	oam     [0xA0]uint8
+	hram    [0x7F]uint8
+	io      [0x80]uint8
+	ie      uint8   // interrupt enable register
+	boot    []uint8 // boot ROM, nil when starting without one
+	booting bool    // boot ROM mapped, until it unmaps itself through 0xFF50
 }
 
+// End of each memory area (exclusive), which is also the start of the next area
+
 const ROM_END = 0x8000
 const VRAM_END = 0xA000
 const SRAM_END = 0xC000
-const WRAM_END = 0xD000
+const WRAM_END = 0xE000
 const ECHO_END = 0xFE00
 const OAM_END = 0xFEA0
 const UNUSED_END = 0xFF00
 const IO_END = 0xFF80
 const HRAM_END = 0xFFFF
 
+const KEY1_ADDRESS = 0xFF4D // CGB speed switch
+const VBK_ADDRESS = 0xFF4F  // CGB VRAM bank
+const SVBK_ADDRESS = 0xFF70 // CGB WRAM bank
+
+const VRAM_BANK_SIZE = 0x2000
+const WRAM_BANK_SIZE = 0x1000
+const WRAM_BANKS = 8
+
 // Reads and Writes, take in any 16-bit address and delegate to the correct memory area
 
 // Write an 8-bit value to the address
 func (mem *MemoryMap) Write8(address uint16, value uint8) {
 	switch {
 	case address < ROM_END:
-		// rom
+		// rom, read only
+		mem.cart.WriteROM(address, value)
 	case address < VRAM_END:
-		// vram
+		// vram, in the bank VBK selects
+		mem.vram[mem.vramBank()+address-ROM_END] = value
 	case address < SRAM_END:
 		// sram
+		mem.cart.WriteRAM(address-VRAM_END, value)
 	case address < WRAM_END:
 		// wram
+		mem.wram[mem.wramAddress(address-SRAM_END)] = value
 	case address < ECHO_END:
-		// echo
+		// echo, mirrors the start of wram
+		mem.wram[mem.wramAddress(address-WRAM_END)] = value
 	case address < OAM_END:
 		// oam
+		mem.console.ppu.oamBugWrite(address)
+		mem.oam[address-ECHO_END] = value
 	case address < UNUSED_END:
-		// unused
+		// unused, writes are ignored but still corrupt oam
+		mem.console.ppu.oamBugWrite(address)
 	case address < IO_END:
 		// io
+		mem.writeIO(address, value)
 	case address < HRAM_END:
-	// hram
-	case address == 0xFFFF:
-		// interrupt flag
+		// hram
+		mem.hram[address-IO_END] = value
+	case address == IE_ADDRESS:
+		// interrupt enable
+		mem.ie = value
 	default:
 		panic("Invalid memory address")
 	}
@@ -82,44 +109,158 @@ func (mem *MemoryMap) Write8(address uint16, value uint8) {
 func (mem *MemoryMap) Read8(address uint16) uint8 {
 	switch {
 	case address < ROM_END:
+		// boot rom, until it unmaps itself
+		if value, ok := mem.readBoot(address); ok {
+			return value
+		}
 		// cart
-		return mem.rom[address]
+		return mem.cart.ReadROM(address)
 	case address < VRAM_END:
-		// vram
-		return mem.vram[address-ROM_END]
+		// vram, in the bank VBK selects
+		return mem.vram[mem.vramBank()+address-ROM_END]
 	case address < SRAM_END:
 		// sram
-		return mem.sram[address-VRAM_END]
+		return mem.cart.ReadRAM(address - VRAM_END)
 	case address < WRAM_END:
 		// wram
-		return mem.wram[address-SRAM_END]
+		return mem.wram[mem.wramAddress(address-SRAM_END)]
 	case address < ECHO_END:
-		// echo
-		return mem.wram[address-WRAM_END]
+		// echo, mirrors the start of wram
+		return mem.wram[mem.wramAddress(address-WRAM_END)]
 	case address < OAM_END:
 		// oam
-		return mem.oam[address-ECHO_END]
+		value := mem.oam[address-ECHO_END]
+		mem.console.ppu.oamBugRead(address)
+		return value
 	case address < UNUSED_END:
-		// unused
-		// what to do here?
+		// unused, reads still corrupt oam
+		mem.console.ppu.oamBugRead(address)
 		return 0
 	case address < IO_END:
 		// io
-		return mem.io[address-UNUSED_END]
+		return mem.readIO(address)
 	case address < HRAM_END:
 		// hram
 		return mem.hram[address-IO_END]
-	case address == 0xFFFF:
-		// interrupt flag
-		return 0 // TODO: interrupts?
+	case address == IE_ADDRESS:
+		// interrupt enable
+		return mem.ie
 	default:
 		panic("Invalid memory address")
 	}
 }
 
+// Read an I/O register, registers owned by other parts of the console are delegated to them
+func (mem *MemoryMap) readIO(address uint16) uint8 {
+	switch {
+	case address == JOYP_ADDRESS:
+		return mem.console.joypad.Read()
+	case address >= DIV_ADDRESS && address <= TAC_ADDRESS:
+		return mem.console.timer.Read(address)
+	case address == KEY1_ADDRESS:
+		if !mem.console.cgbMode() {
+			return 0xFF
+		}
+		return mem.console.cpu.ReadKEY1()
+	case address == VBK_ADDRESS:
+		if !mem.console.cgbRegisters() {
+			return 0xFF
+		}
+		return mem.io[address-UNUSED_END] | 0xFE
+	case address == SVBK_ADDRESS:
+		if !mem.console.cgbRegisters() {
+			return 0xFF
+		}
+		return mem.io[address-UNUSED_END] | 0xF8
+	case address >= LCDC_ADDRESS && address <= WX_ADDRESS && address != DMA_ADDRESS,
+		address >= BCPS_ADDRESS && address <= OCPD_ADDRESS:
+		return mem.console.ppu.Read(address)
+	case address == IF_ADDRESS:
+		// upper 3 bits are unused and read as 1
+		return mem.io[address-UNUSED_END] | 0xE0
+	case address == BOOT_ADDRESS:
+		return 0xFF
+	default:
+		return mem.io[address-UNUSED_END]
+	}
+}
+
+// Write an I/O register, registers owned by other parts of the console are delegated to them
+func (mem *MemoryMap) writeIO(address uint16, value uint8) {
+	switch {
+	case address == JOYP_ADDRESS:
+		mem.console.joypad.Write(value)
+	case address >= DIV_ADDRESS && address <= TAC_ADDRESS:
+		mem.console.timer.Write(address, value)
+	case address == KEY1_ADDRESS:
+		if mem.console.cgbMode() {
+			mem.console.cpu.WriteKEY1(value)
+		}
+	case address == VBK_ADDRESS:
+		if mem.console.cgbRegisters() {
+			mem.io[address-UNUSED_END] = value & 0x01
+		}
+	case address == SVBK_ADDRESS:
+		if mem.console.cgbRegisters() {
+			mem.io[address-UNUSED_END] = value & 0x07
+		}
+	case address >= LCDC_ADDRESS && address <= WX_ADDRESS && address != DMA_ADDRESS,
+		address >= BCPS_ADDRESS && address <= OCPD_ADDRESS:
+		mem.console.ppu.Write(address, value)
+	case address == IF_ADDRESS:
+		mem.io[address-UNUSED_END] = value & 0x1F
+	case address == DMA_ADDRESS:
+		mem.io[address-UNUSED_END] = value
+		mem.dma(value)
+	case address == BOOT_ADDRESS:
+		if value&0x01 != 0 {
+			mem.booting = false
+		}
+	default:
+		mem.io[address-UNUSED_END] = value
+	}
+}
+
+// vramBank returns the offset of the VRAM bank the CPU sees
+func (mem *MemoryMap) vramBank() uint16 {
+	if mem.console.cgbRegisters() && mem.io[VBK_ADDRESS-UNUSED_END]&0x01 != 0 {
+		return VRAM_BANK_SIZE
+	}
+	return 0
+}
+
+// wramAddress returns the index into wram of an offset from C000, D000 - DFFF is the bank SVBK selects,
+// where bank 0 selects bank 1
+func (mem *MemoryMap) wramAddress(offset uint16) uint16 {
+	if offset < WRAM_BANK_SIZE {
+		return offset
+	}
+
+	bank := uint16(1)
+	if mem.console.cgbRegisters() && mem.io[SVBK_ADDRESS-UNUSED_END]&0x07 > 1 {
+		bank = uint16(mem.io[SVBK_ADDRESS-UNUSED_END] & 0x07)
+	}
+	return bank*WRAM_BANK_SIZE + offset - WRAM_BANK_SIZE
+}
+
+// OAM DMA, copies 160 bytes from XX00 to OAM, sources from E000 up read WRAM like the echo area.
+// The transfer happens at once rather than over 160 cycles
+func (mem *MemoryMap) dma(value uint8) {
+	source := uint16(value) << 8
+	if source >= WRAM_END {
+		source -= WRAM_END - SRAM_END
+	}
+
+	for i := uint16(0); i < uint16(len(mem.oam)); i++ {
+		mem.oam[i] = mem.Read8(source + i)
+	}
+}
+
 // Write a 16-bit value to the address
 func (mem *MemoryMap) Write16(address uint16, value uint16) {
-
+	// write the low byte at address and the high byte at address+1
+	mem.Write8(address, uint8(value&0xFF))
+	mem.Write8(address+1, uint8(value>>8))
 }
 
 // Read a 16-bit value from the address
@@ -134,10 +275,39 @@ func (mem *MemoryMap) Read16(address uint16) uint16 {
 	return uint16(low) | (uint16(high) << 8)
 }
 
-//
-func (mem *MemoryMap) WriteToStack16(value uint16, sp *uint16) {
-	// decrement sp by 2
+// Push a 16-bit value onto the stack, used by PUSH, CALL, RST and interrupts
+func (mem *MemoryMap) PushStack16(value uint16, sp *uint16) {
+	// decrement sp by 2, the decrement goes through the IDU
+	mem.console.ppu.oamBugWrite(*sp)
 	*sp -= 2
 
-	// call write 16 to sp
+	// write the value at the new top of the stack
+	mem.Write16(*sp, value)
+}
+
+// Pop a 16-bit value off the stack, used by POP, RET and RETI
+func (mem *MemoryMap) PopStack16(sp *uint16) uint16 {
+	// each byte is read while the IDU increments sp
+	mem.console.ppu.oamBugIncrease(*sp)
+	value := mem.Read16(*sp)
+
+	// increment sp by 2
+	*sp += 2
+
+	return value
+}
+
+// Save states, the cartridge saves its own RAM
+
+func (mem *MemoryMap) saveState(s *stateWriter) {
+	s.write(&mem.vram, &mem.wram, &mem.oam, &mem.hram, &mem.io, mem.ie, mem.booting)
+}
+
+func (mem *MemoryMap) loadState(s *stateReader) {
+	s.read(&mem.vram, &mem.wram, &mem.oam, &mem.hram, &mem.io, &mem.ie, &mem.booting)
+
+	// a state saved while booting can't run without the boot ROM
+	if mem.booting && mem.boot == nil {
+		s.err = errors.New("saved while running the boot rom, which this console doesn't have")
+	}
 }
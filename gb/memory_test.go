@@ -0,0 +1,125 @@
+package gb
+
+import "testing"
+
+// flatMemory is the reference model for the areas of the memory map that are plain memory
+type flatMemory [0x10000]uint8
+
+// modeled reports whether the address is plain memory, ROM, cartridge RAM and I/O have side effects
+func modeled(address uint16) bool {
+	switch {
+	case address < ROM_END:
+		return false
+	case address >= VRAM_END && address < SRAM_END:
+		return false
+	case address >= UNUSED_END && address < IO_END:
+		return false
+	}
+	return true
+}
+
+// mirror returns where an address is stored, echo RAM is WRAM
+func mirror(address uint16) uint16 {
+	if address >= WRAM_END && address < ECHO_END {
+		return address - (WRAM_END - SRAM_END)
+	}
+	return address
+}
+
+func (m *flatMemory) write(address uint16, value uint8) {
+	if address >= OAM_END && address < UNUSED_END {
+		// unused, writes are ignored
+		return
+	}
+	m[mirror(address)] = value
+}
+
+func (m *flatMemory) read(address uint16) uint8 {
+	return m[mirror(address)]
+}
+
+func FuzzMemoryMap(f *testing.F) {
+	f.Add([]byte{0x00, 0x80, 0x12})                                     // VRAM
+	f.Add([]byte{0x34, 0xE1, 0x56, 0x34, 0xC1, 0x78})                   // echo, then the WRAM behind it
+	f.Add([]byte{0x00, 0xDE, 0x9A, 0xFF, 0xFD, 0xBC, 0x00, 0xE0, 0x11}) // ends of WRAM and echo
+	f.Add([]byte{0x9F, 0xFE, 0x42, 0xA0, 0xFE, 0x43, 0xFF, 0xFE, 0x44}) // end of OAM, unused
+	f.Add([]byte{0x80, 0xFF, 0x01, 0xFE, 0xFF, 0x02, 0xFF, 0xFF, 0x1F}) // HRAM, IE
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		c := newTestConsole(t)
+		var model flatMemory
+
+		// start from the same contents
+		for address := 0; address < 0x10000; address++ {
+			if modeled(uint16(address)) {
+				model[address] = c.mem.Read8(uint16(address))
+			}
+		}
+
+		// each write is 3 bytes, the address (little endian) then the value
+		for i := 0; i+3 <= len(data); i += 3 {
+			address := uint16(data[i]) | uint16(data[i+1])<<8
+			value := data[i+2]
+			if !modeled(address) {
+				continue
+			}
+
+			c.mem.Write8(address, value)
+			model.write(address, value)
+
+			if got, want := c.mem.Read8(address), model.read(address); got != want {
+				t.Fatalf("after writing 0x%02X to 0x%04X, read 0x%02X, want 0x%02X", value, address, got, want)
+			}
+		}
+
+		for address := 0; address < 0x10000; address++ {
+			if !modeled(uint16(address)) {
+				continue
+			}
+			if got, want := c.mem.Read8(uint16(address)), model.read(uint16(address)); got != want {
+				t.Fatalf("0x%04X reads 0x%02X, want 0x%02X", address, got, want)
+			}
+		}
+	})
+}
+
+func TestWRAMBanks(t *testing.T) {
+	rom := testROM(0x00, 0x00)
+	rom[0x143] = 0x80 // CGB game
+	c := newROMConsole(t, rom, MODEL_CGB)
+
+	// SVBK 0 selects bank 1, like SVBK 1
+	for bank := uint8(0); bank < WRAM_BANKS; bank++ {
+		c.mem.Write8(SVBK_ADDRESS, bank)
+		c.mem.Write8(0xD123, 0x10|bank)
+	}
+	c.mem.Write8(0xC123, 0xCC)
+
+	for bank := uint8(0); bank < WRAM_BANKS; bank++ {
+		c.mem.Write8(SVBK_ADDRESS, bank)
+		want := 0x10 | bank
+		if bank == 0 {
+			want = 0x11 // bank 1, written last through SVBK 1
+		}
+		if got := c.mem.Read8(0xD123); got != want {
+			t.Errorf("bank %d: D123 = 0x%02X, want 0x%02X", bank, got, want)
+		}
+		if got := c.mem.Read8(0xF123); got != want {
+			t.Errorf("bank %d: echo F123 = 0x%02X, want 0x%02X", bank, got, want)
+		}
+		if got := c.mem.Read8(0xC123); got != 0xCC {
+			t.Errorf("bank %d: C123 = 0x%02X, want 0xCC", bank, got)
+		}
+		if got := c.mem.Read8(SVBK_ADDRESS); got != 0xF8|bank {
+			t.Errorf("SVBK = 0x%02X, want 0x%02X", got, 0xF8|bank)
+		}
+	}
+
+	// DMG mode has no SVBK, D000 is always bank 1
+	d := newROMConsole(t, testROM(0x00, 0x00), MODEL_CGB)
+	d.mem.Write8(0xD000, 0x55)
+	d.mem.Write8(SVBK_ADDRESS, 0x03)
+	if got := d.mem.Read8(0xD000); got != 0x55 || d.mem.Read8(SVBK_ADDRESS) != 0xFF {
+		t.Errorf("DMG mode: D000 = 0x%02X SVBK = 0x%02X, want 0x55 0xFF", got, d.mem.Read8(SVBK_ADDRESS))
+	}
+}
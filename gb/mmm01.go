@@ -0,0 +1,136 @@
+package gb
+
+/*
+
+MMM01 multicart
+
+At power on the last 32KB of ROM, which holds the menu, is mapped to 0000 - 7FFF.
+The menu sets up the game's outer bank and bank masks, then locks the mapper,
+after which it behaves like an MBC1 confined to the selected game.
+
+	0000 - 1FFF RAM enable (bits 0-3, 0x0A enables)
+		Bits 4-5 RAM bank mask, frozen RAM bank bits (unlocked only)
+		Bit 6    Lock, maps the selected game (unlocked only)
+	2000 - 3FFF ROM bank number, bits 0-4
+		Bits 5-6 ROM bank bits 5-6 (unlocked only)
+	4000 - 5FFF RAM bank number, bits 0-1
+		Bits 2-3 ROM bank bits 7-8 (unlocked only)
+		Bits 4-5 RAM bank bits 2-3 (unlocked only)
+	6000 - 7FFF Banking mode (bit 0), ignored
+		Bits 2-5 ROM bank mask, frozen ROM bank bits 1-4 (unlocked only)
+
+Frozen bits keep the value they had when the mapper was locked, the game can only change the others.
+
+*/
+
+type MMM01 struct {
+	cart      *Cartridge
+	locked    bool
+	ramEnable bool
+	romBank   uint16 // 9-bit ROM bank, bits 5-8 select the game
+	romMask   uint8  // frozen bits of the 5-bit ROM bank
+	ramBank   uint8  // 4-bit RAM bank, bits 2-3 select the game
+	ramMask   uint8  // frozen bits of the 2-bit RAM bank
+}
+
+func NewMMM01(cart *Cartridge) *MMM01 {
+	return &MMM01{cart: cart}
+}
+
+func (m *MMM01) romBanks() int {
+	return len(m.cart.rom) / ROM_BANK_SIZE
+}
+
+func (m *MMM01) ReadROM(address uint16) uint8 {
+	var bank int
+
+	switch {
+	case !m.locked:
+		// menu
+		bank = m.romBanks() - 2
+		if address >= ROM_BANK_SIZE {
+			bank++
+		}
+	case address < ROM_BANK_SIZE:
+		bank = int(m.romBank &^ uint16(^m.romMask&0x1F))
+	default:
+		// like MBC1, bank 0 is treated as 1, looking only at the bits the game controls
+		bank = int(m.romBank)
+		if m.romBank&uint16(^m.romMask&0x1F) == 0 {
+			bank |= 1
+		}
+	}
+
+	bank %= m.romBanks()
+	return m.cart.rom[bank*ROM_BANK_SIZE+int(address)%ROM_BANK_SIZE]
+}
+
+func (m *MMM01) WriteROM(address uint16, value uint8) {
+	switch {
+	case address < 0x2000:
+		m.ramEnable = value&0x0F == 0x0A
+		if !m.locked {
+			m.ramMask = value >> 4 & 0x03
+			m.locked = value&0x40 != 0
+		}
+	case address < 0x4000:
+		mask := uint16(0x1F &^ m.romMask)
+		if !m.locked {
+			mask |= 0x60
+		}
+		m.romBank = m.romBank&^mask | uint16(value&0x7F)&mask
+	case address < 0x6000:
+		mask := 0x03 &^ m.ramMask
+		if !m.locked {
+			mask |= 0x0C
+			m.romBank = m.romBank&0x7F | uint16(value>>2&0x03)<<7
+		}
+		m.ramBank = m.ramBank&^mask | (value&0x03|value>>2&0x0C)&mask
+	default:
+		if !m.locked {
+			m.romMask = value >> 1 & 0x1E
+		}
+	}
+}
+
+func (m *MMM01) ramAddress(address uint16) int {
+	return (int(m.ramBank)*RAM_BANK_SIZE + int(address)) % len(m.cart.ram)
+}
+
+func (m *MMM01) ReadRAM(address uint16) uint8 {
+	if !m.ramEnable || len(m.cart.ram) == 0 {
+		return 0xFF
+	}
+	return m.cart.ram[m.ramAddress(address)]
+}
+
+func (m *MMM01) WriteRAM(address uint16, value uint8) {
+	if !m.ramEnable || len(m.cart.ram) == 0 {
+		return
+	}
+	m.cart.ram[m.ramAddress(address)] = value
+	m.cart.dirty = true
+}
+
+// mmm01Header finds the menu's header in the last 32KB of an MMM01 multicart, the header
+// at the start of the ROM belongs to the first game
+func mmm01Header(rom []uint8) (CartridgeHeader, bool) {
+	if len(rom) < 0x10000 || len(rom)%0x8000 != 0 {
+		return CartridgeHeader{}, false
+	}
+
+	header, err := parseHeader(rom[len(rom)-0x8000:])
+	if err != nil || header.Type < 0x0B || header.Type > 0x0D {
+		return CartridgeHeader{}, false
+	}
+
+	return header, true
+}
+
+func (m *MMM01) saveState(s *stateWriter) {
+	s.write(m.locked, m.ramEnable, m.romBank, m.romMask, m.ramBank, m.ramMask)
+}
+
+func (m *MMM01) loadState(s *stateReader) {
+	s.read(&m.locked, &m.ramEnable, &m.romBank, &m.romMask, &m.ramBank, &m.ramMask)
+}
@@ -0,0 +1,60 @@
+package gb
+
+import "testing"
+
+func TestMMM01(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []mapperWrite
+		banks  map[uint16]uint8 // address to the ROM bank mapped there
+	}{
+		{"menu", nil, map[uint16]uint8{0x0000: 62, 0x4000: 63}},
+		{"menu before lock", []mapperWrite{{0x2000, 0x25}}, map[uint16]uint8{0x0000: 62, 0x4000: 63}},
+		{"game", []mapperWrite{{0x2000, 0x20}, {0x0000, 0x40}}, map[uint16]uint8{0x0000: 32, 0x4000: 33}},
+		{"game ROM bank", []mapperWrite{{0x2000, 0x20}, {0x0000, 0x40}, {0x2000, 0x05}}, map[uint16]uint8{0x0000: 32, 0x4000: 37}},
+		{"outer bank frozen", []mapperWrite{{0x2000, 0x20}, {0x0000, 0x40}, {0x2000, 0x65}}, map[uint16]uint8{0x0000: 32, 0x4000: 37}},
+		{"lock frozen", []mapperWrite{{0x2000, 0x20}, {0x0000, 0x40}, {0x0000, 0x00}}, map[uint16]uint8{0x0000: 32, 0x4000: 33}},
+		{"ROM mask", []mapperWrite{{0x2000, 0x24}, {0x6000, 0x3C}, {0x0000, 0x40}}, map[uint16]uint8{0x0000: 36, 0x4000: 37}},
+		{"ROM mask frozen bits", []mapperWrite{{0x2000, 0x24}, {0x6000, 0x3C}, {0x0000, 0x40}, {0x2000, 0x1A}}, map[uint16]uint8{0x0000: 36, 0x4000: 37}},
+		{"ROM mask bank 0", []mapperWrite{{0x2000, 0x25}, {0x6000, 0x3C}, {0x0000, 0x40}, {0x2000, 0x00}}, map[uint16]uint8{0x0000: 36, 0x4000: 37}},
+	}
+
+	for _, test := range tests {
+		m := NewMMM01(bankedCart(64, 4*RAM_BANK_SIZE))
+		writeMapper(m, test.writes)
+
+		for address, want := range test.banks {
+			if got := m.ReadROM(address); got != want {
+				t.Errorf("%s: bank at %04X = %d, want %d", test.name, address, got, want)
+			}
+		}
+	}
+}
+
+func TestMMM01RAM(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []mapperWrite
+		bank   int // RAM bank the write lands in, -1 for none
+	}{
+		{"disabled", []mapperWrite{{0xA000, 0x42}}, -1},
+		{"enabled", []mapperWrite{{0x0000, 0x0A}, {0xA000, 0x42}}, 0},
+		{"game bank", []mapperWrite{{0x4000, 0x20}, {0x0000, 0x4A}, {0x4000, 0x01}, {0xA000, 0x42}}, 1},
+		{"RAM mask", []mapperWrite{{0x4000, 0x01}, {0x0000, 0x5A}, {0x4000, 0x02}, {0xA000, 0x42}}, 3},
+	}
+
+	for _, test := range tests {
+		m := NewMMM01(bankedCart(64, 4*RAM_BANK_SIZE))
+		writeMapper(m, test.writes)
+
+		for bank := 0; bank < 4; bank++ {
+			want := uint8(0)
+			if bank == test.bank {
+				want = 0x42
+			}
+			if got := m.cart.ram[bank*RAM_BANK_SIZE]; got != want {
+				t.Errorf("%s: RAM bank %d = %02X, want %02X", test.name, bank, got, want)
+			}
+		}
+	}
+}
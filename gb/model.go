@@ -0,0 +1,106 @@
+package gb
+
+import (
+	"fmt"
+)
+
+/*
+
+Hardware models
+
+	DMG0  Original Game Boy, early Japanese boot ROM
+	DMG   Original Game Boy
+	MGB   Game Boy Pocket (and Light)
+	SGB   Super Game Boy
+	SGB2  Super Game Boy 2
+	CGB   Game Boy Color
+	AGB   Game Boy Advance, running Game Boy Color games
+
+Games tell the models apart by the registers the boot ROM leaves behind: A is 0x01 on DMG and SGB,
+0xFF on MGB and SGB2 and 0x11 on CGB and AGB, where AGB also has bit 0 of B set.
+
+CGB and AGB run games without CGB support in a DMG compatibility mode, without the CGB registers.
+
+What the model changes
+
+	Registers and I/O left by the boot ROM, or which boot ROM is accepted
+	CGB registers, VRAM and WRAM banks, color palettes and double speed, on CGB and AGB in CGB mode
+	Boot ROM palettes coloring DMG games, on CGB and AGB
+	STAT write bug and OAM bug, on DMG family models only
+	Line 153 timing, LY resets at dot 4 on the DMG family and dot 8 on CGB and AGB, see ppu.go
+
+What it doesn't change
+
+	LCD enable timing, the first line skips OAM scan the same way on every model
+	APU, which doesn't generate sound yet, so none of the models' audio differences are emulated
+
+*/
+
+// Model is a Game Boy hardware model, it selects the boot state, CGB features and PPU quirks
+type Model uint8
+
+const (
+	MODEL_AUTO Model = iota // pick from the cartridge header
+	MODEL_DMG0
+	MODEL_DMG
+	MODEL_MGB
+	MODEL_SGB
+	MODEL_SGB2
+	MODEL_CGB
+	MODEL_AGB
+)
+
+var modelNames = [...]string{"AUTO", "DMG0", "DMG", "MGB", "SGB", "SGB2", "CGB", "AGB"}
+
+func (m Model) String() string {
+	if int(m) < len(modelNames) {
+		return modelNames[m]
+	}
+	return fmt.Sprintf("Model(%d)", m)
+}
+
+// IsCGB reports whether the model has the Game Boy Color hardware
+func (m Model) IsCGB() bool {
+	return m == MODEL_CGB || m == MODEL_AGB
+}
+
+// IsSGB reports whether the model is a Super Game Boy
+func (m Model) IsSGB() bool {
+	return m == MODEL_SGB || m == MODEL_SGB2
+}
+
+// bootROMSize returns the size of the model's boot ROM
+func (m Model) bootROMSize() int {
+	if m.IsCGB() {
+		return CGB_BOOT_ROM_SIZE
+	}
+	return DMG_BOOT_ROM_SIZE
+}
+
+// detectModel picks the model a cartridge was made for, CGB for CGB games, SGB for SGB games, DMG otherwise
+func detectModel(header CartridgeHeader) Model {
+	switch {
+	case header.CGBFlag&0x80 != 0:
+		return MODEL_CGB
+	case header.SGBFlag == 0x03:
+		return MODEL_SGB
+	default:
+		return MODEL_DMG
+	}
+}
+
+// Model returns the hardware model the console emulates
+func (c *Console) Model() Model {
+	return c.model
+}
+
+// cgbMode reports whether the CGB hardware is enabled, CGB models run games without CGB support in DMG mode
+func (c *Console) cgbMode() bool {
+	return c.model.IsCGB() && c.cart.Header.CGBFlag&0x80 != 0
+}
+
+// cgbRegisters reports whether the CGB video registers can be used, in CGB mode and on CGB models
+// while the boot ROM runs, as it sets up the palettes for DMG mode
+func (c *Console) cgbRegisters() bool {
+	return c.cgbMode() || c.model.IsCGB() && c.mem.booting
+}
@@ -1,8 +1,12 @@
 package gb
 
+import (
+	"fmt"
+	"sync"
+)
+
 // import (
 // 	"encoding/gob"
-// 	"image"
 // )
 
 // The PPU, or pixel processing unit, is used to render the Gameboy screen and process graphics.
@@ -14,9 +18,842 @@ The Gameboy has 3 distinct video layers that are all made up of 8x8 pixel tiles.
 - Background
 - Window
 - Sprites
+
+Tiles are 16 bytes, 2 bytes per row, the first holding bit 0 of each pixel's color number and the second bit 1,
+bit 7 is the leftmost pixel. Sprites always use tiles at 8000 - 8FFF, the BG and window use 8000 - 8FFF
+(tile numbers 0 - 255) or 8800 - 97FF (tile numbers -128 - 127 from 9000) as LCDC bit 4 selects.
+
+	Background  32x32 tile map at 9800 or 9C00, scrolled by SCX and SCY, wrapping around
+	Window      32x32 tile map at 9800 or 9C00, drawn over the BG from X = WX - 7 once LY has matched WY
+	            this frame. It has its own line counter, which only counts lines the window was drawn on
+	Sprites     40 entries in OAM (FE00 - FE9F), 4 bytes each
+		Byte 0 - Y + 16
+		Byte 1 - X + 8
+		Byte 2 - Tile number, bit 0 ignored for 8x16 sprites
+		Byte 3 - Attributes
+			Bit 7 - BG and window colors 1-3 are drawn over the sprite
+			Bit 6 - Y flip
+			Bit 5 - X flip
+			Bit 4 - Palette, OBP0 or OBP1
+
+Only the first 10 sprites on a line (in OAM order) are drawn. Where sprites overlap the one with the lower X
+is drawn, then the one earlier in OAM. Sprite color 0 is transparent.
+
+With LCDC bit 0 clear the BG and window are blank (color 0), sprites are still drawn.
+With the LCD off (LCDC bit 7) the screen is blank, LY stays 0 and VRAM and OAM are free to access.
+*/
+
+/*
+
+LCD registers
+
+	FF40 - LCDC LCD control
+		Bit 7 - LCD enable
+		Bit 6 - Window tile map (0 = 9800, 1 = 9C00)
+		Bit 5 - Window enable
+		Bit 4 - BG and window tile data (0 = 8800, signed tile numbers, 1 = 8000)
+		Bit 3 - BG tile map (0 = 9800, 1 = 9C00)
+		Bit 2 - Sprite size (0 = 8x8, 1 = 8x16)
+		Bit 1 - Sprite enable
+		Bit 0 - BG and window enable
+	FF41 - STAT LCD status
+		Bit 6 - LYC=LY interrupt enable
+		Bit 5 - Mode 2 (OAM scan) interrupt enable
+		Bit 4 - Mode 1 (VBlank) interrupt enable
+		Bit 3 - Mode 0 (HBlank) interrupt enable
+		Bit 2 - LYC=LY, read only
+		Bit 0-1 - Mode, read only, 0 while the LCD is off
+	FF42 - SCY  Background scroll Y
+	FF43 - SCX  Background scroll X
+	FF44 - LY   Line being drawn, read only
+	FF45 - LYC  Line compare
+	FF46 - DMA  OAM DMA, writing XX copies XX00 - XX9F to OAM
+	FF47 - BGP  Background palette, 2 bits per color, color 0 in bits 0-1
+	FF48 - OBP0 Sprite palette 0, color 0 is transparent
+	FF49 - OBP1 Sprite palette 1
+	FF4A - WY   Window Y
+	FF4B - WX   Window X + 7
+
+CGB registers, see cgb.go
+
+	FF4F - VBK  VRAM bank
+	FF68 - BCPS BG palette index
+	FF69 - BCPD BG palette data
+	FF6A - OCPS Sprite palette index
+	FF6B - OCPD Sprite palette data
+
+Each line takes 456 dots (one dot per cycle, at single speed even in CGB double speed mode).
+Lines 0 - 143 go through OAM scan (mode 2, 80 dots), drawing (mode 3, 172 dots) and HBlank (mode 0, the rest),
+lines 144 - 153 are VBlank (mode 1).
+
+Line 153 resets LY early, and LYC is compared with a line that lags behind LY, which depends on the model:
+
+	Dots     DMG family           CGB and AGB
+	0 - 3    LY 153, LYC vs 153   LY 153, LYC vs 153
+	4 - 7    LY 0, LYC vs 153     LY 153, LYC vs 153
+	8 - 11   LY 0, no match       LY 0, LYC vs 153
+	12 -     LY 0, LYC vs 0       LY 0, LYC vs 0
+
+The first line after the LCD is turned on skips OAM scan: STAT reads mode 0 for its first 80 dots and there's
+no mode 2 interrupt, drawing starts at the usual dot.
+
+The STAT interrupt is requested when any enabled STAT condition becomes true while none was before,
+so back to back conditions (like HBlank followed by a matching LYC) only request one interrupt.
+
 */
 
+const SCREEN_WIDTH = 160
+const SCREEN_HEIGHT = 144
+
+// a frame is 154 lines of 456 dots (cycles), including the 10 lines of VBlank
+const DOTS_PER_LINE = 456
+const LINES_PER_FRAME = 154
+const DOTS_PER_FRAME = DOTS_PER_LINE * LINES_PER_FRAME
+
+const OAM_SCAN_DOTS = 80
+const DRAW_DOTS = 172
+
+const LCDC_ADDRESS = 0xFF40
+const STAT_ADDRESS = 0xFF41
+const SCY_ADDRESS = 0xFF42
+const SCX_ADDRESS = 0xFF43
+const LY_ADDRESS = 0xFF44
+const LYC_ADDRESS = 0xFF45
+const DMA_ADDRESS = 0xFF46
+const BGP_ADDRESS = 0xFF47
+const OBP0_ADDRESS = 0xFF48
+const OBP1_ADDRESS = 0xFF49
+const WY_ADDRESS = 0xFF4A
+const WX_ADDRESS = 0xFF4B
+
+const (
+	LCDC_BG_ENABLE     = 1 << iota // BG and window enable (DMG), BG and window priority (CGB)
+	LCDC_SPRITE_ENABLE             // sprites enable
+	LCDC_SPRITE_SIZE               // 8x16 sprites
+	LCDC_BG_MAP                    // BG tile map at 9C00
+	LCDC_TILE_DATA                 // BG and window tiles at 8000, unsigned
+	LCDC_WINDOW_ENABLE             // window enable
+	LCDC_WINDOW_MAP                // window tile map at 9C00
+	LCDC_LCD_ENABLE                // LCD and PPU enable
+)
+
+const (
+	MODE_HBLANK = iota
+	MODE_VBLANK
+	MODE_OAM_SCAN
+	MODE_DRAW
+)
+
+const (
+	STAT_COINCIDENCE = 0x04
+	STAT_HBLANK      = 0x08
+	STAT_VBLANK      = 0x10
+	STAT_OAM_SCAN    = 0x20
+	STAT_LYC         = 0x40
+)
+
+// Renderer selects how the PPU draws mode 3
+type Renderer uint8
+
+const (
+	RENDERER_SCANLINE Renderer = iota // draws each line at once, fast, mode 3 always takes 172 dots
+	RENDERER_FIFO                     // pixel FIFO, dot by dot, for mid-line register writes and exact mode 3 timing
+)
+
+func (r Renderer) String() string {
+	switch r {
+	case RENDERER_SCANLINE:
+		return "scanline"
+	case RENDERER_FIFO:
+		return "FIFO"
+	}
+	return fmt.Sprintf("Renderer(%d)", uint8(r))
+}
+
+// lineRenderer draws the lines during mode 3, the PPU handles everything else
+type lineRenderer interface {
+	startLine() // mode 3 starts, the line's sprites have been scanned
+	dot() bool  // one dot of mode 3, returns true once the line is drawn
+	saveState(s *stateWriter)
+	loadState(s *stateReader)
+}
+
+// sprite is an OAM entry, X and Y are offset by 8 and 16 so sprites can be partially off screen
+type sprite struct {
+	y, x  uint8
+	tile  uint8
+	attrs uint8
+	index uint8 // position in OAM
+}
+
+const (
+	SPRITE_CGB_PALETTE = 0x07 // CGB palette
+	SPRITE_BANK        = 0x08 // CGB tile VRAM bank
+	SPRITE_PALETTE     = 0x10 // DMG palette, OBP1 instead of OBP0
+	SPRITE_X_FLIP      = 0x20
+	SPRITE_Y_FLIP      = 0x40
+	SPRITE_PRIORITY    = 0x80 // BG and window colors 1-3 are drawn over the sprite
+)
+
+const MAX_SPRITES_PER_LINE = 10
+
+// pixel is a BG, window or sprite pixel before it goes through its palette
+type pixel struct {
+	color    uint8 // color number, 0 - 3
+	palette  uint8 // sprites, 0 = OBP0, 1 = OBP1, CGB palette number in CGB mode
+	priority uint8 // BG and window colors 1-3 are drawn over the sprite, from the sprite or CGB BG attributes
+	index    uint8 // sprites, position in OAM
+}
+
+// screen holds a frame's pixels
+type screen struct {
+	shades [SCREEN_HEIGHT][SCREEN_WIDTH]uint8  // 0 (white) - 3 (black), color numbers in CGB mode
+	colors [SCREEN_HEIGHT][SCREEN_WIDTH]uint16 // CGB models, 15-bit colors
+}
+
+func (s *screen) blank() {
+	s.shades = [SCREEN_HEIGHT][SCREEN_WIDTH]uint8{}
+	for y := range s.colors {
+		for x := range s.colors[y] {
+			s.colors[y][x] = CGB_WHITE
+		}
+	}
+}
+
 type PPU struct {
-	mem     MemoryMap // memory map interface
-	console *Console  // reference to parent console
+	mem      *MemoryMap   // memory map interface
+	console  *Console     // reference to parent console
+	kind     Renderer     // which renderer draws mode 3
+	renderer lineRenderer // draws mode 3
+	screens  [2]screen    // the frame being drawn and the last finished one
+	buffer   *screen      // frame being drawn
+	frame    *screen      // last finished frame
+	frameMu  sync.Mutex   // guards frame, which is read from other goroutines
+	line     uint8        // line being drawn, 0 - 153
+	ly       uint8        // LY, the line except at the end of line 153
+	compare  int16        // line LYC is compared with, -1 for none, lags behind LY on line 153
+	firstOn  bool         // first line since the LCD was turned on, which reads mode 0 during OAM scan
+	dots     int          // dots into the line
+	mode     uint8        // STAT mode
+	statLine bool         // any enabled STAT condition is true
+	finished bool         // a frame was finished since the last FrameReady
+
+	bgPalettes  [64]uint8 // CGB BG palette RAM, 8 palettes of 4 little endian 15-bit colors
+	objPalettes [64]uint8 // CGB sprite palette RAM
+
+	sprites     [MAX_SPRITES_PER_LINE]sprite // sprites on the line, in OAM order
+	spriteCount uint8
+	windowY     bool  // LY matched WY this frame, so the window can be drawn
+	windowLine  uint8 // line of the window to draw next, only counts lines the window was drawn on
+}
+
+// NewPPU creates a PPU drawing with the given renderer
+func NewPPU(mem *MemoryMap, console *Console, renderer Renderer) *PPU {
+	ppu := &PPU{mem: mem, console: console, kind: renderer}
+	ppu.buffer = &ppu.screens[0]
+	ppu.frame = &ppu.screens[1]
+
+	switch renderer {
+	case RENDERER_FIFO:
+		ppu.renderer = &fifoRenderer{ppu: ppu}
+	default:
+		ppu.renderer = &scanlineRenderer{ppu: ppu}
+	}
+
+	return ppu
+}
+
+// register reads and writes, the registers without side effects are kept in the memory map's io
+
+func (ppu *PPU) register(address uint16) uint8 {
+	return ppu.mem.io[address-UNUSED_END]
+}
+
+func (ppu *PPU) enabled() bool {
+	return ppu.register(LCDC_ADDRESS)&LCDC_LCD_ENABLE != 0
+}
+
+func (ppu *PPU) Read(address uint16) uint8 {
+	if address >= BCPS_ADDRESS {
+		return ppu.readPalette(address)
+	}
+
+	switch address {
+	case STAT_ADDRESS:
+		value := 0x80 | ppu.register(STAT_ADDRESS)&0x78
+		if ppu.coincidence() {
+			value |= STAT_COINCIDENCE
+		}
+		if ppu.enabled() {
+			value |= ppu.mode
+		}
+		return value
+	case LY_ADDRESS:
+		return ppu.ly
+	default:
+		return ppu.register(address)
+	}
+}
+
+func (ppu *PPU) Write(address uint16, value uint8) {
+	if address >= BCPS_ADDRESS {
+		ppu.writePalette(address, value)
+		return
+	}
+
+	switch address {
+	case LCDC_ADDRESS:
+		wasEnabled := ppu.enabled()
+		ppu.mem.io[address-UNUSED_END] = value
+		if wasEnabled && !ppu.enabled() {
+			ppu.turnOff()
+		} else if !wasEnabled && ppu.enabled() {
+			ppu.turnOn()
+		}
+	case STAT_ADDRESS:
+		ppu.mem.io[address-UNUSED_END] = value & 0x78
+		ppu.writeSTATBug()
+		ppu.updateSTAT()
+	case LY_ADDRESS:
+		// read only
+	case LYC_ADDRESS:
+		ppu.mem.io[address-UNUSED_END] = value
+		ppu.updateSTAT()
+	default:
+		ppu.mem.io[address-UNUSED_END] = value
+	}
+}
+
+// Reset puts the PPU in its power on state, with the LCD off
+func (ppu *PPU) Reset() {
+	ppu.turnOff()
+	ppu.buffer.blank()
+	ppu.bgPalettes = [64]uint8{}
+	ppu.objPalettes = [64]uint8{}
+	ppu.finished = false
+}
+
+// turnOff blanks the screen, LY and the mode stay 0 until the LCD is turned back on
+func (ppu *PPU) turnOff() {
+	ppu.line = 0
+	ppu.ly = 0
+	ppu.compare = 0
+	ppu.dots = 0
+	ppu.firstOn = false
+	ppu.mode = MODE_HBLANK
+	ppu.statLine = false
+	ppu.frameMu.Lock()
+	ppu.frame.blank()
+	ppu.frameMu.Unlock()
+	ppu.finished = true
+}
+
+// turnOn starts drawing from the top of the screen, the first line reads mode 0 instead of OAM scan
+func (ppu *PPU) turnOn() {
+	ppu.line = 0
+	ppu.ly = 0
+	ppu.compare = 0
+	ppu.dots = 0
+	ppu.firstOn = true
+	ppu.windowY = ppu.register(WY_ADDRESS) == 0
+	ppu.windowLine = 0
+	ppu.setMode(MODE_HBLANK)
+}
+
+// coincidence reports whether LY=LYC, as STAT sees it
+func (ppu *PPU) coincidence() bool {
+	return ppu.compare == int16(ppu.register(LYC_ADDRESS))
+}
+
+// writeSTATBug emulates writes to STAT on DMG family models, which act as if every interrupt
+// were enabled for a moment, requesting a STAT interrupt during HBlank, VBlank or LY=LYC
+func (ppu *PPU) writeSTATBug() {
+	if ppu.console.model.IsCGB() || !ppu.enabled() || ppu.statLine {
+		return
+	}
+
+	if ppu.mode == MODE_HBLANK || ppu.mode == MODE_VBLANK || ppu.coincidence() {
+		ppu.mem.RequestInterrupt(INT_STAT)
+	}
+}
+
+// updateSTAT requests the STAT interrupt on the rising edge of the STAT line
+func (ppu *PPU) updateSTAT() {
+	if !ppu.enabled() {
+		return
+	}
+
+	stat := ppu.register(STAT_ADDRESS)
+	line := stat&STAT_LYC != 0 && ppu.coincidence()
+
+	switch ppu.mode {
+	case MODE_HBLANK:
+		// the mode 0 of the first line after turning the LCD on doesn't
+		line = line || (stat&STAT_HBLANK != 0 && !ppu.firstOn)
+	case MODE_VBLANK:
+		// the OAM scan interrupt also fires at the start of VBlank
+		line = line || stat&STAT_VBLANK != 0 || (ppu.line == SCREEN_HEIGHT && ppu.dots == 0 && stat&STAT_OAM_SCAN != 0)
+	case MODE_OAM_SCAN:
+		line = line || stat&STAT_OAM_SCAN != 0
+	}
+
+	if line && !ppu.statLine {
+		ppu.mem.RequestInterrupt(INT_STAT)
+	}
+	ppu.statLine = line
+}
+
+func (ppu *PPU) setMode(mode uint8) {
+	ppu.mode = mode
+	ppu.updateSTAT()
+}
+
+// Step advances the PPU by a number of dots
+func (ppu *PPU) Step(dots int) {
+	if !ppu.enabled() {
+		return
+	}
+
+	for ; dots > 0; dots-- {
+		ppu.dot()
+	}
+}
+
+// dot advances the PPU by a single dot, changing modes and lines at their boundaries
+func (ppu *PPU) dot() {
+	ppu.dots++
+
+	if ppu.line < SCREEN_HEIGHT {
+		switch {
+		case (ppu.mode == MODE_OAM_SCAN || ppu.firstOn) && ppu.dots == OAM_SCAN_DOTS:
+			ppu.firstOn = false
+			ppu.scanOAM()
+			ppu.setMode(MODE_DRAW)
+			ppu.renderer.startLine()
+		case ppu.mode == MODE_DRAW:
+			if ppu.renderer.dot() {
+				ppu.setMode(MODE_HBLANK)
+			}
+		}
+	} else if ppu.line == LINES_PER_FRAME-1 {
+		ppu.line153()
+	}
+
+	if ppu.dots < DOTS_PER_LINE {
+		return
+	}
+
+	// next line
+	ppu.dots = 0
+	ppu.line++
+	if ppu.line == LINES_PER_FRAME {
+		ppu.line = 0
+	}
+	ppu.ly = ppu.line
+	ppu.compare = int16(ppu.line)
+
+	switch {
+	case ppu.line < SCREEN_HEIGHT:
+		if ppu.ly == ppu.register(WY_ADDRESS) {
+			ppu.windowY = true
+		}
+		ppu.setMode(MODE_OAM_SCAN)
+	case ppu.line == SCREEN_HEIGHT:
+		ppu.windowY = false
+		ppu.windowLine = 0
+		ppu.finishFrame()
+		ppu.mem.RequestInterrupt(INT_VBLANK)
+		ppu.setMode(MODE_VBLANK)
+	default:
+		ppu.updateSTAT()
+	}
+}
+
+// line 153 timing, the dots LY reads 0, LYC stops matching 153 and LYC starts matching 0 at
+type line153Timing struct {
+	ly, noCompare, compare int
+}
+
+var dmgLine153 = line153Timing{4, 8, 12}
+var cgbLine153 = line153Timing{8, 12, 12}
+
+// line153 resets LY and the line LYC is compared with early, at the dots the model does
+func (ppu *PPU) line153() {
+	timing := dmgLine153
+	if ppu.console.model.IsCGB() {
+		timing = cgbLine153
+	}
+
+	switch ppu.dots {
+	case timing.ly:
+		ppu.ly = 0
+	case timing.noCompare:
+		ppu.compare = -1
+	}
+	if ppu.dots == timing.compare {
+		ppu.compare = 0
+	}
+
+	if ppu.dots == timing.ly || ppu.dots == timing.noCompare || ppu.dots == timing.compare {
+		ppu.updateSTAT()
+	}
+}
+
+// finishFrame makes the frame that was just drawn the one shown, the next frame is drawn over the old one
+func (ppu *PPU) finishFrame() {
+	ppu.frameMu.Lock()
+	ppu.frame, ppu.buffer = ppu.buffer, ppu.frame
+	ppu.frameMu.Unlock()
+	ppu.finished = true
+}
+
+// FrameReady reports whether a frame was finished since it was last called
+func (ppu *PPU) FrameReady() bool {
+	ready := ppu.finished
+	ppu.finished = false
+	return ready
+}
+
+// withFrame calls f with the last finished frame, which doesn't change until f returns,
+// it's safe to call while the PPU runs on another goroutine
+func (ppu *PPU) withFrame(f func(frame *screen)) {
+	ppu.frameMu.Lock()
+	defer ppu.frameMu.Unlock()
+	f(ppu.frame)
+}
+
+// <----------------------------- RENDERING -----------------------------> //
+
+// windowEnabled reports whether the window is drawn on the current line, once it reaches WX.
+// On DMG LCDC bit 0 hides it along with the BG
+func (ppu *PPU) windowEnabled() bool {
+	lcdc := ppu.register(LCDC_ADDRESS)
+	return lcdc&LCDC_WINDOW_ENABLE != 0 && (lcdc&LCDC_BG_ENABLE != 0 || ppu.console.cgbMode()) && ppu.windowY
+}
+
+// scanOAM finds the first 10 sprites in OAM that are on the current line
+func (ppu *PPU) scanOAM() {
+	height := uint8(8)
+	if ppu.register(LCDC_ADDRESS)&LCDC_SPRITE_SIZE != 0 {
+		height = 16
+	}
+
+	ppu.spriteCount = 0
+	for index := 0; index < 40 && ppu.spriteCount < MAX_SPRITES_PER_LINE; index++ {
+		entry := ppu.mem.oam[index*4 : index*4+4]
+
+		// sprite Y is the top line + 16
+		line := int(ppu.line) + 16
+		if line < int(entry[0]) || line >= int(entry[0])+int(height) {
+			continue
+		}
+
+		ppu.sprites[ppu.spriteCount] = sprite{y: entry[0], x: entry[1], tile: entry[2], attrs: entry[3], index: uint8(index)}
+		ppu.spriteCount++
+	}
+}
+
+// spriteRow returns the low and high bytes of the sprite's row on the current line, flipped as needed,
+// so bit 7 is always the leftmost pixel
+func (ppu *PPU) spriteRow(s sprite) (uint8, uint8) {
+	height := uint8(8)
+	tile := s.tile
+	if ppu.register(LCDC_ADDRESS)&LCDC_SPRITE_SIZE != 0 {
+		height = 16
+		tile &= 0xFE
+	}
+
+	row := ppu.line + 16 - s.y
+	if s.attrs&SPRITE_Y_FLIP != 0 {
+		row = height - 1 - row
+	}
+
+	address := uint16(tile)*16 + uint16(row)*2
+	if ppu.console.cgbMode() && s.attrs&SPRITE_BANK != 0 {
+		address += VRAM_BANK_SIZE
+	}
+	low := ppu.mem.vram[address]
+	high := ppu.mem.vram[address+1]
+
+	if s.attrs&SPRITE_X_FLIP != 0 {
+		low = reverseBits(low)
+		high = reverseBits(high)
+	}
+
+	return low, high
+}
+
+// spritePixel returns the pixel of a sprite's row, column 0 is the leftmost
+func (ppu *PPU) spritePixel(s sprite, low uint8, high uint8, column uint8) pixel {
+	p := pixel{color: rowPixel(low, high, column), index: s.index}
+	if ppu.console.cgbMode() {
+		p.palette = s.attrs & SPRITE_CGB_PALETTE
+	} else if s.attrs&SPRITE_PALETTE != 0 {
+		p.palette = 1
+	}
+	if s.attrs&SPRITE_PRIORITY != 0 {
+		p.priority = 1
+	}
+	return p
+}
+
+func reverseBits(value uint8) uint8 {
+	var result uint8
+	for i := 0; i < 8; i++ {
+		result = result<<1 | value&1
+		value >>= 1
+	}
+	return result
+}
+
+// rowPixel returns the color number (0 - 3) at column of a tile row, column 0 is the leftmost
+func rowPixel(low uint8, high uint8, column uint8) uint8 {
+	bit := 7 - column
+	return (high>>bit&1)<<1 | low>>bit&1
+}
+
+// tileMapAddress returns the address of the tile number at column and row of the BG (or window) tile map
+func (ppu *PPU) tileMapAddress(window bool, column uint8, row uint8) uint16 {
+	bit := uint8(LCDC_BG_MAP)
+	if window {
+		bit = LCDC_WINDOW_MAP
+	}
+
+	tileMap := uint16(0x9800)
+	if ppu.register(LCDC_ADDRESS)&bit != 0 {
+		tileMap = 0x9C00
+	}
+
+	return tileMap + uint16(row%32)*32 + uint16(column%32)
+}
+
+// tileMapEntry returns the tile number and the CGB attributes at a tile map address,
+// the attributes are 0 outside CGB mode
+func (ppu *PPU) tileMapEntry(address uint16) (uint8, uint8) {
+	tile := ppu.mem.vram[address-ROM_END]
+	if !ppu.console.cgbMode() {
+		return tile, 0
+	}
+	return tile, ppu.mem.vram[VRAM_BANK_SIZE+address-ROM_END]
+}
+
+// tileAddress returns the address of a BG or window tile, with the tile data area LCDC selects
+func (ppu *PPU) tileAddress(tile uint8) uint16 {
+	if ppu.register(LCDC_ADDRESS)&LCDC_TILE_DATA != 0 {
+		return 0x8000 + uint16(tile)*16
+	}
+	return uint16(0x9000 + int(int8(tile))*16)
+}
+
+// tileRowIndex returns where in VRAM the row of a BG or window tile starts, taking the CGB attributes'
+// bank and Y flip into account, the high byte follows the low one
+func (ppu *PPU) tileRowIndex(tile uint8, attrs uint8, row uint8) uint16 {
+	if attrs&BG_Y_FLIP != 0 {
+		row = 7 - row
+	}
+
+	index := ppu.tileAddress(tile) + uint16(row)*2 - ROM_END
+	if attrs&BG_BANK != 0 {
+		index += VRAM_BANK_SIZE
+	}
+	return index
+}
+
+// tilePixel returns the BG or window pixel at column of a tile row, flipped as the CGB attributes say
+func tilePixel(low uint8, high uint8, attrs uint8, column uint8) pixel {
+	if attrs&BG_X_FLIP != 0 {
+		column = 7 - column
+	}
+	return pixel{
+		color:    rowPixel(low, high, column),
+		palette:  attrs & BG_PALETTE,
+		priority: attrs & BG_PRIORITY >> 7,
+	}
+}
+
+// drawPixel mixes the BG (or window) and sprite pixels at x on the current line and draws the result,
+// obj has color 0 where there's no sprite
+func (ppu *PPU) drawPixel(x uint8, bg pixel, obj pixel) {
+	lcdc := ppu.register(LCDC_ADDRESS)
+	cgb := ppu.console.cgbMode()
+
+	// LCDC bit 0 blanks the BG and window on DMG, in CGB mode it takes away their priority instead
+	if !cgb && lcdc&LCDC_BG_ENABLE == 0 {
+		bg.color = 0
+	}
+
+	drawSprite := obj.color != 0 && lcdc&LCDC_SPRITE_ENABLE != 0
+	if drawSprite && bg.color != 0 {
+		if cgb {
+			drawSprite = lcdc&LCDC_BG_ENABLE == 0 || (bg.priority == 0 && obj.priority == 0)
+		} else {
+			drawSprite = obj.priority == 0
+		}
+	}
+
+	var shade uint8
+	var color uint16
+	if drawSprite {
+		shade, color = ppu.spriteColor(obj)
+	} else {
+		shade, color = ppu.bgColor(bg)
+	}
+
+	ppu.buffer.shades[ppu.line][x] = shade
+	ppu.buffer.colors[ppu.line][x] = color
+}
+
+// bgColor returns the shade and the CGB color of a BG or window pixel
+func (ppu *PPU) bgColor(p pixel) (uint8, uint16) {
+	if ppu.console.cgbMode() {
+		return p.color, paletteColor(&ppu.bgPalettes, p.palette, p.color)
+	}
+
+	shade := shade(ppu.register(BGP_ADDRESS), p.color)
+	if !ppu.console.model.IsCGB() {
+		return shade, 0
+	}
+	// DMG mode on CGB, the shade picks a color of the first BG palette
+	return shade, paletteColor(&ppu.bgPalettes, 0, shade)
+}
+
+// spriteColor returns the shade and the CGB color of a sprite pixel
+func (ppu *PPU) spriteColor(p pixel) (uint8, uint16) {
+	if ppu.console.cgbMode() {
+		return p.color, paletteColor(&ppu.objPalettes, p.palette, p.color)
+	}
+
+	palette := ppu.register(OBP0_ADDRESS)
+	if p.palette != 0 {
+		palette = ppu.register(OBP1_ADDRESS)
+	}
+
+	shade := shade(palette, p.color)
+	if !ppu.console.model.IsCGB() {
+		return shade, 0
+	}
+	// DMG mode on CGB, OBP0 and OBP1 pick colors of the first two sprite palettes
+	return shade, paletteColor(&ppu.objPalettes, p.palette, shade)
+}
+
+// shade maps a color number through a DMG palette register
+func shade(palette uint8, color uint8) uint8 {
+	return palette >> (color * 2) & 0x03
+}
+
+// <----------------------------- OAM BUG -----------------------------> //
+
+/*
+On the DMG family, reads and writes at FE00 - FEFF and 16-bit increments and decrements of a register holding
+such an address corrupt OAM while the PPU scans it. OAM is scanned as 20 rows of 8 bytes, 4 dots each, and
+the row being scanned gets overwritten with a mix of itself and the row before it. Row 0 is never corrupted.
+Accesses use the row the PPU was on at the start of the instruction rather than at the access's own cycle.
+*/
+
+const OAM_ROW_SIZE = 8
+const OAM_ROWS = 20
+
+// oamBugRow returns the row of OAM an access at the address corrupts, 0 for none
+func (ppu *PPU) oamBugRow(address uint16) int {
+	if address < ECHO_END || address >= UNUSED_END || ppu.console.model.IsCGB() {
+		return 0
+	}
+	if !ppu.enabled() || ppu.mode != MODE_OAM_SCAN {
+		return 0
+	}
+	return ppu.dots / 4
+}
+
+// oamWord reads one of the 4 little endian words of an OAM row
+func (ppu *PPU) oamWord(row int, word int) uint16 {
+	i := row*OAM_ROW_SIZE + word*2
+	return uint16(ppu.mem.oam[i]) | uint16(ppu.mem.oam[i+1])<<8
+}
+
+func (ppu *PPU) setOAMWord(row int, word int, value uint16) {
+	i := row*OAM_ROW_SIZE + word*2
+	ppu.mem.oam[i] = uint8(value)
+	ppu.mem.oam[i+1] = uint8(value >> 8)
+}
+
+// copyOAMRow copies a row from its first byte on
+func (ppu *PPU) copyOAMRow(to int, from int, first int) {
+	copy(ppu.mem.oam[to*OAM_ROW_SIZE+first:(to+1)*OAM_ROW_SIZE], ppu.mem.oam[from*OAM_ROW_SIZE+first:(from+1)*OAM_ROW_SIZE])
+}
+
+// oamBugWrite corrupts OAM for a write or an increment/decrement of the address
+func (ppu *PPU) oamBugWrite(address uint16) {
+	row := ppu.oamBugRow(address)
+	if row == 0 {
+		return
+	}
+
+	a, b, c := ppu.oamWord(row, 0), ppu.oamWord(row-1, 0), ppu.oamWord(row-1, 2)
+	ppu.setOAMWord(row, 0, ((a^c)&(b^c))^c)
+	ppu.copyOAMRow(row, row-1, 2)
+}
+
+// oamBugRead corrupts OAM for a read of the address
+func (ppu *PPU) oamBugRead(address uint16) {
+	row := ppu.oamBugRow(address)
+	if row == 0 {
+		return
+	}
+
+	a, b, c := ppu.oamWord(row, 0), ppu.oamWord(row-1, 0), ppu.oamWord(row-1, 2)
+	ppu.setOAMWord(row, 0, b|(a&c))
+	ppu.copyOAMRow(row, row-1, 2)
+}
+
+// oamBugIncrease corrupts OAM for a read and an increment of the address in the same cycle, which spreads
+// the row before to the rows around it. The read that follows does the read corruption
+func (ppu *PPU) oamBugIncrease(address uint16) {
+	row := ppu.oamBugRow(address)
+	if row < 4 || row >= OAM_ROWS-1 {
+		return
+	}
+
+	a, b, c, d := ppu.oamWord(row-2, 0), ppu.oamWord(row-1, 0), ppu.oamWord(row, 0), ppu.oamWord(row-1, 2)
+	ppu.setOAMWord(row-1, 0, (b&(a|c|d))|(a&c&d))
+	ppu.copyOAMRow(row, row-1, 0)
+	ppu.copyOAMRow(row-2, row-1, 0)
+}
+
+// <----------------------------- SAVE STATES -----------------------------> //
+
+// the PPU's registers and memory are saved with the memory map, the renderer saves its own progress
+
+func (ppu *PPU) saveState(s *stateWriter) {
+	s.write(ppu.kind, ppu.line, ppu.ly, ppu.compare, ppu.firstOn, int32(ppu.dots), ppu.mode, ppu.statLine)
+	s.write(&ppu.buffer.shades, &ppu.buffer.colors, &ppu.frame.shades, &ppu.frame.colors)
+	s.write(&ppu.bgPalettes, &ppu.objPalettes)
+	s.write(ppu.windowY, ppu.windowLine, ppu.spriteCount)
+	for _, sprite := range ppu.sprites {
+		s.write(sprite.y, sprite.x, sprite.tile, sprite.attrs, sprite.index)
+	}
+	ppu.renderer.saveState(s)
+}
+
+func (ppu *PPU) loadState(s *stateReader) {
+	// the renderers save different progress through mode 3
+	var kind Renderer
+	if s.read(&kind); s.err == nil && kind != ppu.kind {
+		s.err = fmt.Errorf("saved with the %v renderer, this console uses the %v renderer", kind, ppu.kind)
+		return
+	}
+
+	ppu.frameMu.Lock()
+	defer ppu.frameMu.Unlock()
+
+	var dots int32
+	s.read(&ppu.line, &ppu.ly, &ppu.compare, &ppu.firstOn, &dots, &ppu.mode, &ppu.statLine)
+	s.read(&ppu.buffer.shades, &ppu.buffer.colors, &ppu.frame.shades, &ppu.frame.colors)
+	s.read(&ppu.bgPalettes, &ppu.objPalettes)
+	s.read(&ppu.windowY, &ppu.windowLine, &ppu.spriteCount)
+	for i := range ppu.sprites {
+		sprite := &ppu.sprites[i]
+		s.read(&sprite.y, &sprite.x, &sprite.tile, &sprite.attrs, &sprite.index)
+	}
+	ppu.renderer.loadState(s)
+	ppu.dots = int(dots)
 }
@@ -0,0 +1,308 @@
+package gb
+
+import "testing"
+
+// lcdOn turns the LCD off and back on, which starts line 0 at dot 0
+func lcdOn(c *Console) {
+	c.mem.Write8(LCDC_ADDRESS, 0x00)
+	c.mem.Write8(LCDC_ADDRESS, 0x91)
+}
+
+// stepTo steps the PPU from line 0 dot 0 to a dot of a line
+func stepTo(c *Console, line int, dot int) {
+	c.ppu.Step(line*DOTS_PER_LINE + dot)
+}
+
+// matches reports whether STAT's coincidence flag is set with LYC at the line
+func matches(c *Console, lyc uint8) bool {
+	c.mem.Write8(LYC_ADDRESS, lyc)
+	return c.mem.Read8(STAT_ADDRESS)&0x04 != 0
+}
+
+func TestLine153(t *testing.T) {
+	tests := []struct {
+		model Model
+		dot   int
+		ly    uint8
+		is153 bool // LYC=153 matches
+		is0   bool // LYC=0 matches
+	}{
+		{MODEL_DMG, 0, 153, true, false},
+		{MODEL_DMG, 4, 0, true, false},
+		{MODEL_DMG, 8, 0, false, false},
+		{MODEL_DMG, 12, 0, false, true},
+		{MODEL_CGB, 0, 153, true, false},
+		{MODEL_CGB, 4, 153, true, false},
+		{MODEL_CGB, 8, 0, true, false},
+		{MODEL_CGB, 12, 0, false, true},
+	}
+
+	for _, test := range tests {
+		c := newROMConsole(t, testROM(0x00, 0x00), test.model)
+		lcdOn(c)
+		stepTo(c, LINES_PER_FRAME-1, test.dot)
+
+		if ly := c.mem.Read8(LY_ADDRESS); ly != test.ly {
+			t.Errorf("%v dot %d: LY = %d, want %d", test.model, test.dot, ly, test.ly)
+		}
+		if got := matches(c, 153); got != test.is153 {
+			t.Errorf("%v dot %d: LYC=153 match = %v, want %v", test.model, test.dot, got, test.is153)
+		}
+		if got := matches(c, 0); got != test.is0 {
+			t.Errorf("%v dot %d: LYC=0 match = %v, want %v", test.model, test.dot, got, test.is0)
+		}
+	}
+}
+
+func TestLCDEnable(t *testing.T) {
+	tests := []struct {
+		line, dot int
+		mode      uint8
+		stat      bool // STAT interrupt requested, for mode 2
+	}{
+		{0, 0, MODE_HBLANK, false},
+		{0, 79, MODE_HBLANK, false},
+		{0, 80, MODE_DRAW, false},
+		{1, 0, MODE_OAM_SCAN, true},
+	}
+
+	for _, test := range tests {
+		c := newTestConsole(t)
+		c.mem.Write8(STAT_ADDRESS, STAT_OAM_SCAN)
+		lcdOn(c)
+		c.mem.ClearInterrupt(INT_STAT)
+		stepTo(c, test.line, test.dot)
+
+		if mode := c.mem.Read8(STAT_ADDRESS) & 0x03; mode != test.mode {
+			t.Errorf("line %d dot %d: mode = %d, want %d", test.line, test.dot, mode, test.mode)
+		}
+		if got := c.mem.io[IF_ADDRESS-UNUSED_END]&(1<<INT_STAT) != 0; got != test.stat {
+			t.Errorf("line %d dot %d: STAT interrupt = %v, want %v", test.line, test.dot, got, test.stat)
+		}
+	}
+}
+
+func TestOAMBug(t *testing.T) {
+	tests := []struct {
+		name    string
+		model   Model
+		dot     int
+		code    []uint8
+		corrupt bool // row 5 is overwritten with row 4
+	}{
+		{"INC HL", MODEL_DMG, 20, []uint8{0x23}, true},
+		{"DEC HL", MODEL_DMG, 20, []uint8{0x2B}, true},
+		{"LD (HL),A", MODEL_DMG, 20, []uint8{0x77}, true},
+		{"INC HL in mode 3", MODEL_DMG, 100, []uint8{0x23}, false},
+		{"INC HL on CGB", MODEL_CGB, 20, []uint8{0x23}, false},
+		{"INC HL in row 0", MODEL_DMG, 0, []uint8{0x23}, false},
+	}
+
+	for _, test := range tests {
+		c := newROMConsole(t, testROM(0x00, 0x00), test.model)
+		lcdOn(c)
+		// the first line after turning the LCD on doesn't scan OAM
+		stepTo(c, 1, test.dot)
+		for i := range c.mem.oam {
+			c.mem.oam[i] = uint8(i)
+		}
+		c.cpu.regs.SetHL(0xFE10)
+		runAt(c, test.code...)
+
+		for i := 0; i < OAM_ROW_SIZE; i++ {
+			want := uint8(5*OAM_ROW_SIZE + i)
+			if test.corrupt {
+				want = uint8(4*OAM_ROW_SIZE + i)
+			}
+			if got := c.mem.oam[5*OAM_ROW_SIZE+i]; got != want {
+				t.Errorf("%s: OAM %02X = %02X, want %02X", test.name, 5*OAM_ROW_SIZE+i, got, want)
+			}
+		}
+	}
+}
+
+var renderers = []Renderer{RENDERER_SCANLINE, RENDERER_FIFO}
+
+// newRendererConsole creates a DMG drawing with the renderer
+func newRendererConsole(t *testing.T, renderer Renderer) *Console {
+	t.Helper()
+	return newConsole(loadTestCart(t, testROM(0x00, 0x00)), nil, MODEL_DMG, renderer)
+}
+
+func TestLY(t *testing.T) {
+	tests := []struct {
+		line, dot int
+		ly        uint8
+	}{
+		{0, 0, 0},
+		{0, 455, 0},
+		{1, 0, 1},
+		{143, 455, 143},
+		{144, 0, 144},
+		{152, 455, 152},
+		{153, 0, 153},
+		{153, 3, 153},
+		{153, 4, 0},
+		{153, 455, 0},
+		{LINES_PER_FRAME, 0, 0},
+		{LINES_PER_FRAME + 1, 0, 1},
+	}
+
+	for _, tt := range tests {
+		c := newTestConsole(t)
+		lcdOn(c)
+		stepTo(c, tt.line, tt.dot)
+
+		if ly := c.mem.Read8(LY_ADDRESS); ly != tt.ly {
+			t.Errorf("line %d dot %d: LY = %d, want %d", tt.line, tt.dot, ly, tt.ly)
+		}
+	}
+}
+
+// statRequests steps the PPU from the LCD being turned on to the end of line last,
+// returning the line and dot of each STAT interrupt from line first on
+func statRequests(c *Console, first int, last int) [][2]int {
+	var requests [][2]int
+	for i := 1; i < (last+1)*DOTS_PER_LINE; i++ {
+		c.ppu.Step(1)
+		if c.mem.Read8(IF_ADDRESS)&(1<<INT_STAT) == 0 {
+			continue
+		}
+		c.mem.ClearInterrupt(INT_STAT)
+
+		if int(c.ppu.line) >= first {
+			requests = append(requests, [2]int{int(c.ppu.line), c.ppu.dots})
+		}
+	}
+	return requests
+}
+
+func TestSTATInterrupts(t *testing.T) {
+	tests := []struct {
+		name        string
+		stat        uint8
+		lyc         uint8
+		first, last int
+		requests    [][2]int // line and dot
+	}{
+		{"HBlank", STAT_HBLANK, 0xFF, 0, 2, [][2]int{{0, 252}, {1, 252}, {2, 252}}},
+		{"OAM scan", STAT_OAM_SCAN, 0xFF, 0, 2, [][2]int{{1, 0}, {2, 0}}},
+		{"HBlank blocks OAM scan", STAT_HBLANK | STAT_OAM_SCAN, 0xFF, 0, 2, [][2]int{{0, 252}, {1, 252}, {2, 252}}},
+		{"LYC", STAT_LYC, 1, 0, 2, [][2]int{{1, 0}}},
+		{"LYC blocks HBlank", STAT_LYC | STAT_HBLANK, 1, 0, 2, [][2]int{{0, 252}, {2, 252}}},
+		{"VBlank", STAT_VBLANK, 0xFF, 140, 153, [][2]int{{144, 0}}},
+		{"OAM scan at VBlank", STAT_OAM_SCAN, 0xFF, 143, 153, [][2]int{{143, 0}, {144, 0}}},
+		{"LYC 153", STAT_LYC, 153, 150, 153, [][2]int{{153, 0}}},
+		{"LYC 0 on line 153", STAT_LYC, 0, 150, 153, [][2]int{{153, 12}}},
+	}
+
+	for _, tt := range tests {
+		c := newTestConsole(t)
+		c.mem.Write8(LYC_ADDRESS, tt.lyc)
+		c.mem.Write8(STAT_ADDRESS, tt.stat)
+		lcdOn(c)
+		c.mem.ClearInterrupt(INT_STAT)
+
+		requests := statRequests(c, tt.first, tt.last)
+		if len(requests) != len(tt.requests) {
+			t.Errorf("%s: requests at %v, want %v", tt.name, requests, tt.requests)
+			continue
+		}
+		for i := range requests {
+			if requests[i] != tt.requests[i] {
+				t.Errorf("%s: requests at %v, want %v", tt.name, requests, tt.requests)
+				break
+			}
+		}
+	}
+}
+
+func TestMode3Length(t *testing.T) {
+	tests := []struct {
+		renderer Renderer
+		scx      uint8
+		end      int // first dot of HBlank
+	}{
+		{RENDERER_SCANLINE, 0, OAM_SCAN_DOTS + DRAW_DOTS},
+		{RENDERER_SCANLINE, 3, OAM_SCAN_DOTS + DRAW_DOTS},
+		{RENDERER_FIFO, 0, OAM_SCAN_DOTS + DRAW_DOTS},
+		{RENDERER_FIFO, 3, OAM_SCAN_DOTS + DRAW_DOTS + 3},
+	}
+
+	for _, tt := range tests {
+		for _, line := range []int{0, 1} {
+			c := newRendererConsole(t, tt.renderer)
+			c.mem.Write8(SCX_ADDRESS, tt.scx)
+			lcdOn(c)
+			stepTo(c, line, 0)
+
+			modes := []struct {
+				dot  int
+				mode uint8
+			}{
+				{OAM_SCAN_DOTS - 1, MODE_OAM_SCAN},
+				{OAM_SCAN_DOTS, MODE_DRAW},
+				{tt.end - 1, MODE_DRAW},
+				{tt.end, MODE_HBLANK},
+				{DOTS_PER_LINE - 1, MODE_HBLANK},
+			}
+			if line == 0 {
+				// the first line after turning the LCD on reads mode 0 during OAM scan
+				modes[0].mode = MODE_HBLANK
+			}
+
+			dot := 0
+			for _, m := range modes {
+				c.ppu.Step(m.dot - dot)
+				dot = m.dot
+
+				if mode := c.mem.Read8(STAT_ADDRESS) & 0x03; mode != m.mode {
+					t.Errorf("renderer %d SCX %d line %d dot %d: mode %d, want %d", tt.renderer, tt.scx, line, m.dot, mode, m.mode)
+				}
+			}
+		}
+	}
+}
+
+func TestWindowLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		wy, wx  uint8
+		off, on int // lines the window is turned off and back on at, -1 for never
+		lines   int // lines drawn
+		want    uint8
+	}{
+		{"whole lines", 0, 7, -1, -1, 10, 10},
+		{"WY 4", 4, 7, -1, -1, 10, 6},
+		{"WY after the lines", 20, 7, -1, -1, 10, 0},
+		{"WX 166", 0, 166, -1, -1, 10, 10},
+		{"WX off screen", 0, 167, -1, -1, 10, 0},
+		{"turned off", 0, 7, 5, -1, 10, 5},
+		{"turned back on", 0, 7, 5, 8, 10, 7},
+		{"reset by VBlank", 0, 7, -1, -1, LINES_PER_FRAME + 2, 2},
+	}
+
+	for _, renderer := range renderers {
+		for _, tt := range tests {
+			c := newRendererConsole(t, renderer)
+			c.mem.Write8(WY_ADDRESS, tt.wy)
+			c.mem.Write8(WX_ADDRESS, tt.wx)
+			lcdOn(c)
+			c.mem.Write8(LCDC_ADDRESS, 0x91|LCDC_WINDOW_ENABLE)
+
+			for line := 0; line < tt.lines; line++ {
+				switch line {
+				case tt.off:
+					c.mem.Write8(LCDC_ADDRESS, 0x91)
+				case tt.on:
+					c.mem.Write8(LCDC_ADDRESS, 0x91|LCDC_WINDOW_ENABLE)
+				}
+				c.ppu.Step(DOTS_PER_LINE)
+			}
+
+			if c.ppu.windowLine != tt.want {
+				t.Errorf("renderer %d %s: window line %d, want %d", renderer, tt.name, c.ppu.windowLine, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package gb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+/*
+
+Real Time Clock (MBC3, also used by HuC3 and TAMA5 style carts)
+
+	08 - Seconds   0-59 (0x00 - 0x3B)
+	09 - Minutes   0-59 (0x00 - 0x3B)
+	0A - Hours     0-23 (0x00 - 0x17)
+	0B - Day counter, lower 8 bits
+	0C - Day counter upper bit, halt and day carry
+		Bit 0 - Bit 8 of the day counter
+		Bit 6 - Halt (0 = active, 1 = stop timer)
+		Bit 7 - Day counter carry (1 = counter overflowed)
+
+The registers are only readable after latching, which copies the running clock into the latched copy.
+
+By default the clock advances with emulated cycles, so it is deterministic and stops when the emulator does.
+With a Clock set it follows wall-clock time instead.
+
+*/
+
+const RTC_SAVE_SIZE = 48
+
+// Clock provides the current time, so wall-clock RTCs can be driven by a fake clock in tests
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the host's wall clock
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+type RTC struct {
+	regs    [5]uint8  // running seconds, minutes, hours, day low, day high
+	latched [5]uint8  // copy of regs made by the last latch
+	cycles  uint32    // cycles since the last second, when running off emulated cycles
+	clock   Clock     // wall clock, nil when running off emulated cycles
+	synced  time.Time // wall clock time the registers are up to date with
+	saved   time.Time // timestamp of a save loaded without a clock, for a clock set later to catch up from
+}
+
+const (
+	RTC_SECONDS = iota
+	RTC_MINUTES
+	RTC_HOURS
+	RTC_DAY_LOW
+	RTC_DAY_HIGH
+)
+
+// writable bits of each register
+var rtcMasks = [5]uint8{0x3F, 0x3F, 0x1F, 0xFF, 0xC1}
+
+// SetClock switches the RTC to follow the given wall clock, nil goes back to emulated cycles.
+// After loading a save without a clock, the first clock set catches up on the time since the save
+func (rtc *RTC) SetClock(clock Clock) {
+	rtc.clock = clock
+	if clock == nil {
+		return
+	}
+
+	if rtc.saved.IsZero() {
+		rtc.synced = clock.Now()
+		return
+	}
+
+	rtc.synced = rtc.saved
+	rtc.saved = time.Time{}
+	rtc.sync()
+}
+
+func (rtc *RTC) halted() bool {
+	return rtc.regs[RTC_DAY_HIGH]&0x40 != 0
+}
+
+// Step advances the clock by emulated cycles, ignored when following the wall clock
+func (rtc *RTC) Step(ticks int) {
+	if rtc.clock != nil || rtc.halted() {
+		return
+	}
+
+	rtc.cycles += uint32(ticks)
+	for rtc.cycles >= CLOCK_SPEED {
+		rtc.cycles -= CLOCK_SPEED
+		rtc.tick()
+	}
+}
+
+// sync catches the registers up with the wall clock
+func (rtc *RTC) sync() {
+	if rtc.clock == nil {
+		return
+	}
+
+	now := rtc.clock.Now()
+	elapsed := now.Sub(rtc.synced)
+	if elapsed < time.Second {
+		return
+	}
+
+	seconds := uint64(elapsed / time.Second)
+	rtc.synced = rtc.synced.Add(time.Duration(seconds) * time.Second)
+
+	if !rtc.halted() {
+		rtc.advance(seconds)
+	}
+}
+
+// tick advances the clock by one second, out of range values count up to the register limit before wrapping
+func (rtc *RTC) tick() {
+	rtc.regs[RTC_SECONDS] = (rtc.regs[RTC_SECONDS] + 1) & 0x3F
+	if rtc.regs[RTC_SECONDS] != 60 {
+		return
+	}
+	rtc.regs[RTC_SECONDS] = 0
+
+	rtc.regs[RTC_MINUTES] = (rtc.regs[RTC_MINUTES] + 1) & 0x3F
+	if rtc.regs[RTC_MINUTES] != 60 {
+		return
+	}
+	rtc.regs[RTC_MINUTES] = 0
+
+	rtc.regs[RTC_HOURS] = (rtc.regs[RTC_HOURS] + 1) & 0x1F
+	if rtc.regs[RTC_HOURS] != 24 {
+		return
+	}
+	rtc.regs[RTC_HOURS] = 0
+
+	rtc.setDays(uint64(rtc.days()) + 1)
+}
+
+// advance moves the clock forward by a number of seconds
+func (rtc *RTC) advance(seconds uint64) {
+	// step one second at a time while any register holds an out of range value
+	for seconds > 0 && (rtc.regs[RTC_SECONDS] >= 60 || rtc.regs[RTC_MINUTES] >= 60 || rtc.regs[RTC_HOURS] >= 24) {
+		rtc.tick()
+		seconds--
+	}
+
+	total := uint64(rtc.regs[RTC_SECONDS]) + uint64(rtc.regs[RTC_MINUTES])*60 + uint64(rtc.regs[RTC_HOURS])*3600 + seconds
+
+	rtc.regs[RTC_SECONDS] = uint8(total % 60)
+	rtc.regs[RTC_MINUTES] = uint8(total / 60 % 60)
+	rtc.regs[RTC_HOURS] = uint8(total / 3600 % 24)
+	rtc.setDays(uint64(rtc.days()) + total/86400)
+}
+
+func (rtc *RTC) days() uint16 {
+	return uint16(rtc.regs[RTC_DAY_HIGH]&0x01)<<8 | uint16(rtc.regs[RTC_DAY_LOW])
+}
+
+// setDays sets the 9-bit day counter, setting the carry bit if it overflows
+func (rtc *RTC) setDays(days uint64) {
+	if days > 0x1FF {
+		rtc.regs[RTC_DAY_HIGH] |= 0x80
+		days &= 0x1FF
+	}
+
+	rtc.regs[RTC_DAY_LOW] = uint8(days)
+	rtc.regs[RTC_DAY_HIGH] = rtc.regs[RTC_DAY_HIGH]&0xFE | uint8(days>>8)
+}
+
+// Latch copies the running clock into the readable registers
+func (rtc *RTC) Latch() {
+	rtc.sync()
+	rtc.latched = rtc.regs
+}
+
+// Read returns a latched register, register is 0x08 - 0x0C
+func (rtc *RTC) Read(register uint8) uint8 {
+	return rtc.latched[register-0x08]
+}
+
+// Write sets a running register, register is 0x08 - 0x0C
+func (rtc *RTC) Write(register uint8, value uint8) {
+	rtc.sync()
+
+	index := register - 0x08
+	rtc.regs[index] = value & rtcMasks[index]
+
+	// writing seconds resets the sub-second counter
+	if index == RTC_SECONDS {
+		rtc.cycles = 0
+	}
+}
+
+// <----------------------------- SAVES -----------------------------> //
+
+/*
+
+RTC save trailer, appended to the battery save by VBA-M, BGB, mGBA, SameBoy and others (48 bytes, little endian)
+
+	00 - 13 Running seconds, minutes, hours, day low, day high (4 bytes each)
+	14 - 27 Latched seconds, minutes, hours, day low, day high (4 bytes each)
+	28 - 2F UNIX timestamp of the save (8 bytes)
+
+*/
+
+// Save returns the RTC state in the 48-byte trailer format
+func (rtc *RTC) Save() []uint8 {
+	rtc.sync()
+
+	data := make([]uint8, RTC_SAVE_SIZE)
+
+	for i := 0; i < 5; i++ {
+		binary.LittleEndian.PutUint32(data[i*4:], uint32(rtc.regs[i]))
+		binary.LittleEndian.PutUint32(data[20+i*4:], uint32(rtc.latched[i]))
+	}
+
+	binary.LittleEndian.PutUint64(data[40:], uint64(rtc.now().Unix()))
+
+	return data
+}
+
+// Load restores the RTC state from the 48-byte trailer format, following the wall clock it also
+// catches up on the time passed since the save, without one the timestamp is kept for SetClock
+func (rtc *RTC) Load(data []uint8) error {
+	if len(data) != RTC_SAVE_SIZE {
+		return fmt.Errorf("rtc save is %d bytes, expected %d", len(data), RTC_SAVE_SIZE)
+	}
+
+	for i := 0; i < 5; i++ {
+		rtc.regs[i] = uint8(binary.LittleEndian.Uint32(data[i*4:])) & rtcMasks[i]
+		rtc.latched[i] = uint8(binary.LittleEndian.Uint32(data[20+i*4:])) & rtcMasks[i]
+	}
+	rtc.cycles = 0
+
+	saved := time.Unix(int64(binary.LittleEndian.Uint64(data[40:])), 0)
+	if rtc.clock == nil {
+		rtc.saved = saved
+		return nil
+	}
+
+	rtc.synced = saved
+	rtc.saved = time.Time{}
+	rtc.sync()
+	return nil
+}
+
+func (rtc *RTC) now() time.Time {
+	if rtc.clock != nil {
+		return rtc.clock.Now()
+	}
+	return time.Now()
+}
+
+// save states use the trailer format plus the cycles towards the next second
+
+func (rtc *RTC) saveState(s *stateWriter) {
+	s.write(rtc.Save(), rtc.cycles)
+}
+
+func (rtc *RTC) loadState(s *stateReader) {
+	data := make([]uint8, RTC_SAVE_SIZE)
+	var cycles uint32
+	s.read(data, &cycles)
+	if s.err != nil {
+		return
+	}
+
+	s.err = rtc.Load(data)
+	rtc.cycles = cycles
+}
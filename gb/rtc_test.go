@@ -0,0 +1,189 @@
+package gb
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// fakeClock is a wall clock that only moves when told to
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+var clockStart = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+// newRTCMapper creates an MBC3 with an RTC following the clock, with the seconds register mapped at A000
+func newRTCMapper(clock Clock) *MBC3 {
+	cart := bankedCart(4, RAM_BANK_SIZE)
+	cart.Header.Type = 0x10
+	m := NewMBC3(cart)
+	m.SetClock(clock)
+	writeMapper(m, []mapperWrite{{0x0000, 0x0A}, {0x4000, 0x08}})
+	return m
+}
+
+func TestRTCLatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		latch   []uint8 // written to 6000 after 5 seconds
+		seconds uint8   // seconds read back
+	}{
+		{"no latch", nil, 0},
+		{"00 01", []uint8{0x00, 0x01}, 5},
+		{"01", []uint8{0x01}, 0},
+		{"01 00", []uint8{0x01, 0x00}, 0},
+		{"00 00 01", []uint8{0x00, 0x00, 0x01}, 5},
+		{"00 02 01", []uint8{0x00, 0x02, 0x01}, 0},
+	}
+
+	for _, test := range tests {
+		clock := &fakeClock{clockStart}
+		m := newRTCMapper(clock)
+		writeMapper(m, []mapperWrite{{0x6000, 0x00}, {0x6000, 0x01}})
+
+		clock.now = clock.now.Add(5 * time.Second)
+		for _, value := range test.latch {
+			m.WriteROM(0x6000, value)
+		}
+
+		if got := m.ReadRAM(0x0000); got != test.seconds {
+			t.Errorf("%s: seconds = %d, want %d", test.name, got, test.seconds)
+		}
+	}
+}
+
+func TestRTCLatchedWrite(t *testing.T) {
+	clock := &fakeClock{clockStart}
+	m := newRTCMapper(clock)
+
+	// writes go to the running clock, reads keep the latched value until the next latch
+	writeMapper(m, []mapperWrite{{0x6000, 0x00}, {0x6000, 0x01}, {0xA000, 30}})
+	if got := m.ReadRAM(0x0000); got != 0 {
+		t.Errorf("seconds before latching = %d, want 0", got)
+	}
+
+	writeMapper(m, []mapperWrite{{0x6000, 0x00}, {0x6000, 0x01}})
+	if got := m.ReadRAM(0x0000); got != 30 {
+		t.Errorf("seconds after latching = %d, want 30", got)
+	}
+}
+
+// rtcTrailer builds a 48-byte trailer with the same running and latched registers
+func rtcTrailer(regs [5]uint8, saved time.Time) []uint8 {
+	data := make([]uint8, RTC_SAVE_SIZE)
+	for i, value := range regs {
+		binary.LittleEndian.PutUint32(data[i*4:], uint32(value))
+		binary.LittleEndian.PutUint32(data[20+i*4:], uint32(value))
+	}
+	binary.LittleEndian.PutUint64(data[40:], uint64(saved.Unix()))
+	return data
+}
+
+func TestRTCSave(t *testing.T) {
+	clock := &fakeClock{clockStart}
+	rtc := &RTC{}
+	rtc.SetClock(clock)
+
+	for i, value := range []uint8{12, 34, 5, 0x67, 0x41} {
+		rtc.Write(0x08+uint8(i), value)
+	}
+	rtc.Latch()
+	rtc.Write(0x08, 13)
+
+	data := rtc.Save()
+	if len(data) != RTC_SAVE_SIZE {
+		t.Fatalf("trailer is %d bytes, want %d", len(data), RTC_SAVE_SIZE)
+	}
+
+	tests := []struct {
+		offset int
+		want   uint32
+	}{
+		{0x00, 13}, {0x04, 34}, {0x08, 5}, {0x0C, 0x67}, {0x10, 0x41},
+		{0x14, 12}, {0x18, 34}, {0x1C, 5}, {0x20, 0x67}, {0x24, 0x41},
+	}
+	for _, test := range tests {
+		if got := binary.LittleEndian.Uint32(data[test.offset:]); got != test.want {
+			t.Errorf("trailer %02X = %d, want %d", test.offset, got, test.want)
+		}
+	}
+	if got := int64(binary.LittleEndian.Uint64(data[40:])); got != clockStart.Unix() {
+		t.Errorf("timestamp = %d, want %d", got, clockStart.Unix())
+	}
+
+	loaded := &RTC{}
+	loaded.SetClock(clock)
+	if err := loaded.Load(data); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.regs != rtc.regs || loaded.latched != rtc.latched {
+		t.Errorf("loaded %v %v, want %v %v", loaded.regs, loaded.latched, rtc.regs, rtc.latched)
+	}
+
+	if err := loaded.Load(data[:RTC_SAVE_SIZE-4]); err == nil {
+		t.Error("loaded a 44-byte trailer")
+	}
+}
+
+func TestRTCCatchUp(t *testing.T) {
+	tests := []struct {
+		name       string
+		regs       [5]uint8 // seconds, minutes, hours, day low, day high
+		elapsed    time.Duration
+		clockAfter bool // set the clock after loading instead of before
+		want       [5]uint8
+	}{
+		{"none", [5]uint8{10, 20, 3, 4, 0}, 0, false, [5]uint8{10, 20, 3, 4, 0}},
+		{"seconds", [5]uint8{10, 20, 3, 4, 0}, 90 * time.Second, false, [5]uint8{40, 21, 3, 4, 0}},
+		{"days", [5]uint8{10, 20, 3, 4, 0}, 25 * time.Hour, false, [5]uint8{10, 20, 4, 5, 0}},
+		{"day high bit", [5]uint8{0, 0, 23, 0xFF, 0}, time.Hour, false, [5]uint8{0, 0, 0, 0x00, 0x01}},
+		{"day carry", [5]uint8{0, 0, 23, 0xFF, 0x01}, time.Hour, false, [5]uint8{0, 0, 0, 0x00, 0x80}},
+		{"halted", [5]uint8{10, 20, 3, 4, 0x40}, time.Hour, false, [5]uint8{10, 20, 3, 4, 0x40}},
+		{"clock set after loading", [5]uint8{10, 20, 3, 4, 0}, 90 * time.Second, true, [5]uint8{40, 21, 3, 4, 0}},
+	}
+
+	for _, test := range tests {
+		clock := &fakeClock{clockStart.Add(test.elapsed)}
+		rtc := &RTC{}
+		if !test.clockAfter {
+			rtc.SetClock(clock)
+		}
+		if err := rtc.Load(rtcTrailer(test.regs, clockStart)); err != nil {
+			t.Fatal(err)
+		}
+		if test.clockAfter {
+			rtc.SetClock(clock)
+		}
+
+		rtc.Latch()
+		if rtc.latched != test.want {
+			t.Errorf("%s: registers = %v, want %v", test.name, rtc.latched, test.want)
+		}
+	}
+}
+
+func TestRTCEmulatedCycles(t *testing.T) {
+	rtc := &RTC{}
+	if err := rtc.Load(rtcTrailer([5]uint8{58, 59, 23, 0, 0}, clockStart)); err != nil {
+		t.Fatal(err)
+	}
+
+	// without a clock, time only passes with emulated cycles
+	rtc.Step(int(CLOCK_SPEED) - 1)
+	rtc.Latch()
+	if rtc.latched[RTC_SECONDS] != 58 {
+		t.Errorf("seconds = %d before a second of cycles, want 58", rtc.latched[RTC_SECONDS])
+	}
+
+	rtc.Step(1)
+	rtc.Step(int(CLOCK_SPEED))
+	rtc.Latch()
+	if want := [5]uint8{0, 0, 0, 1, 0}; rtc.latched != want {
+		t.Errorf("registers = %v, want %v", rtc.latched, want)
+	}
+}
@@ -0,0 +1,132 @@
+package gb
+
+/*
+
+Scanline renderer
+
+Draws the whole line at the end of mode 3, from the registers as they are then. Mode 3 always takes 172 dots.
+
+*/
+
+type scanlineRenderer struct {
+	ppu  *PPU
+	dots int // dots into mode 3
+}
+
+func (r *scanlineRenderer) startLine() {
+	r.dots = 0
+}
+
+func (r *scanlineRenderer) dot() bool {
+	r.dots++
+	if r.dots < DRAW_DOTS {
+		return false
+	}
+
+	r.renderLine()
+	return true
+}
+
+// renderLine draws the current line into the buffer
+func (r *scanlineRenderer) renderLine() {
+	ppu := r.ppu
+	wx := int(ppu.register(WX_ADDRESS))
+
+	window := ppu.windowEnabled() && wx < SCREEN_WIDTH+7
+	sprites := r.lineSprites()
+
+	for x := 0; x < SCREEN_WIDTH; x++ {
+		var bg pixel
+		if window && x+7 >= wx {
+			bg = r.windowPixel(x + 7 - wx)
+		} else {
+			bg = r.backgroundPixel(x)
+		}
+
+		ppu.drawPixel(uint8(x), bg, r.spritePixel(sprites, x))
+	}
+
+	if window {
+		ppu.windowLine++
+	}
+}
+
+// backgroundPixel returns the background pixel at screen x on the current line
+func (r *scanlineRenderer) backgroundPixel(x int) pixel {
+	ppu := r.ppu
+
+	bgX := uint8(x) + ppu.register(SCX_ADDRESS)
+	bgY := ppu.line + ppu.register(SCY_ADDRESS)
+
+	return r.tilePixel(ppu.tileMapAddress(false, bgX/8, bgY/8), bgX%8, bgY%8)
+}
+
+// windowPixel returns the window pixel at window x on the current window line
+func (r *scanlineRenderer) windowPixel(x int) pixel {
+	ppu := r.ppu
+
+	windowX := uint8(x)
+	windowY := ppu.windowLine
+
+	return r.tilePixel(ppu.tileMapAddress(true, windowX/8, windowY/8), windowX%8, windowY%8)
+}
+
+// tilePixel returns the pixel at x, y of the tile at a tile map address
+func (r *scanlineRenderer) tilePixel(mapAddress uint16, x uint8, y uint8) pixel {
+	ppu := r.ppu
+
+	tile, attrs := ppu.tileMapEntry(mapAddress)
+	index := ppu.tileRowIndex(tile, attrs, y)
+	return tilePixel(ppu.mem.vram[index], ppu.mem.vram[index+1], attrs, x)
+}
+
+type lineSprite struct {
+	sprite
+	low, high uint8 // the sprite's row, bit 7 leftmost
+}
+
+// lineSprites returns the line's sprites with their rows, highest priority first. In CGB mode that's
+// OAM order, otherwise lower X wins, then the earlier sprite in OAM
+func (r *scanlineRenderer) lineSprites() []lineSprite {
+	ppu := r.ppu
+	sprites := make([]lineSprite, 0, MAX_SPRITES_PER_LINE)
+	byX := !ppu.console.cgbMode()
+
+	for _, s := range ppu.sprites[:ppu.spriteCount] {
+		low, high := ppu.spriteRow(s)
+
+		i := len(sprites)
+		sprites = append(sprites, lineSprite{})
+		for ; byX && i > 0 && sprites[i-1].x > s.x; i-- {
+			sprites[i] = sprites[i-1]
+		}
+		sprites[i] = lineSprite{s, low, high}
+	}
+
+	return sprites
+}
+
+// spritePixel returns the sprite pixel drawn at screen x, color 0 when no sprite is
+func (r *scanlineRenderer) spritePixel(sprites []lineSprite, x int) pixel {
+	for _, s := range sprites {
+		column := x - (int(s.x) - 8)
+		if column < 0 || column >= 8 {
+			continue
+		}
+
+		if p := r.ppu.spritePixel(s.sprite, s.low, s.high, uint8(column)); p.color != 0 {
+			return p
+		}
+	}
+	return pixel{}
+}
+
+func (r *scanlineRenderer) saveState(s *stateWriter) {
+	s.write(int32(r.dots))
+}
+
+func (r *scanlineRenderer) loadState(s *stateReader) {
+	var dots int32
+	s.read(&dots)
+	r.dots = int(dots)
+}
@@ -0,0 +1,231 @@
+package gb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+/*
+
+Save slots
+
+Each ROM gets STATE_SLOTS numbered slots in its own directory, named after the title and ROM checksum,
+e.g. <state dir>/POKEMON RED-9F7FDD53/slot3.state. The state dir defaults to GoGB/states in the user's config
+directory.
+
+A slot is a save state (see state.go) with two extra chunks, which LoadState skips:
+
+	META Timestamp (UNIX nanoseconds, 8 bytes), frame count (8 bytes)
+	THMB PNG thumbnail of the screen when the slot was saved
+
+*/
+
+const STATE_SLOTS = 10
+
+// SlotInfo describes a used save slot
+type SlotInfo struct {
+	Slot      int
+	Time      time.Time   // when the slot was saved
+	Frames    uint64      // frames run since power on when the slot was saved
+	Thumbnail image.Image // the screen when the slot was saved
+	Err       error       // why the slot can't be read, the fields above are then only filled in as far as it could be
+}
+
+// SlotEmptyError is returned when loading or exporting a slot that has nothing saved in it
+type SlotEmptyError struct {
+	Slot int
+}
+
+func (e *SlotEmptyError) Error() string {
+	return fmt.Sprintf("save slot %d is empty", e.Slot)
+}
+
+// SetStateDir sets the directory the per-game slot directories go in, empty uses the default
+func (c *Console) SetStateDir(dir string) {
+	c.stateDir = dir
+}
+
+// SlotDir returns the directory this ROM's slots are saved in
+func (c *Console) SlotDir() (string, error) {
+	dir := c.stateDir
+	if dir == "" {
+		config, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(config, "GoGB", "states")
+	}
+
+	// keep the title safe to use as a file name
+	title := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < 0x20 {
+			return '_'
+		}
+		return r
+	}, c.cart.Header.Title)
+
+	return filepath.Join(dir, fmt.Sprintf("%s-%08X", title, c.cart.CRC32())), nil
+}
+
+func (c *Console) slotPath(slot int) (string, error) {
+	if slot < 0 || slot >= STATE_SLOTS {
+		return "", fmt.Errorf("save slot %d out of range, slots are 0 - %d", slot, STATE_SLOTS-1)
+	}
+
+	dir, err := c.SlotDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, fmt.Sprintf("slot%d.state", slot)), nil
+}
+
+// SaveSlot saves the console state, a thumbnail, the time and the frame count to a slot, replacing what was there
+func (c *Console) SaveSlot(slot int) error {
+	path, err := c.slotPath(slot)
+	if err != nil {
+		return err
+	}
+
+	meta := make([]uint8, 16)
+	binary.LittleEndian.PutUint64(meta, uint64(time.Now().UnixNano()))
+	binary.LittleEndian.PutUint64(meta[8:], c.frames)
+
+	var thumbnail bytes.Buffer
+	if err := png.Encode(&thumbnail, c.Frame()); err != nil {
+		return err
+	}
+
+	var state bytes.Buffer
+	extra := []rawChunk{{"META", meta}, {"THMB", thumbnail.Bytes()}}
+	if err := c.writeState(&state, extra); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, state.Bytes())
+}
+
+// LoadSlot loads the state saved in a slot
+func (c *Console) LoadSlot(slot int) error {
+	state, err := c.readSlot(slot)
+	if err != nil {
+		return err
+	}
+
+	return c.applyState(state)
+}
+
+// DeleteSlot empties a slot, deleting an empty slot does nothing
+func (c *Console) DeleteSlot(slot int) error {
+	path, err := c.slotPath(slot)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// ExportSlot writes a slot to w, the result is a save state LoadState accepts
+func (c *Console) ExportSlot(slot int, w io.Writer) error {
+	path, err := c.slotPath(slot)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &SlotEmptyError{Slot: slot}
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// ListSlots returns the used slots in order, empty slots are left out.
+// A slot that can't be read is still listed, with the reason in its Err
+func (c *Console) ListSlots() ([]SlotInfo, error) {
+	if _, err := c.SlotDir(); err != nil {
+		return nil, err
+	}
+
+	var slots []SlotInfo
+
+	for slot := 0; slot < STATE_SLOTS; slot++ {
+		state, err := c.readSlot(slot)
+		if _, empty := err.(*SlotEmptyError); empty {
+			continue
+		}
+		if err != nil {
+			slots = append(slots, SlotInfo{Slot: slot, Err: err})
+			continue
+		}
+
+		info, err := slotInfo(slot, state)
+		info.Err = err
+		slots = append(slots, info)
+	}
+
+	return slots, nil
+}
+
+func (c *Console) readSlot(slot int) (*savedState, error) {
+	path, err := c.slotPath(slot)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, &SlotEmptyError{Slot: slot}
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	state, err := readState(file)
+	if err != nil {
+		return nil, fmt.Errorf("save slot %d: %w", slot, err)
+	}
+
+	return state, nil
+}
+
+// slotInfo decodes the metadata chunks of a slot
+func slotInfo(slot int, state *savedState) (SlotInfo, error) {
+	info := SlotInfo{Slot: slot}
+
+	meta, ok := state.chunks["META"]
+	if !ok || len(meta) != 16 {
+		return info, fmt.Errorf("save slot %d: %w", slot, &StateChunkError{Chunk: "META", Err: fmt.Errorf("missing or not 16 bytes")})
+	}
+	info.Time = time.Unix(0, int64(binary.LittleEndian.Uint64(meta)))
+	info.Frames = binary.LittleEndian.Uint64(meta[8:])
+
+	thumbnail, err := png.Decode(bytes.NewReader(state.chunks["THMB"]))
+	if err != nil {
+		return info, fmt.Errorf("save slot %d: %w", slot, &StateChunkError{Chunk: "THMB", Err: err})
+	}
+	info.Thumbnail = thumbnail
+
+	return info, nil
+}
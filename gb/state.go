@@ -0,0 +1,401 @@
+package gb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+/*
+
+Save states
+
+A save state is a header followed by chunks, all little endian.
+
+Header (30 bytes)
+
+	00 - 07 Magic "GOGBSTAT"
+	08 - 09 Format version
+	0A - 0D CRC-32 of the ROM the state was saved from
+	0E - 1D ROM title, padded with 0s
+
+Chunk
+
+	00 - 03 ID, e.g. "CPU "
+	04 - 07 Length of the data
+	08 -    Data
+
+Chunks
+
+	CONS Hardware model and frame count
+	CPU  Registers, ticks, IME and HALT/STOP state
+	MEM  VRAM, WRAM, OAM, HRAM, I/O registers (including IF) and IE
+	CART External RAM and the mapper's registers
+	TIMR Timer
+	JOYP Joypad
+	PPU  PPU
+	APU  APU
+	END  End of the state, no data
+
+Every chunk up to END is required, unknown chunks are skipped. Each part of the console reads and writes
+its own chunk, a chunk that doesn't match the size its part expects fails the load.
+The version changes whenever a chunk's contents change, states from other versions are rejected.
+
+*/
+
+const STATE_MAGIC = "GOGBSTAT"
+const STATE_VERSION = 11
+
+const stateTitleSize = 16
+
+// ErrNotSaveState is returned when loading data that doesn't start with the save state magic
+var ErrNotSaveState = errors.New("not a save state")
+
+// StateVersionError is returned when loading a state saved by an incompatible version
+type StateVersionError struct {
+	Version uint16
+}
+
+func (e *StateVersionError) Error() string {
+	return fmt.Sprintf("save state version %d is not supported, expected version %d", e.Version, STATE_VERSION)
+}
+
+// StateROMError is returned when loading a state saved from a different ROM
+type StateROMError struct {
+	Title    string // title of the ROM the state was saved from
+	Checksum uint32 // CRC-32 of the ROM the state was saved from
+	Expected uint32 // CRC-32 of the loaded ROM
+}
+
+func (e *StateROMError) Error() string {
+	return fmt.Sprintf("save state is for a different ROM: %q (CRC-32 %08X), loaded ROM has CRC-32 %08X", e.Title, e.Checksum, e.Expected)
+}
+
+// StateChunkError is returned when a chunk is missing, truncated or doesn't match its part of the console
+type StateChunkError struct {
+	Chunk string
+	Err   error
+}
+
+func (e *StateChunkError) Error() string {
+	return fmt.Sprintf("save state chunk %q: %v", e.Chunk, e.Err)
+}
+
+func (e *StateChunkError) Unwrap() error {
+	return e.Err
+}
+
+// StateRollbackError is returned when a state fails to load and the state before it can't be restored either,
+// which leaves the console locked
+type StateRollbackError struct {
+	Err         error // why the state failed to load
+	RollbackErr error // why the previous state failed to load back
+}
+
+func (e *StateRollbackError) Error() string {
+	return fmt.Sprintf("%v, restoring the previous state failed: %v", e.Err, e.RollbackErr)
+}
+
+func (e *StateRollbackError) Unwrap() []error {
+	return []error{e.Err, e.RollbackErr}
+}
+
+// stateChunk is one part of the console's state
+type stateChunk struct {
+	id   string
+	save func(s *stateWriter)
+	load func(s *stateReader)
+}
+
+func (c *Console) stateChunks() []stateChunk {
+	return []stateChunk{
+		{"CONS", c.saveState, c.loadState},
+		{"CPU ", c.cpu.saveState, c.cpu.loadState},
+		{"MEM ", c.mem.saveState, c.mem.loadState},
+		{"CART", c.cart.saveState, c.cart.loadState},
+		{"TIMR", c.timer.saveState, c.timer.loadState},
+		{"JOYP", c.joypad.saveState, c.joypad.loadState},
+		{"PPU ", c.ppu.saveState, c.ppu.loadState},
+		{"APU ", c.apu.saveState, c.apu.loadState},
+	}
+}
+
+// SaveState writes the whole console state to w
+func (c *Console) SaveState(w io.Writer) error {
+	return c.writeState(w, nil)
+}
+
+// writeState writes the console state followed by extra chunks, e.g. save slot metadata
+func (c *Console) writeState(w io.Writer, extra []rawChunk) error {
+	header := make([]uint8, stateHeaderSize)
+	copy(header, STATE_MAGIC)
+	binary.LittleEndian.PutUint16(header[8:], STATE_VERSION)
+	binary.LittleEndian.PutUint32(header[10:], c.cart.CRC32())
+	copy(header[14:], c.cart.Header.Title)
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	for _, chunk := range c.stateChunks() {
+		s := &stateWriter{}
+		chunk.save(s)
+		if s.err != nil {
+			return &StateChunkError{Chunk: chunk.id, Err: s.err}
+		}
+
+		if err := writeStateChunk(w, chunk.id, s.buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	for _, chunk := range extra {
+		if err := writeStateChunk(w, chunk.id, chunk.data); err != nil {
+			return err
+		}
+	}
+
+	return writeStateChunk(w, "END ", nil)
+}
+
+// LoadState restores the console state from r, on error the console is left as it was
+func (c *Console) LoadState(r io.Reader) error {
+	state, err := readState(r)
+	if err != nil {
+		return err
+	}
+
+	return c.applyState(state)
+}
+
+// applyState checks a state was saved from this ROM by this version and loads it
+func (c *Console) applyState(state *savedState) error {
+	if state.version != STATE_VERSION {
+		return &StateVersionError{Version: state.version}
+	}
+
+	if state.checksum != c.cart.CRC32() {
+		return &StateROMError{Title: state.title, Checksum: state.checksum, Expected: c.cart.CRC32()}
+	}
+
+	for _, chunk := range c.stateChunks() {
+		if _, ok := state.chunks[chunk.id]; !ok {
+			return &StateChunkError{Chunk: chunk.id, Err: errors.New("missing")}
+		}
+	}
+
+	// keep the current state to roll back to if a chunk doesn't fit
+	var backup bytes.Buffer
+	if err := c.SaveState(&backup); err != nil {
+		return err
+	}
+
+	if err := c.loadChunks(state); err != nil {
+		if rollbackErr := c.restoreState(backup.Bytes()); rollbackErr != nil {
+			return c.lockState(&StateRollbackError{Err: err, RollbackErr: rollbackErr})
+		}
+		return err
+	}
+
+	return nil
+}
+
+// loadChunks loads every chunk into its part of the console, stopping at the first that doesn't fit
+func (c *Console) loadChunks(state *savedState) error {
+	for _, chunk := range c.stateChunks() {
+		data := state.chunks[chunk.id]
+		s := &stateReader{r: bytes.NewReader(data)}
+		chunk.load(s)
+
+		if s.err == nil && s.r.Len() > 0 {
+			s.err = fmt.Errorf("%d bytes left over", s.r.Len())
+		}
+		if s.err != nil {
+			return &StateChunkError{Chunk: chunk.id, Err: s.err}
+		}
+	}
+
+	return nil
+}
+
+// the model has to match, since it decides which hardware exists
+
+func (c *Console) saveState(s *stateWriter) {
+	s.write(c.model, c.frames, uint32(c.frameTicks))
+}
+
+func (c *Console) loadState(s *stateReader) {
+	var model Model
+	var frameTicks uint32
+	s.read(&model, &c.frames, &frameTicks)
+	c.frameTicks = int(frameTicks)
+
+	if s.err == nil && model != c.model {
+		s.err = fmt.Errorf("saved on a %v, this console is a %v", model, c.model)
+	}
+}
+
+// restoreState loads back a state the console just saved, without a rollback of its own
+func (c *Console) restoreState(data []uint8) error {
+	state, err := readState(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	return c.loadChunks(state)
+}
+
+// lockState hangs the CPU after a failed rollback, the console is left half loaded and can't be trusted
+func (c *Console) lockState(err error) error {
+	c.cpu.locked = true
+	c.stateErr = err
+	return err
+}
+
+const stateHeaderSize = len(STATE_MAGIC) + 2 + 4 + stateTitleSize
+
+// rawChunk is an encoded chunk
+type rawChunk struct {
+	id   string
+	data []uint8
+}
+
+// savedState is a state read from a file, not yet loaded into a console
+type savedState struct {
+	version  uint16
+	checksum uint32
+	title    string
+	chunks   map[string][]uint8
+}
+
+// readState reads the header and every chunk, so a truncated state is caught before the console is touched
+func readState(r io.Reader) (*savedState, error) {
+	header := make([]uint8, stateHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrNotSaveState
+		}
+		return nil, err
+	}
+
+	if string(header[:8]) != STATE_MAGIC {
+		return nil, ErrNotSaveState
+	}
+
+	state := &savedState{
+		version:  binary.LittleEndian.Uint16(header[8:]),
+		checksum: binary.LittleEndian.Uint32(header[10:]),
+		title:    string(bytes.TrimRight(header[14:], "\x00")),
+		chunks:   map[string][]uint8{},
+	}
+
+	for {
+		id, data, err := readStateChunk(r)
+		if err != nil {
+			return nil, err
+		}
+		if id == "END " {
+			return state, nil
+		}
+		state.chunks[id] = data
+	}
+}
+
+func writeStateChunk(w io.Writer, id string, data []uint8) error {
+	header := make([]uint8, 8)
+	copy(header, id)
+	binary.LittleEndian.PutUint32(header[4:], uint32(len(data)))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+func readStateChunk(r io.Reader) (string, []uint8, error) {
+	header := make([]uint8, 8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return "", nil, &StateChunkError{Chunk: "END ", Err: errors.New("missing, the state is truncated")}
+		}
+		return "", nil, err
+	}
+
+	id := string(header[:4])
+	data := make([]uint8, binary.LittleEndian.Uint32(header[4:]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return "", nil, &StateChunkError{Chunk: id, Err: errors.New("truncated")}
+		}
+		return "", nil, err
+	}
+
+	return id, data, nil
+}
+
+// <----------------------------- ENCODING -----------------------------> //
+
+// stateWriter encodes fixed size values (and slices and arrays of them) into a chunk
+type stateWriter struct {
+	buf bytes.Buffer
+	err error
+}
+
+func (s *stateWriter) write(values ...interface{}) {
+	for _, value := range values {
+		if s.err == nil {
+			s.err = binary.Write(&s.buf, binary.LittleEndian, value)
+		}
+	}
+}
+
+// stateReader decodes a chunk into pointers to fixed size values, in the order they were written
+type stateReader struct {
+	r   *bytes.Reader
+	err error
+}
+
+func (s *stateReader) read(values ...interface{}) {
+	for _, value := range values {
+		if s.err == nil {
+			s.err = binary.Read(s.r, binary.LittleEndian, value)
+		}
+	}
+
+	if s.err == io.EOF || s.err == io.ErrUnexpectedEOF {
+		s.err = errors.New("too short")
+	}
+}
+
+// <----------------------------- CARTRIDGE -----------------------------> //
+
+// CRC32 returns the CRC-32 of the ROM, which identifies it for save states
+func (cart *Cartridge) CRC32() uint32 {
+	return crc32.ChecksumIEEE(cart.rom)
+}
+
+// stateMapper is implemented by mappers with registers to save in save states
+type stateMapper interface {
+	saveState(s *stateWriter)
+	loadState(s *stateReader)
+}
+
+func (cart *Cartridge) saveState(s *stateWriter) {
+	s.write(cart.ram)
+	if m, ok := cart.mapper.(stateMapper); ok {
+		m.saveState(s)
+	}
+}
+
+func (cart *Cartridge) loadState(s *stateReader) {
+	s.read(cart.ram)
+	if m, ok := cart.mapper.(stateMapper); ok {
+		m.loadState(s)
+	}
+
+	// the RAM no longer matches the battery save
+	cart.dirty = true
+}
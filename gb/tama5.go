@@ -0,0 +1,392 @@
+package gb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+/*
+
+Bandai TAMA5 (Game de Hakken!! Tamagotchi Osucchi to Mesucchi)
+
+Everything goes through two addresses, the rest of A000 - BFFF and the whole ROM area have no registers.
+
+	A000 Register value, 4 bits
+	A001 Register select, reads 0xF1 (ready)
+
+Registers
+
+	0 ROM bank number, bits 0-3
+	1 ROM bank number, bit 4
+	4 Data to write, low nibble
+	5 Data to write, high nibble
+	6 Address bit 4 (bit 0) and command (bits 1-3): 0 = write RAM, 1 = read RAM, 2 = RTC command, 4 = RTC register
+	7 Address bits 0-3, writing it runs the command
+	C Data read, low nibble
+	D Data read, high nibble
+
+RAM is 32 bytes. The data written is registers 5 and 4, the data read is the byte read by the last command.
+
+RTC commands, the address selects the command
+
+	00 Stop the clock
+	01 Start the clock, resetting the seconds
+	04 Set the minutes (BCD)
+	05 Set the hours (BCD)
+	06 Read the minutes (BCD)
+	07 Read the hours (BCD)
+	10 Disable the alarm
+	11 Enable the alarm
+
+RTC registers give direct access to the TC8521, register 4 selects one of its 13 registers,
+register 7 selects the page (bits 1-2) and reads (bit 0 set) or writes register 5 to it.
+
+	Page 0, the time, one BCD digit per register: seconds, minutes, hours (2 each), weekday,
+	        day, month, year (2 each), years are 2000 - 2099
+	Page 1, the alarm
+	Page 2 - 3, free RAM
+
+The clock is battery backed, it's saved after the RAM in 72 bytes, little endian:
+
+	00 - 2F Pages 1 - 3, 16 bytes each, one nibble per byte
+	30 - 37 Time on the clock, as UNIX time
+	38      Weekday offset from the date's weekday
+	39      Flags: bit 0 stopped, bit 1 alarm enabled
+	40 - 47 UNIX timestamp of the save
+
+Like the MBC3 RTC it runs off emulated cycles unless a wall clock is set.
+
+*/
+
+const TAMA5_RAM_SIZE = 0x20
+const TAMA5_SAVE_SIZE = 0x48
+
+const TC8521_REGISTERS = 13
+
+type TAMA5 struct {
+	cart      *Cartridge
+	registers [0x10]uint8
+	selected  uint8 // register selected through A001
+	read      uint8 // byte read by the last read command
+
+	// RTC
+	pages   [3][0x10]uint8 // TC8521 pages 1 - 3
+	time    time.Time      // time on the clock, in UTC
+	weekday uint8          // weekday offset from the date's weekday
+	stopped bool
+	alarm   bool      // alarm enabled
+	cycles  uint32    // cycles since the last second, when running off emulated cycles
+	clock   Clock     // wall clock, nil when running off emulated cycles
+	synced  time.Time // wall clock time the clock is up to date with
+	saved   time.Time // timestamp of a save loaded without a clock, for a clock set later to catch up from
+}
+
+func NewTAMA5(cart *Cartridge) *TAMA5 {
+	if len(cart.ram) != TAMA5_RAM_SIZE {
+		cart.ram = make([]uint8, TAMA5_RAM_SIZE)
+	}
+
+	return &TAMA5{
+		cart: cart,
+		time: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// SetClock makes the RTC follow the given wall clock, nil goes back to emulated cycles.
+// After loading a save without a clock, the first clock set catches up on the time since the save
+func (m *TAMA5) SetClock(clock Clock) {
+	m.clock = clock
+	if clock == nil {
+		return
+	}
+
+	if m.saved.IsZero() {
+		m.synced = clock.Now()
+		return
+	}
+
+	m.synced = m.saved
+	m.saved = time.Time{}
+	m.sync()
+}
+
+// Step runs the RTC off emulated cycles
+func (m *TAMA5) Step(ticks int) {
+	if m.clock != nil {
+		return
+	}
+
+	m.cycles += uint32(ticks)
+	for m.cycles >= CLOCK_SPEED {
+		m.cycles -= CLOCK_SPEED
+		m.advance(1)
+	}
+}
+
+func (m *TAMA5) romBank() int {
+	return int(m.registers[0x1]&0x01)<<4 | int(m.registers[0x0])
+}
+
+func (m *TAMA5) ReadROM(address uint16) uint8 {
+	if address < ROM_BANK_SIZE {
+		return m.cart.rom[address]
+	}
+
+	bank := m.romBank() % (len(m.cart.rom) / ROM_BANK_SIZE)
+	return m.cart.rom[bank*ROM_BANK_SIZE+int(address-ROM_BANK_SIZE)]
+}
+
+func (m *TAMA5) WriteROM(address uint16, value uint8) {}
+
+func (m *TAMA5) ReadRAM(address uint16) uint8 {
+	switch address {
+	case 0x0000:
+		switch m.selected {
+		case 0xC:
+			return 0xF0 | m.read&0x0F
+		case 0xD:
+			return 0xF0 | m.read>>4
+		default:
+			return 0xFF
+		}
+	case 0x0001:
+		return 0xF1
+	default:
+		return 0xFF
+	}
+}
+
+func (m *TAMA5) WriteRAM(address uint16, value uint8) {
+	switch address {
+	case 0x0000:
+		m.registers[m.selected] = value & 0x0F
+		if m.selected == 0x7 {
+			m.command()
+		}
+	case 0x0001:
+		m.selected = value & 0x0F
+	}
+}
+
+// command runs the command in register 6 on the address in registers 6 and 7
+func (m *TAMA5) command() {
+	address := m.registers[0x6]&0x01<<4 | m.registers[0x7]
+	data := m.registers[0x5]<<4 | m.registers[0x4]
+
+	switch m.registers[0x6] >> 1 {
+	case 0x0:
+		m.cart.ram[address] = data
+		m.cart.dirty = true
+	case 0x1:
+		m.read = m.cart.ram[address]
+	case 0x2:
+		m.rtcCommand(address, data)
+	case 0x4:
+		m.rtcRegister(m.registers[0x7], m.registers[0x4], m.registers[0x5])
+	default:
+		m.read = 0
+	}
+}
+
+// rtcCommand runs an RTC command, reads put their result in m.read
+func (m *TAMA5) rtcCommand(command uint8, data uint8) {
+	m.sync()
+	m.read = 0
+
+	hour, minute, second := m.time.Clock()
+
+	switch command {
+	case 0x00:
+		m.stopped = true
+	case 0x01:
+		m.stopped = false
+		m.cycles = 0
+		m.time = m.time.Add(-time.Duration(second) * time.Second)
+	case 0x04:
+		m.time = m.time.Add(time.Duration(int(fromBCD(data))-minute) * time.Minute)
+	case 0x05:
+		m.time = m.time.Add(time.Duration(int(fromBCD(data))-hour) * time.Hour)
+	case 0x06:
+		m.read = toBCD(uint8(minute))
+	case 0x07:
+		m.read = toBCD(uint8(hour))
+	case 0x10:
+		m.alarm = false
+	case 0x11:
+		m.alarm = true
+	}
+}
+
+// rtcRegister reads or writes a TC8521 register, mode is the page in bits 1-2 and reads when bit 0 is set
+func (m *TAMA5) rtcRegister(mode uint8, register uint8, value uint8) {
+	page := mode >> 1 & 0x03
+	read := mode&0x01 != 0
+
+	if register >= TC8521_REGISTERS {
+		m.read = 0
+		return
+	}
+
+	if page != 0 {
+		if read {
+			m.read = m.pages[page-1][register]
+		} else {
+			m.pages[page-1][register] = value
+		}
+		return
+	}
+
+	m.sync()
+	digits := m.digits()
+	if read {
+		m.read = digits[register]
+		return
+	}
+
+	digits[register] = value
+	m.setDigits(digits)
+}
+
+// digits returns page 0 of the TC8521, the time as BCD digits
+func (m *TAMA5) digits() [TC8521_REGISTERS]uint8 {
+	t := m.time
+	year, month, day := t.Date()
+	hour, minute, second := t.Clock()
+	weekday := (uint8(t.Weekday()) + m.weekday) % 7
+
+	var digits [TC8521_REGISTERS]uint8
+	for i, value := range []int{second, minute, hour} {
+		digits[i*2] = uint8(value % 10)
+		digits[i*2+1] = uint8(value / 10)
+	}
+	digits[6] = weekday
+	for i, value := range []int{day, int(month), year % 100} {
+		digits[7+i*2] = uint8(value % 10)
+		digits[7+i*2+1] = uint8(value / 10)
+	}
+	return digits
+}
+
+// setDigits sets the time from page 0 of the TC8521, out of range values roll over into the next unit
+func (m *TAMA5) setDigits(digits [TC8521_REGISTERS]uint8) {
+	value := func(i int) int {
+		return int(digits[i+1]&0x0F)*10 + int(digits[i]&0x0F)
+	}
+
+	weekday := digits[6] % 7
+	m.time = time.Date(2000+value(11), time.Month(value(9)), value(7), value(4), value(2), value(0), 0, time.UTC)
+	m.weekday = (weekday + 7 - uint8(m.time.Weekday())) % 7
+}
+
+// sync catches the time up with the wall clock
+func (m *TAMA5) sync() {
+	if m.clock == nil {
+		return
+	}
+
+	elapsed := m.clock.Now().Sub(m.synced)
+	if elapsed < time.Second {
+		return
+	}
+
+	seconds := uint64(elapsed / time.Second)
+	m.synced = m.synced.Add(time.Duration(seconds) * time.Second)
+	m.advance(seconds)
+}
+
+// advance moves the time forward by a number of seconds, years wrap from 2099 to 2000
+func (m *TAMA5) advance(seconds uint64) {
+	if m.stopped {
+		return
+	}
+
+	m.time = m.time.Add(time.Duration(seconds) * time.Second)
+	if year := m.time.Year(); year >= 2100 {
+		m.time = m.time.AddDate(-(year-2000)/100*100, 0, 0)
+	}
+}
+
+func (m *TAMA5) batterySize() int {
+	return TAMA5_SAVE_SIZE
+}
+
+func (m *TAMA5) saveBattery() []uint8 {
+	m.sync()
+
+	data := make([]uint8, TAMA5_SAVE_SIZE)
+	for i := range m.pages {
+		copy(data[i*0x10:], m.pages[i][:])
+	}
+	binary.LittleEndian.PutUint64(data[0x30:], uint64(m.time.Unix()))
+	data[0x38] = m.weekday
+	if m.stopped {
+		data[0x39] |= 0x01
+	}
+	if m.alarm {
+		data[0x39] |= 0x02
+	}
+
+	now := time.Now()
+	if m.clock != nil {
+		now = m.clock.Now()
+	}
+	binary.LittleEndian.PutUint64(data[0x40:], uint64(now.Unix()))
+
+	return data
+}
+
+// loadBattery restores the clock, following the wall clock it also catches up on the time passed since the save
+func (m *TAMA5) loadBattery(data []uint8) error {
+	if len(data) != TAMA5_SAVE_SIZE {
+		return fmt.Errorf("tama5 save is %d bytes, expected %d", len(data), TAMA5_SAVE_SIZE)
+	}
+
+	for i := range m.pages {
+		for j := range m.pages[i] {
+			m.pages[i][j] = data[i*0x10+j] & 0x0F
+		}
+	}
+	m.time = time.Unix(int64(binary.LittleEndian.Uint64(data[0x30:])), 0).UTC()
+	m.weekday = data[0x38] % 7
+	m.stopped = data[0x39]&0x01 != 0
+	m.alarm = data[0x39]&0x02 != 0
+	m.cycles = 0
+
+	saved := time.Unix(int64(binary.LittleEndian.Uint64(data[0x40:])), 0)
+	if m.clock == nil {
+		m.saved = saved
+		return nil
+	}
+
+	m.synced = saved
+	m.saved = time.Time{}
+	m.sync()
+	return nil
+}
+
+func (m *TAMA5) saveState(s *stateWriter) {
+	m.sync()
+	s.write(&m.registers, m.selected, m.read)
+	s.write(&m.pages, m.time.Unix(), m.weekday, m.stopped, m.alarm, m.cycles)
+}
+
+func (m *TAMA5) loadState(s *stateReader) {
+	var unix int64
+	s.read(&m.registers, &m.selected, &m.read)
+	s.read(&m.pages, &unix, &m.weekday, &m.stopped, &m.alarm, &m.cycles)
+	m.time = time.Unix(unix, 0).UTC()
+
+	// following the wall clock, the time keeps going from now
+	if m.clock != nil {
+		m.synced = m.clock.Now()
+	}
+}
+
+func toBCD(value uint8) uint8 {
+	return value/10<<4 | value%10
+}
+
+func fromBCD(value uint8) uint8 {
+	return value>>4*10 + value&0x0F
+}
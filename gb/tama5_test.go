@@ -0,0 +1,73 @@
+package gb
+
+import "testing"
+
+// tama5Write writes a value to a register
+func tama5Write(m *TAMA5, register uint8, value uint8) {
+	m.WriteRAM(0x0001, register)
+	m.WriteRAM(0x0000, value)
+}
+
+// tama5Run runs a command on an address with the data byte, returning the byte read
+func tama5Run(m *TAMA5, command uint8, address uint8, data uint8) uint8 {
+	tama5Write(m, 0x4, data&0x0F)
+	tama5Write(m, 0x5, data>>4)
+	tama5Write(m, 0x6, command<<1|address>>4)
+	tama5Write(m, 0x7, address&0x0F)
+
+	m.WriteRAM(0x0001, 0xC)
+	low := m.ReadRAM(0x0000) & 0x0F
+	m.WriteRAM(0x0001, 0xD)
+	return m.ReadRAM(0x0000)<<4 | low
+}
+
+func TestTAMA5Time(t *testing.T) {
+	tests := []struct {
+		minutes, hours         uint8 // BCD
+		stop                   bool
+		seconds                int // emulated seconds between setting and reading the time
+		wantMinutes, wantHours uint8
+	}{
+		{0x00, 0x00, false, 0, 0x00, 0x00},
+		{0x45, 0x13, false, 0, 0x45, 0x13},
+		{0x45, 0x13, false, 59, 0x45, 0x13},
+		{0x45, 0x13, false, 60, 0x46, 0x13},
+		{0x59, 0x23, false, 60, 0x00, 0x00},
+		{0x59, 0x09, false, 60, 0x00, 0x10},
+		{0x45, 0x13, true, 120, 0x45, 0x13},
+	}
+
+	for _, test := range tests {
+		m := NewTAMA5(bankedCart(2, 0))
+		tama5Run(m, 0x2, 0x04, test.minutes)
+		tama5Run(m, 0x2, 0x05, test.hours)
+		if test.stop {
+			tama5Run(m, 0x2, 0x00, 0)
+		}
+		for i := 0; i < test.seconds; i++ {
+			m.Step(int(CLOCK_SPEED))
+		}
+
+		minutes := tama5Run(m, 0x2, 0x06, 0)
+		hours := tama5Run(m, 0x2, 0x07, 0)
+		if minutes != test.wantMinutes || hours != test.wantHours {
+			t.Errorf("%02X:%02X + %ds: read %02X:%02X, want %02X:%02X",
+				test.hours, test.minutes, test.seconds, hours, minutes, test.wantHours, test.wantMinutes)
+		}
+	}
+}
+
+func TestTAMA5RAM(t *testing.T) {
+	m := NewTAMA5(bankedCart(2, 0))
+	tama5Run(m, 0x0, 0x1F, 0xA5)
+
+	if got := tama5Run(m, 0x1, 0x1F, 0); got != 0xA5 {
+		t.Errorf("RAM 1F = %02X, want A5", got)
+	}
+	if !m.cart.dirty {
+		t.Error("RAM write didn't mark the RAM dirty")
+	}
+	if got := m.ReadRAM(0x0001); got != 0xF1 {
+		t.Errorf("A001 = %02X, want F1", got)
+	}
+}
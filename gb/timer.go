@@ -0,0 +1,94 @@
+package gb
+
+/*
+
+Timer registers
+
+	FF04 - DIV  Divider, upper 8 bits of a 16-bit counter incremented every clock cycle, writing resets it
+	FF05 - TIMA Timer counter, incremented at the rate selected by TAC, requests an interrupt on overflow
+	FF06 - TMA  Timer modulo, loaded into TIMA when it overflows
+	FF07 - TAC  Timer control, bit 2 enables TIMA, bits 0-1 select the rate
+
+*/
+
+const DIV_ADDRESS = 0xFF04
+const TIMA_ADDRESS = 0xFF05
+const TMA_ADDRESS = 0xFF06
+const TAC_ADDRESS = 0xFF07
+
+// bit of the internal divider whose falling edge increments TIMA, indexed by TAC bits 0-1
+var timerBits = [4]uint16{9, 3, 5, 7}
+
+type Timer struct {
+	mem  *MemoryMap // memory map, for requesting interrupts
+	div  uint16     // internal divider, DIV is the upper byte
+	tima uint8
+	tma  uint8
+	tac  uint8
+}
+
+// Step advances the timer by the given number of clock cycles
+func (t *Timer) Step(ticks int) {
+	for i := 0; i < ticks; i++ {
+		t.setDivider(t.div + 1)
+	}
+}
+
+// setDivider updates the internal divider and increments TIMA on a falling edge of the selected bit
+func (t *Timer) setDivider(value uint16) {
+	bit := timerBits[t.tac&0x03]
+	wasSet := t.tac&0x04 != 0 && t.div&(1<<bit) != 0
+	isSet := t.tac&0x04 != 0 && value&(1<<bit) != 0
+
+	t.div = value
+
+	if wasSet && !isSet {
+		t.tima++
+		if t.tima == 0 {
+			t.tima = t.tma
+			t.mem.RequestInterrupt(INT_TIMER)
+		}
+	}
+}
+
+func (t *Timer) Read(address uint16) uint8 {
+	switch address {
+	case DIV_ADDRESS:
+		return uint8(t.div >> 8)
+	case TIMA_ADDRESS:
+		return t.tima
+	case TMA_ADDRESS:
+		return t.tma
+	case TAC_ADDRESS:
+		return t.tac | 0xF8
+	}
+	return 0xFF
+}
+
+func (t *Timer) Write(address uint16, value uint8) {
+	switch address {
+	case DIV_ADDRESS:
+		t.setDivider(0)
+	case TIMA_ADDRESS:
+		t.tima = value
+	case TMA_ADDRESS:
+		t.tma = value
+	case TAC_ADDRESS:
+		t.tac = value & 0x07
+	}
+}
+
+func (t *Timer) Reset() {
+	t.div = 0
+	t.tima = 0
+	t.tma = 0
+	t.tac = 0
+}
+
+func (t *Timer) saveState(s *stateWriter) {
+	s.write(t.div, t.tima, t.tma, t.tac)
+}
+
+func (t *Timer) loadState(s *stateReader) {
+	s.read(&t.div, &t.tima, &t.tma, &t.tac)
+}